@@ -0,0 +1,48 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+)
+
+// unauthenticatedPaths stay open when --auth-user is set so kubelet probes keep working.
+var unauthenticatedPaths = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+}
+
+// validateAuth checks that --auth-user and --auth-pass are given together.
+func validateAuth(cfg config) error {
+	if (cfg.authUser == "") != (cfg.authPass == "") {
+		return fmt.Errorf("--auth-user and --auth-pass must be set together")
+	}
+	return nil
+}
+
+// requireBasicAuth wraps next with HTTP Basic Auth, except for unauthenticatedPaths.
+func requireBasicAuth(next http.Handler, user, pass string) http.Handler {
+	wantUser := sha256.Sum256([]byte(user))
+	wantPass := sha256.Sum256([]byte(pass))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if unauthenticatedPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Compare fixed-size hashes in constant time so neither length nor content leaks
+		gotUser, gotPass, ok := r.BasicAuth()
+		userHash := sha256.Sum256([]byte(gotUser))
+		passHash := sha256.Sum256([]byte(gotPass))
+		userOK := subtle.ConstantTimeCompare(userHash[:], wantUser[:]) == 1
+		passOK := subtle.ConstantTimeCompare(passHash[:], wantPass[:]) == 1
+		if !ok || !userOK || !passOK {
+			w.Header().Set("WWW-Authenticate", `Basic realm="dnmap", charset="UTF-8"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
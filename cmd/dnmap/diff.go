@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/ddl-r-abdulaziz/dnmap/pkg/graph"
+)
+
+// runDiff compares two graphs exported with --format json and prints what changed.
+// When --output is given, it also renders the new graph with changed edges marked.
+func runDiff(cfg config, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("--diff requires two graph files: dnmap --diff old.json new.json")
+	}
+
+	oldGraph, err := readGraph(args[0])
+	if err != nil {
+		return err
+	}
+	newGraph, err := readGraph(args[1])
+	if err != nil {
+		return err
+	}
+
+	diff := graph.Diff(oldGraph, newGraph)
+	printDiff(diff)
+
+	if !flagPassed("output") {
+		return nil
+	}
+
+	renderer, err := newRenderer(cfg)
+	if err != nil {
+		return err
+	}
+	if err := writeMap(renderer, graph.MarkDiff(oldGraph, newGraph), cfg.outputFile); err != nil {
+		return err
+	}
+	slog.Info("diff map written", "path", cfg.outputFile)
+	return nil
+}
+
+// readGraph loads a graph written by --format json.
+func readGraph(path string) (*graph.NetworkGraph, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read graph: %w", err)
+	}
+	var g graph.NetworkGraph
+	if err := json.Unmarshal(data, &g); err != nil {
+		return nil, fmt.Errorf("failed to parse graph %s: %w", path, err)
+	}
+	return &g, nil
+}
+
+// printDiff writes a human-readable summary of diff to stdout.
+func printDiff(diff *graph.GraphDiff) {
+	if diff.Empty() {
+		fmt.Println("No changes")
+		return
+	}
+
+	fmt.Printf("Nodes: +%d -%d, Edges: +%d -%d, Warnings: +%d -%d\n",
+		len(diff.AddedNodes), len(diff.RemovedNodes),
+		len(diff.AddedEdges), len(diff.RemovedEdges),
+		len(diff.AddedWarnings), len(diff.RemovedWarnings))
+
+	for _, n := range diff.AddedNodes {
+		fmt.Printf("+ node %s\n", n.ID)
+	}
+	for _, n := range diff.RemovedNodes {
+		fmt.Printf("- node %s\n", n.ID)
+	}
+	for _, e := range diff.AddedEdges {
+		fmt.Printf("+ edge %s -> %s (%s)\n", e.Source, e.Target, e.Policy)
+	}
+	for _, e := range diff.RemovedEdges {
+		fmt.Printf("- edge %s -> %s (%s)\n", e.Source, e.Target, e.Policy)
+	}
+	for _, w := range diff.AddedWarnings {
+		fmt.Printf("+ warning %s %s/%s (%s)\n", w.WarningType, w.Namespace, w.WorkloadName, w.PolicyName)
+	}
+	for _, w := range diff.RemovedWarnings {
+		fmt.Printf("- warning %s %s/%s (%s)\n", w.WarningType, w.Namespace, w.WorkloadName, w.PolicyName)
+	}
+}
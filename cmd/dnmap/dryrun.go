@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/ddl-r-abdulaziz/dnmap/pkg/k8s"
+)
+
+// runDryRun fetches and builds the graph exactly as a normal run would, then prints the
+// run's counts to stdout instead of rendering and writing the map.
+func runDryRun(cfg config) error {
+	var clients []*k8s.Client
+	if len(cfg.inputs) == 0 {
+		var err error
+		clients, err = newClients(cfg)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, manifest, err := buildMap(clients, cfg)
+	if err != nil {
+		return err
+	}
+	return printRunCounts(os.Stdout, manifest.Counts)
+}
+
+// printRunCounts writes counts as tab-aligned rows.
+func printRunCounts(out io.Writer, counts runCounts) error {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "Workloads\t%d\n", counts.Workloads)
+	fmt.Fprintf(w, "NetworkPolicies\t%d\n", counts.NetworkPolicies)
+	fmt.Fprintf(w, "AuthorizationPolicies\t%d\n", counts.IstioPolicies)
+	fmt.Fprintf(w, "CiliumNetworkPolicies\t%d\n", counts.CiliumPolicies)
+	fmt.Fprintf(w, "Nodes\t%d\n", counts.Nodes)
+	fmt.Fprintf(w, "Edges\t%d\n", counts.Edges)
+	fmt.Fprintf(w, "Warnings\t%d\n", counts.Warnings)
+	return w.Flush()
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// eventKeepAlive is how often /events sends a comment so idle proxies keep the stream open.
+const eventKeepAlive = 30 * time.Second
+
+// broadcaster fans a "map updated" signal out to every subscribed /events client.
+type broadcaster struct {
+	mu   sync.Mutex
+	subs map[chan struct{}]struct{}
+}
+
+// mapUpdates is signalled each time a new map is written.
+var mapUpdates = newBroadcaster()
+
+func newBroadcaster() *broadcaster {
+	return &broadcaster{subs: make(map[chan struct{}]struct{})}
+}
+
+// subscribe registers a new listener. Its channel holds at most one pending signal,
+// so a slow client sees a single update rather than blocking notify.
+func (b *broadcaster) subscribe() chan struct{} {
+	ch := make(chan struct{}, 1)
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+	return ch
+}
+
+// unsubscribe removes a listener registered with subscribe.
+func (b *broadcaster) unsubscribe(ch chan struct{}) {
+	b.mu.Lock()
+	delete(b.subs, ch)
+	b.mu.Unlock()
+}
+
+// notify signals every listener without blocking.
+func (b *broadcaster) notify() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs {
+		select {
+		case ch <- struct{}{}:
+		default: // an update is already pending
+		}
+	}
+}
+
+// serveEvents streams Server-Sent Events, sending an "update" event whenever the map
+// is regenerated. The subscription is dropped as soon as the client disconnects.
+func serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	updates := mapUpdates.subscribe()
+	defer mapUpdates.unsubscribe(updates)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-updates:
+			fmt.Fprint(w, "event: update\ndata: {}\n\n")
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ddl-r-abdulaziz/dnmap/pkg/graph"
+)
+
+// warningGate decides whether a run fails because of the graph's warnings, for using dnmap
+// as a CI policy linter.
+type warningGate struct {
+	enabled bool
+	types   []graph.WarningType // warning types that fail the run; empty means any
+}
+
+// newWarningGate builds the gate from --fail-on-warnings and --fail-on-warning-types.
+// Listing types enables the gate on its own.
+func newWarningGate(cfg config) (warningGate, error) {
+	gate := warningGate{enabled: cfg.failOnWarnings}
+	if cfg.failOnWarningTypes == "" {
+		return gate, nil
+	}
+
+	for _, name := range strings.Split(cfg.failOnWarningTypes, ",") {
+		t := graph.WarningType(strings.TrimSpace(name))
+		if !slices.Contains(graph.WarningTypes, t) {
+			return warningGate{}, fmt.Errorf("invalid --fail-on-warning-types: unknown warning type %q", t)
+		}
+		gate.types = append(gate.types, t)
+	}
+	gate.enabled = true
+	return gate, nil
+}
+
+// check returns an error counting the warnings of g the gate fails on, by type.
+func (gate warningGate) check(g *graph.NetworkGraph) error {
+	if !gate.enabled {
+		return nil
+	}
+
+	counts := make(map[graph.WarningType]int)
+	total := 0
+	for _, wd := range g.WarningDetails {
+		if len(gate.types) > 0 && !slices.Contains(gate.types, wd.WarningType) {
+			continue
+		}
+		counts[wd.WarningType]++
+		total++
+	}
+	if total == 0 {
+		return nil
+	}
+
+	var parts []string
+	for _, t := range graph.WarningTypes {
+		if counts[t] > 0 {
+			parts = append(parts, fmt.Sprintf("%s: %d", t, counts[t]))
+		}
+	}
+	return fmt.Errorf("map has %d warnings (%s)", total, strings.Join(parts, ", "))
+}
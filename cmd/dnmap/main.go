@@ -3,17 +3,23 @@ package main
 
 import (
 	"encoding/csv"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"log/slog"
+	"math/rand/v2"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/ddl-r-abdulaziz/dnmap/pkg/graph"
 	"github.com/ddl-r-abdulaziz/dnmap/pkg/k8s"
 	"github.com/ddl-r-abdulaziz/dnmap/pkg/render"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 const (
@@ -22,27 +28,139 @@ const (
 
 // Global state for the current graph (protected by mutex for concurrent access)
 var (
-	currentGraph *graph.NetworkGraph
-	graphMutex   sync.RWMutex
+	currentGraph   *graph.NetworkGraph
+	lastRefresh    time.Time           // Time of the last successful generation
+	lastRefreshErr error               // Error from the most recent failed refresh, cleared on success
+	baselineGraph  *graph.NetworkGraph // Graph pinned via /api/pin; renders mark changes against it
+	currentRun     *runManifest        // Manifest describing the run that produced currentGraph
+	graphMutex     sync.RWMutex
+	outputMutex    sync.Mutex // Serializes writes of the output file; see writeCurrentMap
 )
 
+// config holds the command-line options for a dnmap run.
+type config struct {
+	kubeconfig         string
+	outputFile         string
+	namespaces         string
+	serve              bool
+	port               string
+	refreshInterval    time.Duration
+	refreshJitter      float64
+	templateFile       string
+	mergeBy            string
+	maxNodes           int
+	readyThreshold     time.Duration
+	respectIgnore      bool
+	runManifest        string
+	contexts           stringList
+	allNamespaces      bool
+	excludeNS          string
+	selector           string
+	inputs             stringList
+	format             string
+	diff               bool
+	query              string
+	trace              string
+	traceDepth         int
+	stats              bool
+	layout             string
+	timeout            time.Duration
+	concurrency        int
+	qps                float64
+	burst              int
+	strict             bool
+	tlsCert            string
+	tlsKey             string
+	authUser           string
+	authPass           string
+	logLevel           string
+	cacheDir           string
+	useCache           bool
+	cacheTTL           time.Duration
+	dryRun             bool
+	broadCIDRPrefix    int
+	istioRootNamespace string
+	initPorts          bool
+	failOnWarnings     bool
+	failOnWarningTypes string
+	watch              bool
+	colorBy            string
+}
+
+// serveStatus is the JSON body of /status.
+type serveStatus struct {
+	Nodes       int       `json:"nodes"`
+	Edges       int       `json:"edges"`
+	Warnings    int       `json:"warnings"`
+	LastRefresh time.Time `json:"lastRefresh"`         // Time of the last successful generation
+	Namespaces  []string  `json:"namespaces"`          // Namespaces scanned for the current graph
+	LastError   string    `json:"lastError,omitempty"` // Error from the most recent refresh, if it failed
+}
+
+// stringList is a flag.Value collecting every occurrence of a repeatable flag.
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
 func main() {
-	var kubeconfig string
-	var outputFile string
-	var namespaces string
-	var serve bool
-	var port string
-	var refreshInterval time.Duration
+	var cfg config
 
 	// Set up flags
 	// Don't set a default kubeconfig path - let the client use standard kubectl loading rules
 	// which respect KUBECONFIG env var and fall back to ~/.kube/config
-	flag.StringVar(&kubeconfig, "kubeconfig", "", "path to the kubeconfig file (default: uses KUBECONFIG env or ~/.kube/config)")
-	flag.StringVar(&outputFile, "output", defaultOutputFile, "output HTML file path")
-	flag.StringVar(&namespaces, "namespaces", "domino-compute,domino-platform", "comma-separated list of namespaces to scan")
-	flag.BoolVar(&serve, "serve", false, "serve the generated HTML via HTTP")
-	flag.StringVar(&port, "port", "8080", "HTTP server port (when --serve is enabled)")
-	flag.DurationVar(&refreshInterval, "refresh", 5*time.Minute, "refresh interval for regenerating the map (when --serve is enabled)")
+	flag.StringVar(&cfg.kubeconfig, "kubeconfig", "", "path to the kubeconfig file (default: uses KUBECONFIG env or ~/.kube/config)")
+	flag.Var(&cfg.contexts, "context", "kubeconfig context to scan; repeat to combine several clusters into one map (default: current context)")
+	flag.Var(&cfg.inputs, "input", "manifest file or directory to read instead of a cluster; repeatable")
+	flag.StringVar(&cfg.outputFile, "output", defaultOutputFile, "output file path; with a --format other than html, the default's extension follows the format")
+	flag.StringVar(&cfg.format, "format", render.FormatHTML, "output format: html, dot (Graphviz), mermaid, json, cytoscape (Cytoscape.js elements) or edges-csv")
+	flag.StringVar(&cfg.namespaces, "namespaces", "domino-compute,domino-platform", "comma-separated list of namespaces to scan")
+	flag.BoolVar(&cfg.allNamespaces, "all-namespaces", false, "scan every namespace in the cluster (overrides --namespaces)")
+	flag.StringVar(&cfg.excludeNS, "exclude-namespaces", "", "comma-separated list of namespaces to skip (applied after --namespaces or --all-namespaces)")
+	flag.StringVar(&cfg.selector, "selector", "", "only graph workloads matching this label selector (e.g. team=ml,tier!=batch)")
+	flag.DurationVar(&cfg.timeout, "timeout", 30*time.Second, "timeout for each Kubernetes API call (0 = no timeout)")
+	flag.IntVar(&cfg.concurrency, "concurrency", k8s.DefaultConcurrency, "number of namespaces fetched in parallel")
+	flag.Float64Var(&cfg.qps, "qps", k8s.DefaultQPS, "maximum sustained Kubernetes API requests per second (negative = no client-side limit)")
+	flag.IntVar(&cfg.burst, "burst", k8s.DefaultBurst, "maximum burst of Kubernetes API requests above --qps")
+	flag.BoolVar(&cfg.strict, "strict", false, "fail when the RBAC role can't list a resource in a scanned namespace, instead of skipping it and reporting what was skipped")
+	flag.BoolVar(&cfg.serve, "serve", false, "serve the generated HTML via HTTP")
+	flag.StringVar(&cfg.port, "port", "8080", "HTTP server port (when --serve is enabled)")
+	flag.StringVar(&cfg.tlsCert, "tls-cert", "", "TLS certificate file; with --tls-key, serve over HTTPS (when --serve is enabled)")
+	flag.StringVar(&cfg.tlsKey, "tls-key", "", "TLS private key file; with --tls-cert, serve over HTTPS (when --serve is enabled)")
+	flag.StringVar(&cfg.authUser, "auth-user", "", "require HTTP Basic Auth with this user name (when --serve is enabled; /healthz and /readyz stay open)")
+	flag.StringVar(&cfg.authPass, "auth-pass", "", "password for --auth-user")
+	flag.DurationVar(&cfg.refreshInterval, "refresh", 5*time.Minute, "refresh interval for regenerating the map (when --serve is enabled)")
+	flag.Float64Var(&cfg.refreshJitter, "refresh-jitter", 0.1, "randomly lengthen or shorten each refresh interval by up to this fraction (0.1 = ±10%), so instances sharing --refresh don't poll in lockstep; 0 disables")
+	flag.BoolVar(&cfg.watch, "watch", false, "with --serve, also regenerate the map within seconds of workload and policy changes, using watches; falls back to --refresh polling when watching is denied")
+	flag.DurationVar(&cfg.readyThreshold, "ready-threshold", 15*time.Minute, "how long refreshes may keep failing before /readyz reports not ready (when --serve is enabled)")
+	flag.BoolVar(&cfg.respectIgnore, "respect-ignore-annotation", true, "exclude workloads and namespaces annotated with "+k8s.IgnoreAnnotation+"=true")
+	flag.StringVar(&cfg.layout, "layout", render.LayoutGrid, "initial layout of the HTML map: grid or hierarchical (sources left, targets right)")
+	flag.StringVar(&cfg.templateFile, "template", "", "path to a custom HTML template (default: built-in template)")
+	flag.IntVar(&cfg.maxNodes, "max-nodes", 0, "maximum number of workloads to render; larger graphs are deterministically pruned (0 = unlimited)")
+	flag.StringVar(&cfg.logLevel, "log-level", "info", "minimum level of log messages written to stderr: debug, info, warn or error")
+	flag.StringVar(&cfg.runManifest, "run-manifest", "", "write a JSON manifest describing the run (namespaces, options, context, version, counts) to this path")
+	flag.BoolVar(&cfg.diff, "diff", false, "compare two graphs exported with --format json (dnmap --diff old.json new.json) instead of scanning")
+	flag.StringVar(&cfg.query, "query", "", "print whether one workload may reach another, and the granting policies, instead of writing a map: source,target,port (e.g. apps/web,apps/db,5432; port * = any)")
+	flag.StringVar(&cfg.trace, "trace", "", "print every allowed multi-hop path between two workloads, and the policies along it, instead of writing a map: source,target (e.g. apps/web,apps/db)")
+	flag.IntVar(&cfg.traceDepth, "trace-depth", 5, "maximum number of hops in a --trace path")
+	flag.BoolVar(&cfg.stats, "stats", false, "print workload, port, edge, policy and warning counts instead of writing a map")
+	flag.StringVar(&cfg.cacheDir, "cache-dir", "", "directory where fetched cluster resources are cached, one file per context and namespace set")
+	flag.BoolVar(&cfg.useCache, "use-cache", false, "load cluster resources from --cache-dir instead of the API when a snapshot younger than --cache-ttl exists")
+	flag.DurationVar(&cfg.cacheTTL, "cache-ttl", time.Hour, "age after which a cached snapshot is refetched (0 = never)")
+	flag.BoolVar(&cfg.dryRun, "dry-run", false, "fetch and build the graph, print workload, policy, node, edge and warning counts, and write nothing")
+	flag.BoolVar(&cfg.initPorts, "include-init-ports", false, "also draw ports declared on init containers, deduplicated against the regular containers' ports")
+	flag.BoolVar(&cfg.failOnWarnings, "fail-on-warnings", false, "exit non-zero after writing the map if it has any policy warnings")
+	flag.StringVar(&cfg.failOnWarningTypes, "fail-on-warning-types", "", "comma-separated warning types that make the run exit non-zero after writing the map (e.g. no-selector,broad-cidr); implies --fail-on-warnings")
+	flag.IntVar(&cfg.broadCIDRPrefix, "broad-cidr-prefix", graph.DefaultBroadCIDRPrefix, "flag NetworkPolicy ipBlock CIDRs with this prefix length or shorter as broad-cidr warnings (-1 = never)")
+	flag.StringVar(&cfg.istioRootNamespace, "istio-root-namespace", graph.DefaultIstioRootNamespace, "Istio root namespace whose AuthorizationPolicies apply to workloads in every scanned namespace; always fetched (empty = none)")
+	flag.StringVar(&cfg.colorBy, "color-by", "", "namespace label key (e.g. environment) whose value colors workload borders in the HTML map")
+	flag.StringVar(&cfg.mergeBy, "merge-by", "", "label key used to merge workloads sharing the same value into a single node (e.g. app.kubernetes.io/name)")
 
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "dnmap - Domino Network Map\n\n")
@@ -55,62 +173,298 @@ func main() {
 
 	flag.Parse()
 
-	if err := run(kubeconfig, outputFile, namespaces, serve, port, refreshInterval); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	err := setupLogging(cfg.logLevel)
+	if err == nil {
+		switch {
+		case cfg.diff:
+			err = runDiff(cfg, flag.Args())
+		case cfg.query != "":
+			err = runQuery(cfg)
+		case cfg.trace != "":
+			err = runTrace(cfg)
+		case cfg.stats:
+			err = runStats(cfg)
+		case cfg.dryRun:
+			err = runDryRun(cfg)
+		default:
+			err = run(cfg)
+		}
+	}
+	if err != nil {
+		slog.Error("dnmap failed", "error", err)
 		os.Exit(1)
 	}
 }
 
-func run(kubeconfig, outputFile, namespaces string, serve bool, port string, refreshInterval time.Duration) error {
-	// Create Kubernetes client
-	client, err := k8s.NewClient(kubeconfig)
+// flagPassed reports whether the named flag was set on the command line.
+func flagPassed(name string) bool {
+	passed := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			passed = true
+		}
+	})
+	return passed
+}
+
+// jitter returns interval moved by a random offset of up to fraction of it either way, so
+// the next refresh of instances started together drifts apart.
+func jitter(interval time.Duration, fraction float64) time.Duration {
+	if fraction <= 0 {
+		return interval
+	}
+	return interval + time.Duration((rand.Float64()*2-1)*fraction*float64(interval))
+}
+
+// setupLogging installs the default slog logger, writing text records at or above
+// level to stderr.
+func setupLogging(level string) error {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("invalid --log-level %q: %w", level, err)
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: l})))
+	return nil
+}
+
+func run(cfg config) error {
+	// Create the renderer up front so a broken custom template fails fast
+	renderer, err := newRenderer(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to create kubernetes client: %w", err)
+		return err
+	}
+	// Name the default output after the format, e.g. network-map.dot
+	if !flagPassed("output") {
+		cfg.outputFile = strings.TrimSuffix(defaultOutputFile, filepath.Ext(defaultOutputFile)) + renderer.FileExtension()
 	}
 
-	// Parse namespaces
-	nsList := k8s.ParseNamespaces(namespaces)
+	// Likewise check the TLS files and auth flags before scanning
+	if err := validateTLS(cfg); err != nil {
+		return err
+	}
+	if err := validateAuth(cfg); err != nil {
+		return err
+	}
+	if cfg.useCache && cfg.cacheDir == "" {
+		return fmt.Errorf("--use-cache requires --cache-dir")
+	}
+	if cfg.refreshJitter < 0 || cfg.refreshJitter >= 1 {
+		return fmt.Errorf("--refresh-jitter must be at least 0 and less than 1, got %v", cfg.refreshJitter)
+	}
+	if err := validateOutputPath(cfg.outputFile); err != nil {
+		return fmt.Errorf("invalid --output: %w", err)
+	}
+	gate, err := newWarningGate(cfg)
+	if err != nil {
+		return err
+	}
+	if gate.enabled && cfg.serve {
+		return fmt.Errorf("--fail-on-warnings can't be combined with --serve")
+	}
+	if cfg.watch && (!cfg.serve || len(cfg.inputs) > 0) {
+		return fmt.Errorf("--watch requires --serve and a cluster to watch")
+	}
+
+	// Create a Kubernetes client per context (or one for the current context),
+	// unless reading manifests offline
+	var clients []*k8s.Client
+	if len(cfg.inputs) == 0 {
+		clients, err = newClients(cfg)
+		if err != nil {
+			return err
+		}
+	}
 
 	// Generate the initial map
-	if err := generateMap(client, nsList, outputFile); err != nil {
+	if err := generateMap(clients, renderer, cfg); err != nil {
 		return err
 	}
 
-	// If not serving, we're done
-	if !serve {
-		return nil
+	// If not serving, we're done once the warnings pass the gate
+	if !cfg.serve {
+		graphMutex.RLock()
+		defer graphMutex.RUnlock()
+		return gate.check(currentGraph)
 	}
 
-	// Start background refresh
+	// Start background refresh. Timer and watch refreshes run one at a time.
+	var refreshMutex sync.Mutex
+	refresh := func() {
+		refreshMutex.Lock()
+		defer refreshMutex.Unlock()
+		slog.Info("refreshing network map")
+		if err := generateMap(clients, renderer, cfg); err != nil {
+			slog.Error("failed to refresh map", "error", err)
+			graphMutex.Lock()
+			lastRefreshErr = err
+			graphMutex.Unlock()
+		}
+	}
 	go func() {
-		ticker := time.NewTicker(refreshInterval)
-		defer ticker.Stop()
-		for range ticker.C {
-			fmt.Printf("Refreshing network map...\n")
-			if err := generateMap(client, nsList, outputFile); err != nil {
-				fmt.Fprintf(os.Stderr, "Error refreshing map: %v\n", err)
-			}
+		for {
+			time.Sleep(jitter(cfg.refreshInterval, cfg.refreshJitter))
+			refresh()
 		}
 	}()
+	if cfg.watch {
+		go startWatches(clients, cfg, refresh)
+	}
 
 	// Serve the HTML file
+	outputFile := cfg.outputFile
 	dir := filepath.Dir(outputFile)
 	file := filepath.Base(outputFile)
 
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path == "/" || r.URL.Path == "/"+file {
+			w.Header().Set("Content-Type", renderer.ContentType())
 			http.ServeFile(w, r, outputFile)
 		} else {
 			http.NotFound(w, r)
 		}
 	})
 
-	// Health check endpoint
+	// Health check endpoint (liveness only)
 	http.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("ok"))
 	})
 
+	// Readiness endpoint: ready once a map exists and refreshes haven't been failing for too long
+	http.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		graphMutex.RLock()
+		g, refreshed, refreshErr := currentGraph, lastRefresh, lastRefreshErr
+		graphMutex.RUnlock()
+
+		if g == nil {
+			http.Error(w, "Graph not yet generated", http.StatusServiceUnavailable)
+			return
+		}
+		if refreshErr != nil && time.Since(refreshed) > cfg.readyThreshold {
+			http.Error(w, fmt.Sprintf("Refresh failing since %s: %v", refreshed.Format(time.RFC3339), refreshErr), http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	})
+
+	// Status endpoint: graph stats and refresh state for dashboards
+	http.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		graphMutex.RLock()
+		m, refreshed, refreshErr := currentRun, lastRefresh, lastRefreshErr
+		graphMutex.RUnlock()
+
+		if m == nil {
+			http.Error(w, "Graph not yet generated", http.StatusServiceUnavailable)
+			return
+		}
+
+		status := serveStatus{
+			Nodes:       m.Counts.Nodes,
+			Edges:       m.Counts.Edges,
+			Warnings:    m.Counts.Warnings,
+			LastRefresh: refreshed.UTC(),
+			Namespaces:  m.Namespaces,
+		}
+		if refreshErr != nil {
+			status.LastError = refreshErr.Error()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(status)
+	})
+
+	// Baseline pinning: POST pins the current graph, DELETE clears the pin
+	http.HandleFunc("/api/pin", func(w http.ResponseWriter, r *http.Request) {
+		graphMutex.Lock()
+		switch r.Method {
+		case http.MethodPost:
+			if currentGraph == nil {
+				graphMutex.Unlock()
+				http.Error(w, "Graph not yet generated", http.StatusServiceUnavailable)
+				return
+			}
+			baselineGraph = currentGraph
+		case http.MethodDelete:
+			baselineGraph = nil
+		default:
+			graphMutex.Unlock()
+			w.Header().Set("Allow", "POST, DELETE")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		graphMutex.Unlock()
+
+		if err := writeCurrentMap(renderer, cfg.outputFile); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		mapUpdates.notify()
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	// Current graph as rendered into the page, fetched by the page on live updates
+	http.HandleFunc("/api/graph", func(w http.ResponseWriter, r *http.Request) {
+		graphMutex.RLock()
+		g, baseline := currentGraph, baselineGraph
+		graphMutex.RUnlock()
+
+		if g == nil {
+			http.Error(w, "Graph not yet generated", http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(graph.MarkDiff(baseline, g))
+	})
+
+	// The current graph in every output format, rendered on demand (e.g. /graph.dot)
+	for _, format := range render.FormatNames() {
+		formatCfg := cfg
+		formatCfg.format = format
+		formatRenderer, err := newRenderer(formatCfg)
+		if err != nil {
+			return err
+		}
+		filename := "graph" + formatRenderer.FileExtension()
+		http.HandleFunc("/graph."+format, func(w http.ResponseWriter, r *http.Request) {
+			graphMutex.RLock()
+			g := currentGraph
+			graphMutex.RUnlock()
+
+			if g == nil {
+				http.Error(w, "Graph not yet generated", http.StatusServiceUnavailable)
+				return
+			}
+
+			out, err := formatRenderer.Render(g)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", formatRenderer.ContentType())
+			w.Header().Set("Content-Disposition", "attachment; filename="+filename)
+			w.Write([]byte(out))
+		})
+	}
+
+	// Live update stream: an "update" event each time the map is regenerated
+	http.HandleFunc("/events", serveEvents)
+
+	// Manifest of the run that produced the current map
+	http.HandleFunc("/api/run", func(w http.ResponseWriter, r *http.Request) {
+		graphMutex.RLock()
+		m := currentRun
+		graphMutex.RUnlock()
+
+		if m == nil {
+			http.Error(w, "Graph not yet generated", http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(m)
+	})
+
 	// Warnings CSV endpoint
 	http.HandleFunc("/warnings.csv", func(w http.ResponseWriter, r *http.Request) {
 		graphMutex.RLock()
@@ -133,96 +487,522 @@ func run(kubeconfig, outputFile, namespaces string, serve bool, port string, ref
 
 		// Write data
 		for _, wd := range g.WarningDetails {
-			// Extract policy name without namespace prefix
-			policyName := wd.PolicyName
-			if idx := len(wd.Namespace) + 1; idx < len(policyName) {
-				policyName = policyName[idx:]
-			}
-
-			// Get warning description
-			var description string
-			switch wd.WarningType {
-			case graph.WarningNoPorts:
-				description = "Rule allows all ports (no port restriction)"
-			case graph.WarningNoSelector:
-				description = "Rule allows from all sources (no selector)"
-			default:
-				description = string(wd.WarningType)
+			// Extract policy names without namespace prefix (conflicts name two policies)
+			policyNames := strings.Split(wd.PolicyName, ", ")
+			for i, name := range policyNames {
+				policyNames[i] = strings.TrimPrefix(name, wd.Namespace+"/")
 			}
+			policyName := strings.Join(policyNames, ", ")
 
 			csvWriter.Write([]string{
 				wd.WorkloadName,
 				wd.Namespace,
 				policyName,
 				string(wd.WarningType),
-				description,
+				warningDescription(wd),
 			})
 		}
 	})
 
-	fmt.Printf("Serving network map at http://0.0.0.0:%s/ (refresh every %v)\n", port, refreshInterval)
-	fmt.Printf("Serving from directory: %s\n", dir)
-	return http.ListenAndServe(":"+port, nil)
+	// Edges CSV export endpoint: every allowed connection
+	http.HandleFunc("/edges.csv", func(w http.ResponseWriter, r *http.Request) {
+		graphMutex.RLock()
+		g := currentGraph
+		graphMutex.RUnlock()
+
+		if g == nil {
+			http.Error(w, "Graph not yet generated", http.StatusServiceUnavailable)
+			return
+		}
+
+		out, err := render.NewEdgesCSVRenderer().Render(g)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", "attachment; filename=edges.csv")
+		w.Write([]byte(out))
+	})
+
+	handler := http.Handler(http.DefaultServeMux)
+	if cfg.authUser != "" {
+		handler = requireBasicAuth(handler, cfg.authUser, cfg.authPass)
+	}
+
+	if cfg.tlsCert != "" {
+		slog.Info("serving network map", "url", "https://0.0.0.0:"+cfg.port+"/", "refresh", cfg.refreshInterval, "dir", dir)
+		return http.ListenAndServeTLS(":"+cfg.port, cfg.tlsCert, cfg.tlsKey, handler)
+	}
+
+	slog.Info("serving network map", "url", "http://0.0.0.0:"+cfg.port+"/", "refresh", cfg.refreshInterval, "dir", dir)
+	return http.ListenAndServe(":"+cfg.port, handler)
+}
+
+// validateTLS checks that --tls-cert and --tls-key are given together and both exist.
+func validateTLS(cfg config) error {
+	if (cfg.tlsCert == "") != (cfg.tlsKey == "") {
+		return fmt.Errorf("--tls-cert and --tls-key must be set together")
+	}
+	for _, path := range []string{cfg.tlsCert, cfg.tlsKey} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("failed to read TLS file: %w", err)
+		}
+	}
+	return nil
+}
+
+// newRenderer returns the renderer for --format; the HTML renderer uses --template when
+// provided and starts in the --layout layout.
+func newRenderer(cfg config) (render.Renderer, error) {
+	if cfg.format != render.FormatHTML {
+		renderer, err := render.New(cfg.format)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --format: %w", err)
+		}
+		return renderer, nil
+	}
+
+	switch cfg.layout {
+	case render.LayoutGrid, render.LayoutHierarchical:
+	default:
+		return nil, fmt.Errorf("unknown --layout %q (want %s or %s)", cfg.layout, render.LayoutGrid, render.LayoutHierarchical)
+	}
+
+	if cfg.templateFile == "" {
+		renderer, err := render.NewHTMLRenderer()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create renderer: %w", err)
+		}
+		return renderer.WithLayout(cfg.layout).WithColorBy(cfg.colorBy), nil
+	}
+	renderer, err := render.NewHTMLRendererWithTemplate(cfg.templateFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load custom template: %w", err)
+	}
+	return renderer.WithLayout(cfg.layout).WithColorBy(cfg.colorBy), nil
+}
+
+// newClients creates one client per --context, or a single client for the current context.
+func newClients(cfg config) ([]*k8s.Client, error) {
+	// Validate the selector before touching the cluster
+	selector, err := labels.Parse(cfg.selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --selector %q: %w", cfg.selector, err)
+	}
+
+	opts := k8s.ClientOptions{QPS: float32(cfg.qps), Burst: cfg.burst}
+	if len(cfg.contexts) == 0 {
+		client, err := k8s.NewClient(cfg.kubeconfig, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create kubernetes client: %w", err)
+		}
+		client.WithIgnoreAnnotation(cfg.respectIgnore).
+			WithLabelSelector(selector.String()).
+			WithInitContainerPorts(cfg.initPorts).
+			WithTimeout(cfg.timeout).
+			WithConcurrency(cfg.concurrency).
+			WithSkipForbidden(!cfg.strict).
+			WithLogger(slog.With("context", client.Context()))
+		return []*k8s.Client{client}, nil
+	}
+
+	clients := make([]*k8s.Client, 0, len(cfg.contexts))
+	for _, contextName := range cfg.contexts {
+		client, err := k8s.NewClientForContext(cfg.kubeconfig, contextName, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create kubernetes client for context %s: %w", contextName, err)
+		}
+		client.WithIgnoreAnnotation(cfg.respectIgnore).
+			WithLabelSelector(selector.String()).
+			WithInitContainerPorts(cfg.initPorts).
+			WithTimeout(cfg.timeout).
+			WithConcurrency(cfg.concurrency).
+			WithSkipForbidden(!cfg.strict).
+			WithLogger(slog.With("context", client.Context()))
+		clients = append(clients, client)
+	}
+	return clients, nil
+}
+
+// resolveNamespaces returns the namespaces to scan through client, minus --exclude-namespaces.
+// With --all-namespaces the cluster is listed on every call, so refreshes pick up new namespaces.
+func resolveNamespaces(client *k8s.Client, cfg config) ([]string, error) {
+	nsList := k8s.ParseNamespaces(cfg.namespaces)
+	if cfg.allNamespaces {
+		var err error
+		nsList, err = client.ListAllNamespaces()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list all namespaces: %w", err)
+		}
+	}
+	return k8s.FilterNamespaces(nsList, k8s.ParseNamespaces(cfg.excludeNS)), nil
+}
+
+// generateMap builds the graph, then renders it to --output and records it for the server.
+func generateMap(clients []*k8s.Client, renderer render.Renderer, cfg config) error {
+	networkGraph, manifest, err := buildMap(clients, cfg)
+	if err != nil {
+		return err
+	}
+
+	// Store the graph for CSV export
+	graphMutex.Lock()
+	currentGraph = networkGraph
+	currentRun = manifest
+	graphMutex.Unlock()
+
+	// The refresh only counts as successful once the map is written
+	if err := writeCurrentMap(renderer, cfg.outputFile); err != nil {
+		graphMutex.Lock()
+		lastRefreshErr = err
+		graphMutex.Unlock()
+		return err
+	}
+	graphMutex.Lock()
+	lastRefresh = time.Now()
+	lastRefreshErr = nil
+	graphMutex.Unlock()
+
+	slog.Info("network map written", "path", cfg.outputFile)
+	mapUpdates.notify()
+
+	if cfg.runManifest != "" {
+		if err := writeRunManifest(manifest, cfg.runManifest); err != nil {
+			return err
+		}
+		slog.Info("run manifest written", "path", cfg.runManifest)
+	}
+	return nil
+}
+
+// buildMap scans the clusters, or reads --input manifests, then merges and truncates
+// the graph as configured. It returns the graph and the manifest describing the run.
+func buildMap(clients []*k8s.Client, cfg config) (*graph.NetworkGraph, *runManifest, error) {
+	var (
+		networkGraph *graph.NetworkGraph
+		contextName  string
+		scanned      []string
+		counts       runCounts
+		err          error
+	)
+	if len(cfg.inputs) > 0 {
+		networkGraph, scanned, counts, err = loadManifests(cfg)
+	} else {
+		networkGraph, contextName, scanned, counts, err = scanClusters(clients, cfg)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Collapse near-duplicate workloads into meta-nodes
+	if cfg.mergeBy != "" {
+		networkGraph = graph.MergeByLabel(networkGraph, cfg.mergeBy)
+		slog.Info("merged workloads", "label", cfg.mergeBy, "nodes", len(networkGraph.Nodes), "edges", len(networkGraph.Edges))
+	}
+
+	// Prune oversized graphs so the browser can still render them
+	networkGraph = graph.Truncate(networkGraph, cfg.maxNodes)
+	if t := networkGraph.Truncation; t != nil {
+		slog.Warn("graph truncated", "shownWorkloads", t.ShownWorkloads, "totalWorkloads", t.TotalWorkloads)
+	}
+
+	networkGraph.Context = contextName
+	return networkGraph, newRunManifest(contextName, scanned, counts, networkGraph), nil
+}
+
+// scanClusters scans each client's cluster and combines the results; with several
+// clusters, IDs are prefixed by context so they don't collide. It also returns the
+// comma-separated contexts, the union of scanned namespaces, and summed counts.
+func scanClusters(clients []*k8s.Client, cfg config) (*graph.NetworkGraph, string, []string, runCounts, error) {
+	graphs := make([]*graph.NetworkGraph, 0, len(clients))
+	contexts := make([]string, 0, len(clients))
+	var scanned []string
+	seen := make(map[string]bool)
+	var counts runCounts
+	for _, client := range clients {
+		// Name the failing context when scanning several
+		wrap := func(err error) error {
+			if len(clients) > 1 {
+				return fmt.Errorf("context %s: %w", client.Context(), err)
+			}
+			return err
+		}
+
+		nsList, err := resolveNamespaces(client, cfg)
+		if err != nil {
+			return nil, "", nil, runCounts{}, wrap(err)
+		}
+		for _, ns := range nsList {
+			if !seen[ns] {
+				seen[ns] = true
+				scanned = append(scanned, ns)
+			}
+		}
+
+		clusterGraph, clusterCounts, err := scanCluster(client, nsList, cfg)
+		if err != nil {
+			return nil, "", nil, runCounts{}, wrap(err)
+		}
+		if len(clients) > 1 {
+			clusterGraph = graph.WithCluster(clusterGraph, client.Context())
+		}
+		graphs = append(graphs, clusterGraph)
+		contexts = append(contexts, client.Context())
+		counts.Workloads += clusterCounts.Workloads
+		counts.NetworkPolicies += clusterCounts.NetworkPolicies
+		counts.IstioPolicies += clusterCounts.IstioPolicies
+		counts.CiliumPolicies += clusterCounts.CiliumPolicies
+	}
+
+	networkGraph := graphs[0]
+	if len(graphs) > 1 {
+		networkGraph = graph.Combine(graphs...)
+		slog.Info("combined clusters", "clusters", len(graphs), "nodes", len(networkGraph.Nodes), "edges", len(networkGraph.Edges))
+	}
+	return networkGraph, strings.Join(contexts, ","), scanned, counts, nil
+}
+
+// loadManifests builds the graph from manifest files instead of a live cluster.
+func loadManifests(cfg config) (*graph.NetworkGraph, []string, runCounts, error) {
+	slog.Info("reading manifests", "paths", cfg.inputs)
+
+	// The same filters a cluster scan applies
+	snapshot, err := k8s.LoadFromManifests(cfg.inputs, k8s.ManifestOptions{
+		InitContainerPorts:      cfg.initPorts,
+		LabelSelector:           cfg.selector,
+		RespectIgnoreAnnotation: cfg.respectIgnore,
+		ExcludeNamespaces:       k8s.ParseNamespaces(cfg.excludeNS),
+	})
+	if err != nil {
+		return nil, nil, runCounts{}, fmt.Errorf("failed to load manifests: %w", err)
+	}
+
+	counts := countPolicies(snapshot.Policies)
+	counts.Workloads = len(snapshot.Workloads)
+	slog.Info("loaded manifests", "workloads", counts.Workloads, "networkPolicies", counts.NetworkPolicies, "istioPolicies", counts.IstioPolicies, "ciliumPolicies", counts.CiliumPolicies, "services", len(snapshot.Services))
+
+	nsList := make([]string, 0, len(snapshot.Namespaces))
+	for _, ns := range snapshot.Namespaces {
+		nsList = append(nsList, ns.Name)
+	}
+
+	networkGraph := graph.NewBuilder().WithNamespaceLabels(snapshot.Namespaces).WithServices(snapshot.Services).
+		WithBroadCIDRPrefix(cfg.broadCIDRPrefix).WithIstioRootNamespace(cfg.istioRootNamespace).
+		Build(snapshot.Workloads, snapshot.Policies)
+	slog.Info("generated graph", "nodes", len(networkGraph.Nodes), "edges", len(networkGraph.Edges))
+	return networkGraph, nsList, counts, nil
 }
 
-func generateMap(client *k8s.Client, nsList []string, outputFile string) error {
-	// Fetch workloads and policies
-	fmt.Printf("Scanning namespaces: %v\n", nsList)
+// scanCluster fetches workloads and policies through client and builds their graph. With
+// --cache-dir, fetched resources are cached; with --use-cache, a fresh cached snapshot is
+// used instead of the API.
+func scanCluster(client *k8s.Client, nsList []string, cfg config) (*graph.NetworkGraph, runCounts, error) {
+	start := time.Now()
+	log := slog.With("context", client.Context())
+
+	var cache *k8s.Cache
+	key := k8s.CacheKey(client.Context(), nsList, cfg.selector, fmt.Sprint(cfg.respectIgnore), fmt.Sprint(cfg.initPorts), cfg.istioRootNamespace)
+	if cfg.cacheDir != "" {
+		cache = k8s.NewCache(cfg.cacheDir, cfg.cacheTTL)
+	}
+
+	var snapshot *k8s.Snapshot
+	if cache != nil && cfg.useCache {
+		cached, ok, err := cache.Load(key)
+		if err != nil {
+			log.Warn("ignoring unreadable cache", "error", err)
+		} else if ok {
+			log.Info("using cached resources", "namespaces", nsList, "fetchedAt", cached.FetchedAt)
+			snapshot = cached
+		}
+	}
+	if snapshot == nil {
+		fetched, err := fetchSnapshot(client, nsList, cfg.istioRootNamespace)
+		if err != nil {
+			return nil, runCounts{}, err
+		}
+		snapshot = fetched
+		if cache != nil {
+			if err := cache.Save(key, snapshot); err != nil {
+				log.Warn("failed to cache resources", "error", err)
+			}
+		}
+	}
+
+	counts := countPolicies(snapshot.Policies)
+	counts.Workloads = len(snapshot.Workloads)
+
+	// Build the graph with namespace labels for proper namespace selector evaluation
+	builder := graph.NewBuilder().WithNamespaceLabels(snapshot.Namespaces).WithServices(snapshot.Services).
+		WithBroadCIDRPrefix(cfg.broadCIDRPrefix).WithIstioRootNamespace(cfg.istioRootNamespace)
+	networkGraph := builder.Build(snapshot.Workloads, snapshot.Policies)
+	log.Info("generated graph", "nodes", len(networkGraph.Nodes), "edges", len(networkGraph.Edges), "duration", time.Since(start))
+	return networkGraph, counts, nil
+}
+
+// fetchSnapshot fetches the namespaces' labels, workloads, policies and services through client.
+// The mesh-wide AuthorizationPolicies of istioRootNS are fetched even when it isn't scanned.
+func fetchSnapshot(client *k8s.Client, nsList []string, istioRootNS string) (*k8s.Snapshot, error) {
+	start := time.Now()
+	log := slog.With("context", client.Context())
+	log.Info("scanning namespaces", "namespaces", nsList)
 
 	// Get namespace labels for proper namespace selector matching
 	namespaceInfos, err := client.GetNamespaces(nsList)
 	if err != nil {
-		return fmt.Errorf("failed to get namespace info: %w", err)
+		return nil, fmt.Errorf("failed to get namespace info: %w", err)
 	}
 
-	workloads, err := client.GetWorkloads(nsList)
+	// Services let Istio rules that list service ports resolve to container ports
+	workloads, services, err := client.GetWorkloads(nsList)
 	if err != nil {
-		return fmt.Errorf("failed to get workloads: %w", err)
+		return nil, fmt.Errorf("failed to get workloads: %w", err)
 	}
-	fmt.Printf("Found %d workloads\n", len(workloads))
+	log.Debug("fetched workloads", "workloads", len(workloads), "duration", time.Since(start))
 
 	policies, err := client.GetPolicies(nsList)
 	if err != nil {
-		return fmt.Errorf("failed to get policies: %w", err)
+		return nil, fmt.Errorf("failed to get policies: %w", err)
+	}
+
+	if istioRootNS != "" && !slices.Contains(nsList, istioRootNS) {
+		rootPolicies, err := client.GetAuthorizationPolicies([]string{istioRootNS})
+		if err != nil {
+			// Like the scanned namespaces' policies, skipped when Istio isn't installed or readable
+			log.Warn("failed to list Istio root namespace AuthorizationPolicies", "namespace", istioRootNS, "error", err)
+		}
+		for _, ap := range rootPolicies {
+			policies = append(policies, k8s.Policy{
+				Name:            ap.Name,
+				Namespace:       ap.Namespace,
+				Type:            k8s.PolicyTypeIstioAuthorizationPolicy,
+				IstioAuthPolicy: ap,
+			})
+		}
+	}
+
+	peerAuths, err := client.GetPeerAuthentications(nsList)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get peer authentications: %w", err)
 	}
+	policies = append(policies, peerAuths...)
 
-	// Count policy types
-	var k8sPolicies, istioPolicies int
+	httpRoutes, err := client.GetHTTPRoutes(nsList)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HTTP routes: %w", err)
+	}
+	policies = append(policies, httpRoutes...)
+
+	ciliumPolicies, err := client.GetCiliumNetworkPolicies(nsList)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Cilium network policies: %w", err)
+	}
+	policies = append(policies, ciliumPolicies...)
+
+	// Summarize what the RBAC role couldn't list, one line per namespace
+	if forbidden := client.TakeForbidden(); len(forbidden) > 0 {
+		byNamespace := make(map[string][]string)
+		var namespaces []string
+		for _, r := range forbidden {
+			if _, ok := byNamespace[r.Namespace]; !ok {
+				namespaces = append(namespaces, r.Namespace)
+			}
+			byNamespace[r.Namespace] = append(byNamespace[r.Namespace], r.Resource)
+		}
+		log.Warn("skipped resources the RBAC role can't list; the map may be missing workloads or policies (use --strict to fail instead)", "namespaces", len(namespaces))
+		for _, ns := range namespaces {
+			log.Warn("skipped forbidden resources", "namespace", ns, "resources", strings.Join(byNamespace[ns], ","))
+		}
+	}
+
+	counts := countPolicies(policies)
+	log.Info("fetched resources", "workloads", len(workloads), "networkPolicies", counts.NetworkPolicies, "istioPolicies", counts.IstioPolicies, "peerAuthentications", len(peerAuths), "httpRoutes", len(httpRoutes), "ciliumNetworkPolicies", len(ciliumPolicies), "services", len(services), "duration", time.Since(start))
+	return &k8s.Snapshot{
+		FetchedAt:  time.Now(),
+		Namespaces: namespaceInfos,
+		Workloads:  workloads,
+		Policies:   policies,
+		Services:   services,
+	}, nil
+}
+
+// countPolicies returns the run counts of the K8s NetworkPolicies, Istio
+// AuthorizationPolicies and CiliumNetworkPolicies in policies.
+func countPolicies(policies []k8s.Policy) runCounts {
+	var counts runCounts
 	for _, p := range policies {
 		switch p.Type {
 		case k8s.PolicyTypeK8sNetworkPolicy:
-			k8sPolicies++
+			counts.NetworkPolicies++
 		case k8s.PolicyTypeIstioAuthorizationPolicy:
-			istioPolicies++
+			counts.IstioPolicies++
+		case k8s.PolicyTypeCiliumNetworkPolicy:
+			counts.CiliumPolicies++
 		}
 	}
-	fmt.Printf("Found %d K8s NetworkPolicies, %d Istio AuthorizationPolicies\n", k8sPolicies, istioPolicies)
+	return counts
+}
 
-	// Build the graph with namespace labels for proper namespace selector evaluation
-	builder := graph.NewBuilder().WithNamespaceLabels(namespaceInfos)
-	networkGraph := builder.Build(workloads, policies)
-	fmt.Printf("Generated graph with %d nodes and %d edges\n", len(networkGraph.Nodes), len(networkGraph.Edges))
+// writeCurrentMap writes the current graph, with changes against the pinned baseline
+// marked, to outputFile. Writes are serialized and each reads the graph and baseline once
+// it holds the lock, so a refresh and a pin racing can't leave an older map on disk.
+func writeCurrentMap(renderer render.Renderer, outputFile string) error {
+	outputMutex.Lock()
+	defer outputMutex.Unlock()
 
-	// Store the graph for CSV export
-	graphMutex.Lock()
-	currentGraph = networkGraph
-	graphMutex.Unlock()
+	graphMutex.RLock()
+	g, baseline := currentGraph, baselineGraph
+	graphMutex.RUnlock()
 
-	// Render to HTML
-	renderer, err := render.NewHTMLRenderer()
-	if err != nil {
-		return fmt.Errorf("failed to create renderer: %w", err)
+	if g == nil {
+		return nil
 	}
-	html, err := renderer.Render(networkGraph)
+	return writeMap(renderer, graph.MarkDiff(baseline, g), outputFile)
+}
+
+// writeMap renders the graph in the renderer's format and writes it to outputFile.
+func writeMap(renderer render.Renderer, g *graph.NetworkGraph, outputFile string) error {
+	out, err := renderer.Render(g)
 	if err != nil {
 		return fmt.Errorf("failed to render graph: %w", err)
 	}
 
-	// Write output file
-	if err := os.WriteFile(outputFile, []byte(html), 0644); err != nil {
+	if err := writeOutputFile(outputFile, []byte(out)); err != nil {
 		return fmt.Errorf("failed to write output file: %w", err)
 	}
+	return nil
+}
 
-	fmt.Printf("Network map written to: %s\n", outputFile)
+// validateOutputPath rejects an output path naming an existing directory.
+func validateOutputPath(path string) error {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return fmt.Errorf("%s is a directory", path)
+	}
 	return nil
 }
+
+// writeOutputFile writes data to path, first creating any missing parent directories so
+// scripted runs can write into fresh dated folders.
+func writeOutputFile(path string, data []byte) error {
+	if err := validateOutputPath(path); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// warningDescription explains a warning for the CSV report, adding what triggered it when
+// the builder recorded that.
+func warningDescription(wd graph.WarningDetail) string {
+	if wd.Description == "" {
+		return wd.WarningType.Description()
+	}
+	return wd.WarningType.Description() + ": " + wd.Description
+}
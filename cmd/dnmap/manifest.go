@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/ddl-r-abdulaziz/dnmap/pkg/graph"
+)
+
+// version is the dnmap version, set at build time with -ldflags "-X main.version=...".
+var version = "dev"
+
+// runManifest describes what a run scanned and with which options, so an archived
+// map can be traced back to its inputs and the tool version that produced it.
+type runManifest struct {
+	Version    string            `json:"version"`
+	Timestamp  time.Time         `json:"timestamp"`
+	Context    string            `json:"context"`
+	Namespaces []string          `json:"namespaces"`
+	Options    map[string]string `json:"options"` // All command-line flags and their effective values
+	Counts     runCounts         `json:"counts"`
+}
+
+// runCounts summarizes the resources found and the graph produced by a run.
+type runCounts struct {
+	Workloads       int `json:"workloads"`
+	NetworkPolicies int `json:"networkPolicies"`
+	IstioPolicies   int `json:"istioPolicies"`
+	CiliumPolicies  int `json:"ciliumPolicies"`
+	Nodes           int `json:"nodes"`
+	Edges           int `json:"edges"`
+	Warnings        int `json:"warnings"`
+}
+
+// secretFlags are flags whose values are never written to a manifest.
+var secretFlags = map[string]bool{"auth-pass": true}
+
+// newRunManifest builds the manifest for a run that produced g.
+func newRunManifest(context string, nsList []string, counts runCounts, g *graph.NetworkGraph) *runManifest {
+	options := make(map[string]string)
+	flag.VisitAll(func(f *flag.Flag) {
+		options[f.Name] = f.Value.String()
+		if secretFlags[f.Name] && options[f.Name] != "" {
+			options[f.Name] = "<redacted>"
+		}
+	})
+
+	counts.Nodes = len(g.Nodes)
+	counts.Edges = len(g.Edges)
+	counts.Warnings = len(g.WarningDetails)
+
+	return &runManifest{
+		Version:    version,
+		Timestamp:  time.Now().UTC(),
+		Context:    context,
+		Namespaces: nsList,
+		Options:    options,
+		Counts:     counts,
+	}
+}
+
+// writeRunManifest writes the manifest as indented JSON to path.
+func writeRunManifest(m *runManifest, path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal run manifest: %w", err)
+	}
+	if err := writeOutputFile(path, data); err != nil {
+		return fmt.Errorf("failed to write run manifest: %w", err)
+	}
+	return nil
+}
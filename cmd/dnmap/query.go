@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ddl-r-abdulaziz/dnmap/pkg/graph"
+)
+
+// reachabilityQuery is a parsed --query: can source reach target on port (0 = any port)?
+type reachabilityQuery struct {
+	source string
+	target string
+	port   int32
+}
+
+// parseQuery parses --query "source,target,port", where source and target are workload
+// IDs (namespace/name) and port is a number or "*" for any port.
+func parseQuery(s string) (reachabilityQuery, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 3 {
+		return reachabilityQuery{}, fmt.Errorf("invalid --query %q (want source,target,port, e.g. apps/web,apps/db,5432)", s)
+	}
+
+	q := reachabilityQuery{source: strings.TrimSpace(parts[0]), target: strings.TrimSpace(parts[1])}
+	if port := strings.TrimSpace(parts[2]); port != "*" {
+		n, err := strconv.ParseInt(port, 10, 32)
+		if err != nil || n < 0 {
+			return reachabilityQuery{}, fmt.Errorf("invalid --query port %q (want a number or *)", port)
+		}
+		q.port = int32(n)
+	}
+	return q, nil
+}
+
+// runQuery builds the graph and prints whether the --query source may reach its target,
+// with the policies granting or denying each matching connection.
+func runQuery(cfg config) error {
+	q, err := parseQuery(cfg.query)
+	if err != nil {
+		return err
+	}
+
+	g, err := buildGraph(cfg)
+	if err != nil {
+		return err
+	}
+	if err := requireWorkloads(g, q.source, q.target); err != nil {
+		return err
+	}
+
+	if !graph.Reachable(g, q.source, q.target, q.port) {
+		fmt.Printf("deny %s -> %s\n", q.source, q.target)
+		for _, e := range graph.DenyingEdges(g, q.source, q.target, q.port) {
+			fmt.Printf("  %s denied by %s\n", e.Label, strings.Join(edgePolicies(e), ", "))
+		}
+		return nil
+	}
+
+	fmt.Printf("allow %s -> %s\n", q.source, q.target)
+	edges := graph.ReachingEdges(g, q.source, q.target, q.port)
+	if len(edges) == 0 {
+		fmt.Printf("  no policy selects %s, so all ingress is allowed\n", q.target)
+	}
+	for _, e := range edges {
+		fmt.Printf("  %s granted by %s\n", e.Label, strings.Join(edgePolicies(e), ", "))
+	}
+	return nil
+}
+
+// requireWorkloads returns an error naming the first ID that isn't a workload node of the
+// graph, so a mistyped workload isn't answered as if it were uncovered or unreachable.
+func requireWorkloads(g *graph.NetworkGraph, ids ...string) error {
+	workloads := make(map[string]bool)
+	for _, n := range g.Nodes {
+		if n.Type == graph.NodeTypeWorkload {
+			workloads[n.ID] = true
+		}
+	}
+	for _, id := range ids {
+		if !workloads[id] {
+			return fmt.Errorf("workload %q not found", id)
+		}
+	}
+	return nil
+}
+
+// edgePolicies returns the policies behind an edge, falling back to its single Policy.
+func edgePolicies(e graph.Edge) []string {
+	if len(e.Policies) > 0 {
+		return e.Policies
+	}
+	return []string{e.Policy}
+}
+
+// runTrace builds the graph and prints every allowed path from the --trace source to its
+// target, up to --trace-depth hops, with the policies granting each hop.
+func runTrace(cfg config) error {
+	parts := strings.Split(cfg.trace, ",")
+	if len(parts) != 2 {
+		return fmt.Errorf("invalid --trace %q (want source,target, e.g. apps/web,apps/db)", cfg.trace)
+	}
+	source, target := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+
+	g, err := buildGraph(cfg)
+	if err != nil {
+		return err
+	}
+	if err := requireWorkloads(g, source, target); err != nil {
+		return err
+	}
+
+	paths := graph.FindPaths(g, source, target, cfg.traceDepth)
+	if len(paths) == 0 {
+		fmt.Printf("no path %s -> %s within %d hops\n", source, target, cfg.traceDepth)
+		return nil
+	}
+
+	for i, path := range paths {
+		fmt.Printf("path %d: %s\n", i+1, strings.Join(path, " -> "))
+		for j := 1; j < len(path); j++ {
+			fmt.Printf("  %s -> %s via %s\n", path[j-1], path[j], strings.Join(hopPolicies(g, path[j-1], path[j]), ", "))
+		}
+	}
+	if len(paths) == graph.MaxPaths {
+		fmt.Printf("stopped after %d paths\n", graph.MaxPaths)
+	}
+	return nil
+}
+
+// hopPolicies returns the distinct policies granting any edge from source to target.
+func hopPolicies(g *graph.NetworkGraph, source, target string) []string {
+	var policies []string
+	seen := make(map[string]bool)
+	for _, e := range graph.ReachingEdges(g, source, target, 0) {
+		for _, name := range edgePolicies(e) {
+			if !seen[name] {
+				seen[name] = true
+				policies = append(policies, name)
+			}
+		}
+	}
+	return policies
+}
+
+// buildGraph scans the clusters, or reads --input manifests, and returns the graph
+// without merging, truncating or writing it.
+func buildGraph(cfg config) (*graph.NetworkGraph, error) {
+	if len(cfg.inputs) > 0 {
+		g, _, _, err := loadManifests(cfg)
+		return g, err
+	}
+
+	clients, err := newClients(cfg)
+	if err != nil {
+		return nil, err
+	}
+	g, _, _, _, err := scanClusters(clients, cfg)
+	return g, err
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/ddl-r-abdulaziz/dnmap/pkg/graph"
+)
+
+// runStats builds the graph and prints its summary to stdout instead of writing a map.
+func runStats(cfg config) error {
+	g, err := buildGraph(cfg)
+	if err != nil {
+		return err
+	}
+	return printStats(os.Stdout, graph.Summarize(g))
+}
+
+// printStats writes s as tab-aligned text: each total followed by its breakdown in
+// sorted order, so output is stable between runs.
+func printStats(out io.Writer, s graph.Summary) error {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+
+	printCounts(w, "Workloads", s.WorkloadsByKind)
+	fmt.Fprintf(w, "Ports\t%d\n", s.Ports)
+	printCounts(w, "Edges", s.EdgesByDirection)
+	printCounts(w, "Policies", s.PoliciesByType)
+
+	warnings := make(map[string]int, len(s.WarningsByType))
+	for t, n := range s.WarningsByType {
+		warnings[string(t)] = n
+	}
+	printCounts(w, "Warnings", warnings)
+
+	return w.Flush()
+}
+
+// printCounts writes the total of counts under title, then one indented row per key.
+func printCounts(w io.Writer, title string, counts map[string]int) {
+	keys := make([]string, 0, len(counts))
+	total := 0
+	for k, n := range counts {
+		keys = append(keys, k)
+		total += n
+	}
+	sort.Strings(keys)
+
+	fmt.Fprintf(w, "%s\t%d\n", title, total)
+	for _, k := range keys {
+		fmt.Fprintf(w, "  %s\t%d\n", k, counts[k])
+	}
+}
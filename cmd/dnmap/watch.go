@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/ddl-r-abdulaziz/dnmap/pkg/k8s"
+)
+
+// watchDebounce is how long --watch waits after the last change before regenerating the
+// map, so a burst of events such as a rollout causes a single refresh.
+const watchDebounce = 2 * time.Second
+
+// startWatches watches each client's workloads and policies and calls refresh after every
+// burst of changes. Clients that may not watch are logged and left to the --refresh timer.
+func startWatches(clients []*k8s.Client, cfg config, refresh func()) {
+	changed := debounce(watchDebounce, refresh)
+
+	// With --all-namespaces, watch everything so new namespaces are seen too
+	var namespaces []string
+	if !cfg.allNamespaces {
+		namespaces = k8s.FilterNamespaces(k8s.ParseNamespaces(cfg.namespaces), k8s.ParseNamespaces(cfg.excludeNS))
+	}
+
+	for _, client := range clients {
+		log := slog.With("context", client.Context())
+		if err := client.Watch(context.Background(), namespaces, changed); err != nil {
+			log.Warn("watch unavailable, falling back to polling", "interval", cfg.refreshInterval, "error", err)
+			continue
+		}
+		log.Info("watching for policy and workload changes")
+	}
+}
+
+// debounce returns a function that calls fn once wait has passed without another call.
+func debounce(wait time.Duration, fn func()) func() {
+	var mu sync.Mutex
+	var timer *time.Timer
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if timer == nil {
+			timer = time.AfterFunc(wait, fn)
+			return
+		}
+		timer.Reset(wait)
+	}
+}
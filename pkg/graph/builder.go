@@ -1,27 +1,69 @@
 package graph
 
 import (
+	"cmp"
 	"fmt"
+	"maps"
+	"net"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/ddl-r-abdulaziz/dnmap/pkg/k8s"
+	securityv1beta1 "istio.io/api/security/v1beta1"
+	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"sigs.k8s.io/yaml"
 )
 
 // Builder constructs network graphs from Kubernetes resources.
 type Builder struct {
 	namespaceLabels map[string]map[string]string // namespace name -> labels
+	services        map[string][]k8s.ServiceInfo // namespace name -> services
+	broadCIDRPrefix int                          // ipBlock prefixes this short or shorter are flagged
+	istioRootNS     string                       // AuthorizationPolicies here apply mesh-wide
+
+	// Kept by Build for AddPolicy and RemovePolicy
+	workloads     []k8s.Workload            // with Service-only ports added
+	workloadMap   map[string]k8s.Workload   // workloadID -> Workload
+	workloadsByNS map[string][]k8s.Workload // namespace -> []Workload
+	nodes         []Node                    // workload and port nodes
+	contributions []policyContribution      // in the order the policies were given
 }
 
+// DefaultBroadCIDRPrefix is the longest ipBlock prefix length flagged with WarningBroadCIDR
+// unless WithBroadCIDRPrefix says otherwise.
+const DefaultBroadCIDRPrefix = 8
+
+// DefaultIstioRootNamespace is the namespace of Istio's default mesh config rootNamespace.
+const DefaultIstioRootNamespace = "istio-system"
+
 // NewBuilder creates a new graph builder.
 func NewBuilder() *Builder {
 	return &Builder{
 		namespaceLabels: make(map[string]map[string]string),
+		services:        make(map[string][]k8s.ServiceInfo),
+		broadCIDRPrefix: DefaultBroadCIDRPrefix,
+		istioRootNS:     DefaultIstioRootNamespace,
 	}
 }
 
+// WithBroadCIDRPrefix sets the longest ipBlock prefix length flagged as overly broad, so the
+// default of 8 flags /0 through /8. A negative value turns the warning off.
+func (b *Builder) WithBroadCIDRPrefix(bits int) *Builder {
+	b.broadCIDRPrefix = bits
+	return b
+}
+
+// WithIstioRootNamespace sets the Istio root namespace, whose AuthorizationPolicies select
+// workloads in every namespace instead of their own. An empty namespace disables this.
+func (b *Builder) WithIstioRootNamespace(namespace string) *Builder {
+	b.istioRootNS = namespace
+	return b
+}
+
 // WithNamespaceLabels sets the namespace labels for proper namespace selector matching.
 func (b *Builder) WithNamespaceLabels(namespaces []k8s.NamespaceInfo) *Builder {
 	for _, ns := range namespaces {
@@ -30,66 +72,262 @@ func (b *Builder) WithNamespaceLabels(namespaces []k8s.NamespaceInfo) *Builder {
 	return b
 }
 
-// Build constructs a NetworkGraph from workloads and policies.
+// WithServices sets the Services used to map Service port numbers in Istio rules back to
+// workload container ports, and to add ports that a Service targets but the pod spec omits.
+func (b *Builder) WithServices(services []k8s.ServiceInfo) *Builder {
+	for _, svc := range services {
+		b.services[svc.Namespace] = append(b.services[svc.Namespace], svc)
+	}
+	return b
+}
+
+// Build constructs a NetworkGraph from workloads and policies. The builder keeps what each
+// policy contributed, so AddPolicy and RemovePolicy can update the graph afterwards.
 func (b *Builder) Build(workloads []k8s.Workload, policies []k8s.Policy) *NetworkGraph {
-	graph := &NetworkGraph{
-		Nodes:          make([]Node, 0),
-		Edges:          make([]Edge, 0),
-		WarningDetails: make([]WarningDetail, 0),
+	b.setWorkloads(workloads)
+	for _, policy := range policies {
+		b.contributions = append(b.contributions, b.contribution(policy))
 	}
+	return b.assemble()
+}
 
-	// Build maps for quick lookup
-	workloadMap := make(map[string]k8s.Workload)     // workloadID -> Workload
-	workloadsByNS := make(map[string][]k8s.Workload) // namespace -> []Workload
-	portNodes := make(map[string]Node)               // portID -> Node
-	nodeIndex := make(map[string]int)                // nodeID -> index in graph.Nodes
+// AddPolicy adds policy to the graph of the last Build, replacing the policy with the same
+// type, namespace and name if there is one, and returns the updated graph. Only policy's
+// own rules are evaluated; warnings that depend on every policy, such as coverage and
+// ALLOW/DENY conflicts, are recomputed from what the other policies contributed. Without a
+// previous Build, the graph has no workloads.
+func (b *Builder) AddPolicy(policy k8s.Policy) *NetworkGraph {
+	if b.workloadMap == nil {
+		b.setWorkloads(nil)
+	}
+	c := b.contribution(policy)
+	if i := slices.IndexFunc(b.contributions, func(x policyContribution) bool { return x.key == c.key }); i >= 0 {
+		b.contributions[i] = c // Keep its place, as a rebuild with the changed policy would
+	} else {
+		b.contributions = append(b.contributions, c)
+	}
+	return b.assemble()
+}
 
-	// Track warnings per workload (for node-level display)
-	workloadWarnings := make(map[string]map[WarningType]bool) // workloadID -> set of warnings
+// RemovePolicy removes the policy with policy's type, namespace and name from the graph of
+// the last Build and returns the updated graph.
+func (b *Builder) RemovePolicy(policy k8s.Policy) *NetworkGraph {
+	if b.workloadMap == nil {
+		b.setWorkloads(nil)
+	}
+	key := policyKey(policy)
+	b.contributions = slices.DeleteFunc(b.contributions, func(c policyContribution) bool { return c.key == key })
+	return b.assemble()
+}
 
-	// Create nodes for each workload and its ports
-	for _, w := range workloads {
-		wID := WorkloadID(w.Namespace, w.Name)
-		workloadMap[wID] = w
-		workloadsByNS[w.Namespace] = append(workloadsByNS[w.Namespace], w)
-		workloadWarnings[wID] = make(map[WarningType]bool)
+// policyContribution is what a single policy adds to the graph, kept between builds.
+type policyContribution struct {
+	key      string // see policyKey
+	policy   k8s.Policy
+	gateways []Node // Gateways that HTTPRoutes attach to
+	edges    []Edge // Before dedupe
+	details  []WarningDetail
+	warnings map[string]map[WarningType]bool // workloadID -> warnings, for node display
+	targets  []string                        // IDs of the workloads whose access the policy controls
+}
+
+// policyKey identifies a policy across updates.
+func policyKey(policy k8s.Policy) string {
+	return string(policy.Type) + "/" + policy.Namespace + "/" + policy.Name
+}
 
-		// Add workload node
-		nodeIndex[wID] = len(graph.Nodes)
-		graph.Nodes = append(graph.Nodes, NewWorkloadNode(w))
+// setWorkloads resets the builder to workloads, with their port nodes and no policies.
+func (b *Builder) setWorkloads(workloads []k8s.Workload) {
+	b.workloads = make([]k8s.Workload, 0, len(workloads))
+	b.workloadMap = make(map[string]k8s.Workload)
+	b.workloadsByNS = make(map[string][]k8s.Workload)
+	b.nodes = make([]Node, 0)
+	b.contributions = nil
 
-		// Add port nodes
+	portIDs := make(map[string]bool)
+	for _, w := range workloads {
+		wID := WorkloadID(w.Namespace, w.Name)
+		w, servicePorts := b.withServicePorts(w)
+		b.workloads = append(b.workloads, w)
+		b.workloadMap[wID] = w
+		b.workloadsByNS[w.Namespace] = append(b.workloadsByNS[w.Namespace], w)
+		b.nodes = append(b.nodes, NewWorkloadNode(w))
+
+		// Add port nodes; a port declared more than once (e.g. by two containers) gets one
+		// node, named after its first declaration
 		for _, p := range w.Ports {
 			portNode := NewPortNode(wID, p)
-			graph.Nodes = append(graph.Nodes, portNode)
-			portNodes[portNode.ID] = portNode
+			if portIDs[portNode.ID] {
+				continue
+			}
+			portNode.Namespace = w.Namespace // lets the UI group ports with their workload's namespace
+			if servicePorts[portNode.ID] {
+				portNode.Metadata = map[string]string{"source": "service"}
+			}
+			b.nodes = append(b.nodes, portNode)
+			portIDs[portNode.ID] = true
 		}
 	}
+}
 
-	// Process policies to create edges and detect warnings
-	edgeID := 0
-	for _, policy := range policies {
-		switch policy.Type {
-		case k8s.PolicyTypeK8sNetworkPolicy:
-			if policy.K8sNetworkPolicy != nil {
-				edges, warnings, details := b.processK8sNetworkPolicyWithWarnings(policy.K8sNetworkPolicy, workloadsByNS, workloadMap, &edgeID)
-				graph.Edges = append(graph.Edges, edges...)
-				graph.WarningDetails = append(graph.WarningDetails, details...)
-				// Merge warnings for node display
-				for wID, warnSet := range warnings {
-					for warn := range warnSet {
-						workloadWarnings[wID][warn] = true
-					}
+// contribution evaluates a single policy against the workloads.
+func (b *Builder) contribution(policy k8s.Policy) policyContribution {
+	c := policyContribution{key: policyKey(policy), policy: policy}
+	switch policy.Type {
+	case k8s.PolicyTypeK8sNetworkPolicy:
+		if policy.K8sNetworkPolicy != nil {
+			c.edges, c.warnings, c.details = b.processK8sNetworkPolicyWithWarnings(policy.K8sNetworkPolicy, b.workloadsByNS, b.workloadMap)
+		}
+	case k8s.PolicyTypeIstioAuthorizationPolicy:
+		if policy.IstioAuthPolicy != nil {
+			c.edges = b.processIstioAuthPolicy(policy.IstioAuthPolicy, b.workloadsByNS)
+		}
+	case k8s.PolicyTypeCiliumNetworkPolicy:
+		if policy.CiliumNetworkPolicy != nil {
+			c.edges, c.details = b.processCiliumNetworkPolicy(policy.CiliumNetworkPolicy, b.workloadsByNS)
+			c.warnings = make(map[string]map[WarningType]bool)
+			for _, d := range c.details {
+				if c.warnings[d.WorkloadID] == nil {
+					c.warnings[d.WorkloadID] = make(map[WarningType]bool)
 				}
+				c.warnings[d.WorkloadID][d.WarningType] = true
 			}
-		case k8s.PolicyTypeIstioAuthorizationPolicy:
-			if policy.IstioAuthPolicy != nil {
-				edges := b.processIstioAuthPolicy(policy.IstioAuthPolicy, workloadsByNS, &edgeID)
-				graph.Edges = append(graph.Edges, edges...)
+		}
+	case k8s.PolicyTypeHTTPRoute:
+		if policy.HTTPRoute != nil {
+			c.gateways, c.edges = b.processHTTPRoute(policy.HTTPRoute, b.workloadsByNS)
+		}
+	}
+	annotateSourceFile(c.edges, policy.SourceFile)
+	for _, w := range b.policyTargets(policy, b.workloadsByNS) {
+		c.targets = append(c.targets, WorkloadID(w.Namespace, w.Name))
+	}
+	return c
+}
+
+// assemble builds the graph from the workloads and the policies' contributions, adding the
+// warnings that depend on several policies. The contributions aren't modified, so they can
+// be assembled again after an update.
+func (b *Builder) assemble() *NetworkGraph {
+	graph := &NetworkGraph{
+		Nodes:          make([]Node, 0, len(b.nodes)),
+		Edges:          make([]Edge, 0),
+		WarningDetails: make([]WarningDetail, 0),
+		PolicyCounts:   make(map[string]int),
+	}
+
+	nodeIndex := make(map[string]int) // nodeID -> index in graph.Nodes
+
+	// Track warnings per workload (for node-level display)
+	workloadWarnings := make(map[string]map[WarningType]bool) // workloadID -> set of warnings
+
+	for _, n := range b.nodes {
+		n.Metadata = maps.Clone(n.Metadata)
+		if n.Type == NodeTypeWorkload {
+			nodeIndex[n.ID] = len(graph.Nodes)
+			workloadWarnings[n.ID] = make(map[WarningType]bool)
+		}
+		graph.Nodes = append(graph.Nodes, n)
+	}
+
+	// Collect each policy's edges and warnings
+	var peerAuths []*k8s.IstioPeerAuthentication
+	var istioEdges []Edge            // AuthorizationPolicy edges before dedupe, to compare ALLOW and DENY
+	covered := make(map[string]bool) // workloads that an access policy selects
+	for _, c := range b.contributions {
+		graph.PolicyCounts[string(c.policy.Type)]++
+		for _, gw := range c.gateways {
+			if _, ok := nodeIndex[gw.ID]; !ok {
+				gw.Metadata = maps.Clone(gw.Metadata)
+				nodeIndex[gw.ID] = len(graph.Nodes)
+				graph.Nodes = append(graph.Nodes, gw)
 			}
 		}
+		for _, e := range c.edges {
+			e.Policies = slices.Clone(e.Policies)
+			e.Operations = slices.Clone(e.Operations)
+			e.Metadata = maps.Clone(e.Metadata)
+			graph.Edges = append(graph.Edges, e)
+		}
+		if c.policy.Type == k8s.PolicyTypeIstioAuthorizationPolicy {
+			istioEdges = append(istioEdges, c.edges...)
+		}
+		if c.policy.Type == k8s.PolicyTypeIstioPeerAuthentication && c.policy.IstioPeerAuth != nil {
+			peerAuths = append(peerAuths, c.policy.IstioPeerAuth)
+		}
+		graph.WarningDetails = append(graph.WarningDetails, c.details...)
+		for wID, warnSet := range c.warnings {
+			for warn := range warnSet {
+				if workloadWarnings[wID] != nil {
+					workloadWarnings[wID][warn] = true
+				}
+			}
+		}
+		for _, wID := range c.targets {
+			covered[wID] = true
+		}
+	}
+
+	// Allow-all ingress edges come from the ANY node
+	if slices.ContainsFunc(graph.Edges, func(e Edge) bool { return e.Source == AnyNodeID }) {
+		graph.Nodes = append(graph.Nodes, NewAnyNode())
+	}
+
+	// Flag ALLOW policies that a DENY policy overrides for the same source and port
+	for _, d := range detectPolicyConflicts(istioEdges, portParents(graph), b.workloadMap) {
+		workloadWarnings[d.WorkloadID][d.WarningType] = true
+		graph.WarningDetails = append(graph.WarningDetails, d)
+	}
+
+	// Several policies may grant the same connection; draw it once
+	graph.Edges = dedupeEdges(graph.Edges)
+
+	// Record namespaces with a default-deny ingress posture
+	for _, c := range b.contributions {
+		if c.policy.Type == k8s.PolicyTypeK8sNetworkPolicy && isDefaultDenyIngress(c.policy.K8sNetworkPolicy) {
+			graph.WarningDetails = append(graph.WarningDetails, WarningDetail{
+				Namespace:   c.policy.Namespace,
+				PolicyName:  c.policy.Namespace + "/" + c.policy.Name,
+				WarningType: WarningDefaultDeny,
+			})
+		}
+	}
+
+	// Annotate workload nodes with their effective mTLS mode
+	b.applyMTLSModes(graph, peerAuths, b.workloadsByNS)
+
+	// Flag workloads that no access policy selects
+	for _, w := range b.workloads {
+		wID := WorkloadID(w.Namespace, w.Name)
+		if covered[wID] || workloadWarnings[wID][WarningUncovered] {
+			continue
+		}
+		workloadWarnings[wID][WarningUncovered] = true
+		graph.WarningDetails = append(graph.WarningDetails, WarningDetail{
+			WorkloadID:   wID,
+			WorkloadName: w.Name,
+			Namespace:    w.Namespace,
+			WarningType:  WarningUncovered,
+		})
 	}
 
+	// Flag host-networked workloads, whose traffic bypasses pod networking
+	for _, w := range b.workloads {
+		if !w.HostNetwork {
+			continue
+		}
+		wID := WorkloadID(w.Namespace, w.Name)
+		workloadWarnings[wID][WarningHostNetwork] = true
+		graph.WarningDetails = append(graph.WarningDetails, WarningDetail{
+			WorkloadID:   wID,
+			WorkloadName: w.Name,
+			Namespace:    w.Namespace,
+			WarningType:  WarningHostNetwork,
+		})
+	}
+
+	graph.Namespaces = b.namespaceNodes(b.workloadsByNS)
+
 	// Apply warnings to workload nodes
 	for wID, warnSet := range workloadWarnings {
 		if idx, ok := nodeIndex[wID]; ok && len(warnSet) > 0 {
@@ -97,96 +335,267 @@ func (b *Builder) Build(workloads []k8s.Workload, policies []k8s.Policy) *Networ
 			for warn := range warnSet {
 				warnings = append(warnings, warn)
 			}
+			slices.Sort(warnings)
 			graph.Nodes[idx].Warnings = warnings
 		}
 	}
 
+	// Reduce ignored workloads to stubs, or drop them if nothing references them
+	ignored := make(map[string]bool)
+	for _, w := range b.workloads {
+		if w.Ignored {
+			ignored[WorkloadID(w.Namespace, w.Name)] = true
+		}
+	}
+	if len(ignored) > 0 {
+		b.pruneIgnored(graph, ignored)
+	}
+
+	// Sort so the same input always renders the same output. Warnings are already applied,
+	// so nodeIndex is no longer needed.
+	slices.SortFunc(graph.Nodes, func(x, y Node) int {
+		return cmp.Or(
+			strings.Compare(string(x.Type), string(y.Type)),
+			strings.Compare(x.Namespace, y.Namespace),
+			strings.Compare(x.ID, y.ID),
+		)
+	})
+	slices.SortFunc(graph.Edges, func(x, y Edge) int { return strings.Compare(x.ID, y.ID) })
+
 	return graph
 }
 
-// BuildFromNetworkPolicies constructs a NetworkGraph using only K8s NetworkPolicies.
-// This is for backwards compatibility.
-func (b *Builder) BuildFromNetworkPolicies(workloads []k8s.Workload, netPolicies []networkingv1.NetworkPolicy) *NetworkGraph {
-	policies := make([]k8s.Policy, 0, len(netPolicies))
-	for i := range netPolicies {
-		policies = append(policies, k8s.Policy{
-			Name:             netPolicies[i].Name,
-			Namespace:        netPolicies[i].Namespace,
-			Type:             k8s.PolicyTypeK8sNetworkPolicy,
-			K8sNetworkPolicy: &netPolicies[i],
-		})
+// namespaceNodes lists every namespace with workloads or known labels, sorted by name.
+func (b *Builder) namespaceNodes(workloadsByNS map[string][]k8s.Workload) []NamespaceNode {
+	names := make(map[string]bool, len(workloadsByNS)+len(b.namespaceLabels))
+	for ns := range workloadsByNS {
+		names[ns] = true
 	}
-	return b.Build(workloads, policies)
+	for ns := range b.namespaceLabels {
+		names[ns] = true
+	}
+
+	namespaces := make([]NamespaceNode, 0, len(names))
+	for _, ns := range slices.Sorted(maps.Keys(names)) {
+		namespaces = append(namespaces, NamespaceNode{Name: ns, Labels: b.namespaceLabels[ns]})
+	}
+	return namespaces
 }
 
-// processK8sNetworkPolicy processes a K8s NetworkPolicy and returns edges.
-func (b *Builder) processK8sNetworkPolicy(policy *networkingv1.NetworkPolicy, workloadsByNS map[string][]k8s.Workload, edgeID *int) []Edge {
-	var edges []Edge
+// pruneIgnored removes ignored workloads from the graph. Ignored workloads that are still
+// referenced by an edge are kept as stub nodes with only their referenced ports, so
+// connectivity isn't silently lost.
+func (b *Builder) pruneIgnored(graph *NetworkGraph, ignored map[string]bool) {
+	referenced := make(map[string]bool) // workload and port IDs used by edges
+	for _, e := range graph.Edges {
+		referenced[e.Source] = true
+		referenced[e.Target] = true
+	}
+	for _, n := range graph.Nodes {
+		if n.Type == NodeTypePort && referenced[n.ID] {
+			referenced[n.Parent] = true
+		}
+	}
 
-	// Find workloads that this policy applies to (targets)
-	targetWorkloads := b.findMatchingWorkloads(policy.Namespace, policy.Spec.PodSelector, workloadsByNS)
+	nodes := graph.Nodes[:0]
+	for _, n := range graph.Nodes {
+		switch {
+		case n.Type == NodeTypeWorkload && ignored[n.ID]:
+			if !referenced[n.ID] {
+				continue
+			}
+			n.Stub = true
+			n.Warnings = nil
+		case n.Type == NodeTypePort && ignored[n.Parent]:
+			if !referenced[n.ID] {
+				continue
+			}
+		}
+		nodes = append(nodes, n)
+	}
+	graph.Nodes = nodes
+
+	details := graph.WarningDetails[:0]
+	for _, wd := range graph.WarningDetails {
+		if !ignored[wd.WorkloadID] {
+			details = append(details, wd)
+		}
+	}
+	graph.WarningDetails = details
+}
 
-	// Process ingress rules
-	for ruleIdx, ingressRule := range policy.Spec.Ingress {
-		// Find source workloads allowed by this rule
-		sourceWorkloads := b.findSourceWorkloads(policy.Namespace, ingressRule.From, workloadsByNS)
+// applyMTLSModes sets Metadata["mtlsMode"] on workload nodes covered by a PeerAuthentication.
+// A PeerAuthentication with a selector applies to the matching workloads and overrides a
+// namespace-wide one (no selector). An UNSET mode inherits and is skipped.
+func (b *Builder) applyMTLSModes(graph *NetworkGraph, peerAuths []*k8s.IstioPeerAuthentication, workloadsByNS map[string][]k8s.Workload) {
+	namespaceModes := make(map[string]string) // namespace -> mode
+	workloadModes := make(map[string]string)  // workloadID -> mode
 
-		// For each target workload
-		for _, targetW := range targetWorkloads {
-			targetWID := WorkloadID(targetW.Namespace, targetW.Name)
+	for _, pa := range peerAuths {
+		mode := pa.Spec.GetMtls().GetMode()
+		if mode == securityv1beta1.PeerAuthentication_MutualTLS_UNSET {
+			continue
+		}
 
-			// Determine which ports are allowed
-			allowedPorts := b.getAllowedPorts(targetW, ingressRule.Ports)
+		matchLabels := pa.Spec.GetSelector().GetMatchLabels()
+		if len(matchLabels) == 0 {
+			namespaceModes[pa.Namespace] = mode.String()
+			continue
+		}
+		for _, w := range b.findWorkloadsByLabels(pa.Namespace, matchLabels, workloadsByNS) {
+			workloadModes[WorkloadID(w.Namespace, w.Name)] = mode.String()
+		}
+	}
 
-			// Create edges from each source to each allowed port
-			for _, sourceW := range sourceWorkloads {
-				sourceWID := WorkloadID(sourceW.Namespace, sourceW.Name)
+	for i := range graph.Nodes {
+		node := &graph.Nodes[i]
+		if node.Type != NodeTypeWorkload {
+			continue
+		}
+		mode, ok := workloadModes[node.ID]
+		if !ok {
+			mode, ok = namespaceModes[node.Namespace]
+		}
+		if !ok {
+			continue
+		}
 
-				// Don't create self-referencing edges
-				if sourceWID == targetWID {
-					continue
-				}
+		if node.Metadata == nil {
+			node.Metadata = make(map[string]string)
+		}
+		node.Metadata["mtlsMode"] = mode
+	}
+}
 
-				// Generate policy YAML once per policy (elide managedFields)
-				policyYAML := ""
-				policyCopy := policy.DeepCopy()
-				policyCopy.ManagedFields = nil
-				if yamlBytes, err := yaml.Marshal(policyCopy); err == nil {
-					policyYAML = string(yamlBytes)
-				}
+// dedupeEdges merges edges with the same source, target, direction, policy type and action,
+// keeping the first and listing every contributing policy in Policies. Edges of different
+// kinds (an ALLOW and a DENY, or a NetworkPolicy and an AuthorizationPolicy) stay separate.
+// HTTP operations are combined, unless one of the edges allows every operation, in which
+// case the merged edge does too.
+func dedupeEdges(edges []Edge) []Edge {
+	type edgeKey struct{ source, target, direction, policyType, action string }
+	index := make(map[edgeKey]int)
+	result := edges[:0]
+
+	for _, e := range edges {
+		direction := e.Direction
+		if direction == "" {
+			direction = DirectionIngress
+		}
+		key := edgeKey{e.Source, e.Target, direction, e.Metadata["policyType"], e.Metadata["action"]}
 
-				for _, port := range allowedPorts {
-					protocol := string(port.Protocol)
-					if protocol == "" {
-						protocol = "TCP"
-					}
-					portID := PortID(targetWID, port.ContainerPort, protocol)
+		policies := e.Policies
+		if len(policies) == 0 {
+			policies = []string{e.Policy}
+		}
 
-					edge := Edge{
-						ID:         fmt.Sprintf("edge-%d", *edgeID),
-						Source:     sourceWID,
-						Target:     portID,
-						Label:      fmt.Sprintf("%s:%d", protocol, port.ContainerPort),
-						Rule:       b.formatK8sRule(ingressRule, ruleIdx),
-						Policy:     policy.Namespace + "/" + policy.Name,
-						PolicyYAML: policyYAML,
-						Metadata: map[string]string{
-							"policyType": "NetworkPolicy",
-							"ruleType":   "ingress",
-						},
-					}
-					edges = append(edges, edge)
-					*edgeID++
+		i, ok := index[key]
+		if !ok {
+			e.Policies = slices.Clone(policies)
+			index[key] = len(result)
+			result = append(result, e)
+			continue
+		}
+
+		merged := &result[i]
+		for _, p := range policies {
+			if !slices.Contains(merged.Policies, p) {
+				merged.Policies = append(merged.Policies, p)
+			}
+		}
+		if len(merged.Operations) > 0 && len(e.Operations) > 0 {
+			merged.Operations = append(slices.Clip(merged.Operations), e.Operations...)
+		} else {
+			merged.Operations = nil
+		}
+		if merged.Metadata != nil {
+			setOperationMetadata(merged.Metadata, merged.Operations)
+		}
+	}
+	return result
+}
+
+// detectPolicyConflicts reports workloads that an ALLOW and a DENY AuthorizationPolicy both
+// match for the same source and port. Istio evaluates DENY first, so the ALLOW never takes
+// effect there, which is easy to misread on the map. Each detail names both policies.
+func detectPolicyConflicts(istioEdges []Edge, portParent map[string]string, workloadMap map[string]k8s.Workload) []WarningDetail {
+	type access struct{ source, target string }
+	allows := make(map[access][]string)
+	denies := make(map[access][]string)
+	for _, e := range istioEdges {
+		key := access{e.Source, e.Target}
+		switch e.Metadata["action"] {
+		case securityv1beta1.AuthorizationPolicy_ALLOW.String():
+			if !slices.Contains(allows[key], e.Policy) {
+				allows[key] = append(allows[key], e.Policy)
+			}
+		case securityv1beta1.AuthorizationPolicy_DENY.String():
+			if !slices.Contains(denies[key], e.Policy) {
+				denies[key] = append(denies[key], e.Policy)
+			}
+		}
+	}
+
+	var details []WarningDetail
+	seen := make(map[string]bool) // workloadID|allow|deny
+	for key, denyPolicies := range denies {
+		wID := edgeTargetWorkload(Edge{Target: key.target}, portParent)
+		w, ok := workloadMap[wID]
+		if !ok {
+			continue
+		}
+		for _, allow := range allows[key] {
+			for _, deny := range denyPolicies {
+				if seen[wID+"|"+allow+"|"+deny] {
+					continue
 				}
+				seen[wID+"|"+allow+"|"+deny] = true
+				details = append(details, WarningDetail{
+					WorkloadID:   wID,
+					WorkloadName: w.Name,
+					Namespace:    w.Namespace,
+					PolicyName:   allow + ", " + deny,
+					WarningType:  WarningPolicyConflict,
+				})
 			}
 		}
 	}
+	slices.SortFunc(details, func(x, y WarningDetail) int {
+		return cmp.Or(strings.Compare(x.WorkloadID, y.WorkloadID), strings.Compare(x.PolicyName, y.PolicyName))
+	})
+	return details
+}
 
-	return edges
+// annotateSourceFile records the manifest file a policy was loaded from on its edges.
+func annotateSourceFile(edges []Edge, sourceFile string) {
+	if sourceFile == "" {
+		return
+	}
+	for i := range edges {
+		if edges[i].Metadata == nil {
+			edges[i].Metadata = make(map[string]string)
+		}
+		edges[i].Metadata["sourceFile"] = sourceFile
+	}
+}
+
+// BuildFromNetworkPolicies constructs a NetworkGraph using only K8s NetworkPolicies.
+// This is for backwards compatibility.
+func (b *Builder) BuildFromNetworkPolicies(workloads []k8s.Workload, netPolicies []networkingv1.NetworkPolicy) *NetworkGraph {
+	policies := make([]k8s.Policy, 0, len(netPolicies))
+	for i := range netPolicies {
+		policies = append(policies, k8s.Policy{
+			Name:             netPolicies[i].Name,
+			Namespace:        netPolicies[i].Namespace,
+			Type:             k8s.PolicyTypeK8sNetworkPolicy,
+			K8sNetworkPolicy: &netPolicies[i],
+		})
+	}
+	return b.Build(workloads, policies)
 }
 
 // processK8sNetworkPolicyWithWarnings processes a K8s NetworkPolicy and returns edges, warnings, and warning details.
-func (b *Builder) processK8sNetworkPolicyWithWarnings(policy *networkingv1.NetworkPolicy, workloadsByNS map[string][]k8s.Workload, workloadMap map[string]k8s.Workload, edgeID *int) ([]Edge, map[string]map[WarningType]bool, []WarningDetail) {
+func (b *Builder) processK8sNetworkPolicyWithWarnings(policy *networkingv1.NetworkPolicy, workloadsByNS map[string][]k8s.Workload, workloadMap map[string]k8s.Workload) ([]Edge, map[string]map[WarningType]bool, []WarningDetail) {
 	var edges []Edge
 	var warningDetails []WarningDetail
 	warnings := make(map[string]map[WarningType]bool)
@@ -204,14 +613,34 @@ func (b *Builder) processK8sNetworkPolicyWithWarnings(policy *networkingv1.Netwo
 		}
 	}
 
-	// Process ingress rules
-	for ruleIdx, ingressRule := range policy.Spec.Ingress {
+	// Generate policy YAML once per policy (elide managedFields)
+	policyYAML := ""
+	policyCopy := policy.DeepCopy()
+	policyCopy.ManagedFields = nil
+	if yamlBytes, err := yaml.Marshal(policyCopy); err == nil {
+		policyYAML = string(yamlBytes)
+	}
+
+	// Process ingress rules (skipped when the policy only governs egress)
+	var ingressRules []networkingv1.NetworkPolicyIngressRule
+	if policyAppliesTo(policy, networkingv1.PolicyTypeIngress) {
+		ingressRules = policy.Spec.Ingress
+	}
+	for ruleIdx, ingressRule := range ingressRules {
 		// Check for warnings
 		hasNoPorts := len(ingressRule.Ports) == 0
 		hasNoSelector := len(ingressRule.From) == 0
 
-		// Find source workloads allowed by this rule
-		sourceWorkloads := b.findSourceWorkloads(policy.Namespace, ingressRule.From, workloadsByNS)
+		// Find sources allowed by this rule; a rule without peers allows every source, drawn
+		// as a single edge from the ANY node rather than one from each workload
+		var sourceIDs []string
+		if hasNoSelector {
+			sourceIDs = []string{AnyNodeID}
+		} else {
+			for _, sourceW := range b.findSourceWorkloads(policy.Namespace, ingressRule.From, workloadsByNS) {
+				sourceIDs = append(sourceIDs, WorkloadID(sourceW.Namespace, sourceW.Name))
+			}
+		}
 
 		// For each target workload
 		for _, targetW := range targetWorkloads {
@@ -247,22 +676,12 @@ func (b *Builder) processK8sNetworkPolicyWithWarnings(policy *networkingv1.Netwo
 			allowedPorts := b.getAllowedPorts(targetW, ingressRule.Ports)
 
 			// Create edges from each source to each allowed port
-			for _, sourceW := range sourceWorkloads {
-				sourceWID := WorkloadID(sourceW.Namespace, sourceW.Name)
-
+			for _, sourceWID := range sourceIDs {
 				// Don't create self-referencing edges
 				if sourceWID == targetWID {
 					continue
 				}
 
-				// Generate policy YAML once per policy (elide managedFields)
-				policyYAML := ""
-				policyCopy := policy.DeepCopy()
-				policyCopy.ManagedFields = nil
-				if yamlBytes, err := yaml.Marshal(policyCopy); err == nil {
-					policyYAML = string(yamlBytes)
-				}
-
 				for _, port := range allowedPorts {
 					protocol := string(port.Protocol)
 					if protocol == "" {
@@ -271,30 +690,203 @@ func (b *Builder) processK8sNetworkPolicyWithWarnings(policy *networkingv1.Netwo
 					portID := PortID(targetWID, port.ContainerPort, protocol)
 
 					edge := Edge{
-						ID:         fmt.Sprintf("edge-%d", *edgeID),
+						ID:         EdgeID(sourceWID, portID, DirectionIngress, policyFullName, ruleIdx),
 						Source:     sourceWID,
 						Target:     portID,
 						Label:      fmt.Sprintf("%s:%d", protocol, port.ContainerPort),
 						Rule:       b.formatK8sRule(ingressRule, ruleIdx),
 						Policy:     policyFullName,
 						PolicyYAML: policyYAML,
+						Direction:  DirectionIngress,
 						Metadata: map[string]string{
 							"policyType": "NetworkPolicy",
 							"ruleType":   "ingress",
 						},
 					}
+					if hasNoSelector {
+						edge.Metadata["warning"] = string(WarningNoSelector)
+					}
 					edges = append(edges, edge)
-					*edgeID++
 				}
 			}
 		}
 	}
 
+	// Process egress rules: edges run from the selected workloads to the destination ports
+	if policyAppliesTo(policy, networkingv1.PolicyTypeEgress) {
+		edges = append(edges, b.processK8sEgressRules(policy, targetWorkloads, workloadsByNS)...)
+	}
+
+	// Flag ipBlock peers that open the selected workloads to most of the address space
+	var broad []string
+	for _, rule := range ingressRules {
+		for _, cidr := range b.broadCIDRs(rule.From) {
+			broad = append(broad, "allows ingress from "+cidr)
+		}
+	}
+	if policyAppliesTo(policy, networkingv1.PolicyTypeEgress) {
+		for _, rule := range policy.Spec.Egress {
+			for _, cidr := range b.broadCIDRs(rule.To) {
+				broad = append(broad, "allows egress to "+cidr)
+			}
+		}
+	}
+	slices.Sort(broad)
+	broad = slices.Compact(broad)
+	for _, description := range broad {
+		for _, targetW := range targetWorkloads {
+			targetWID := WorkloadID(targetW.Namespace, targetW.Name)
+			warnings[targetWID][WarningBroadCIDR] = true
+			warningDetails = append(warningDetails, WarningDetail{
+				WorkloadID:   targetWID,
+				WorkloadName: targetW.Name,
+				Namespace:    targetW.Namespace,
+				PolicyName:   policyFullName,
+				WarningType:  WarningBroadCIDR,
+				Description:  description,
+			})
+		}
+	}
+
 	return edges, warnings, warningDetails
 }
 
+// broadCIDRs returns the ipBlock CIDRs among peers whose prefix length is at most the
+// builder's broad-CIDR threshold. Unparseable CIDRs are ignored.
+func (b *Builder) broadCIDRs(peers []networkingv1.NetworkPolicyPeer) []string {
+	var cidrs []string
+	for _, peer := range peers {
+		if peer.IPBlock == nil {
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(peer.IPBlock.CIDR)
+		if err != nil {
+			continue
+		}
+		if ones, _ := ipNet.Mask.Size(); ones <= b.broadCIDRPrefix {
+			cidrs = append(cidrs, peer.IPBlock.CIDR)
+		}
+	}
+	return cidrs
+}
+
+// processK8sEgressRules creates edges from the workloads selected by a NetworkPolicy to the
+// ports of the destination workloads its egress rules allow.
+func (b *Builder) processK8sEgressRules(policy *networkingv1.NetworkPolicy, sourceWorkloads []k8s.Workload, workloadsByNS map[string][]k8s.Workload) []Edge {
+	var edges []Edge
+	if len(policy.Spec.Egress) == 0 {
+		return edges
+	}
+
+	policyFullName := policy.Namespace + "/" + policy.Name
+
+	// Generate policy YAML once per policy (elide managedFields)
+	policyYAML := ""
+	policyCopy := policy.DeepCopy()
+	policyCopy.ManagedFields = nil
+	if yamlBytes, err := yaml.Marshal(policyCopy); err == nil {
+		policyYAML = string(yamlBytes)
+	}
+
+	for ruleIdx, egressRule := range policy.Spec.Egress {
+		destWorkloads := b.findDestinationWorkloads(policy.Namespace, egressRule.To, workloadsByNS)
+
+		for _, destW := range destWorkloads {
+			destWID := WorkloadID(destW.Namespace, destW.Name)
+			allowedPorts := b.getAllowedPorts(destW, egressRule.Ports)
+
+			for _, sourceW := range sourceWorkloads {
+				sourceWID := WorkloadID(sourceW.Namespace, sourceW.Name)
+
+				// Don't create self-referencing edges
+				if sourceWID == destWID {
+					continue
+				}
+
+				for _, port := range allowedPorts {
+					protocol := string(port.Protocol)
+					if protocol == "" {
+						protocol = "TCP"
+					}
+					portID := PortID(destWID, port.ContainerPort, protocol)
+
+					edge := Edge{
+						ID:         EdgeID(sourceWID, portID, DirectionEgress, policyFullName, ruleIdx),
+						Source:     sourceWID,
+						Target:     portID,
+						Label:      fmt.Sprintf("%s:%d", protocol, port.ContainerPort),
+						Rule:       b.formatK8sEgressRule(egressRule, ruleIdx),
+						Policy:     policyFullName,
+						PolicyYAML: policyYAML,
+						Direction:  DirectionEgress,
+						Metadata: map[string]string{
+							"policyType": "NetworkPolicy",
+							"ruleType":   "egress",
+						},
+					}
+					edges = append(edges, edge)
+				}
+			}
+		}
+	}
+
+	return edges
+}
+
+// policyTargets returns the workloads a NetworkPolicy or AuthorizationPolicy applies to.
+// Other policy types don't control access and select nothing.
+func (b *Builder) policyTargets(policy k8s.Policy, workloadsByNS map[string][]k8s.Workload) []k8s.Workload {
+	switch policy.Type {
+	case k8s.PolicyTypeK8sNetworkPolicy:
+		if policy.K8sNetworkPolicy != nil {
+			return b.findMatchingWorkloads(policy.K8sNetworkPolicy.Namespace, policy.K8sNetworkPolicy.Spec.PodSelector, workloadsByNS)
+		}
+	case k8s.PolicyTypeIstioAuthorizationPolicy:
+		if policy.IstioAuthPolicy != nil {
+			return b.findIstioTargetWorkloads(policy.IstioAuthPolicy.Namespace, policy.IstioAuthPolicy.Spec.GetSelector(), workloadsByNS)
+		}
+	case k8s.PolicyTypeCiliumNetworkPolicy:
+		if policy.CiliumNetworkPolicy != nil {
+			var targets []k8s.Workload
+			for _, rule := range policy.CiliumNetworkPolicy.Rules() {
+				targets = append(targets, b.findCiliumEndpoints(policy.Namespace, rule.EndpointSelector, workloadsByNS)...)
+			}
+			return targets
+		}
+	}
+	return nil
+}
+
+// isDefaultDenyIngress reports whether a NetworkPolicy selects every pod in its namespace
+// and governs ingress without allowing any, making the namespace default-deny.
+func isDefaultDenyIngress(policy *networkingv1.NetworkPolicy) bool {
+	if policy == nil {
+		return false
+	}
+	selector := policy.Spec.PodSelector
+	if len(selector.MatchLabels) > 0 || len(selector.MatchExpressions) > 0 {
+		return false
+	}
+	return policyAppliesTo(policy, networkingv1.PolicyTypeIngress) && len(policy.Spec.Ingress) == 0
+}
+
+// policyAppliesTo reports whether a NetworkPolicy governs the given direction. Without
+// explicit policyTypes, Kubernetes treats every policy as ingress and as egress only when
+// it has egress rules.
+func policyAppliesTo(policy *networkingv1.NetworkPolicy, policyType networkingv1.PolicyType) bool {
+	if len(policy.Spec.PolicyTypes) == 0 {
+		return policyType == networkingv1.PolicyTypeIngress || len(policy.Spec.Egress) > 0
+	}
+	for _, t := range policy.Spec.PolicyTypes {
+		if t == policyType {
+			return true
+		}
+	}
+	return false
+}
+
 // processIstioAuthPolicy processes an Istio AuthorizationPolicy and returns edges.
-func (b *Builder) processIstioAuthPolicy(policy *k8s.IstioAuthorizationPolicy, workloadsByNS map[string][]k8s.Workload, edgeID *int) []Edge {
+func (b *Builder) processIstioAuthPolicy(policy *k8s.IstioAuthorizationPolicy, workloadsByNS map[string][]k8s.Workload) []Edge {
 	var edges []Edge
 
 	if policy == nil {
@@ -302,13 +894,7 @@ func (b *Builder) processIstioAuthPolicy(policy *k8s.IstioAuthorizationPolicy, w
 	}
 
 	// Find workloads that this policy applies to using the selector
-	var targetWorkloads []k8s.Workload
-	if policy.Spec.GetSelector() != nil && len(policy.Spec.GetSelector().GetMatchLabels()) > 0 {
-		targetWorkloads = b.findWorkloadsByLabels(policy.Namespace, policy.Spec.GetSelector().GetMatchLabels(), workloadsByNS)
-	} else {
-		// No selector means all workloads in the namespace
-		targetWorkloads = workloadsByNS[policy.Namespace]
-	}
+	targetWorkloads := b.findIstioTargetWorkloads(policy.Namespace, policy.Spec.GetSelector(), workloadsByNS)
 
 	// Process rules
 	for ruleIdx, rule := range policy.Spec.GetRules() {
@@ -319,20 +905,19 @@ func (b *Builder) processIstioAuthPolicy(policy *k8s.IstioAuthorizationPolicy, w
 		// Find source workloads from the 'from' section
 		sourceWorkloads := b.findIstioSourceWorkloads(policy.Namespace, rule.GetFrom(), workloadsByNS)
 
-		// Get operations (ports) from the 'to' section
-		allowedPorts := b.getIstioAllowedPorts(rule.GetTo())
+		// Get L7 methods/paths from the 'to' section
+		operations := b.getIstioHTTPOperations(rule.GetTo())
 
 		// For each target workload
 		for _, targetW := range targetWorkloads {
 			targetWID := WorkloadID(targetW.Namespace, targetW.Name)
 
-			// If no specific ports in the rule, use all ports of the workload
-			targetPorts := allowedPorts
-			if len(targetPorts) == 0 {
-				for _, p := range targetW.Ports {
-					targetPorts = append(targetPorts, int(p.ContainerPort))
-				}
+			// Resolve the rule's ports, numbered or named, against the workload's declared ports
+			allowedPorts, restricted := b.getIstioAllowedPorts(rule.GetTo(), targetW)
+			if restricted && len(allowedPorts) == 0 {
+				continue // Every listed port is a name the workload doesn't declare
 			}
+			targetPorts := b.resolveIstioPorts(targetW, allowedPorts)
 
 			// Generate policy YAML once per policy (elide managedFields)
 			policyYAML := ""
@@ -352,24 +937,25 @@ func (b *Builder) processIstioAuthPolicy(policy *k8s.IstioAuthorizationPolicy, w
 				}
 
 				for _, port := range targetPorts {
-					protocol := "TCP" // Istio primarily uses TCP
-					portID := PortID(targetWID, int32(port), protocol)
+					portID := PortID(targetWID, port.ContainerPort, string(port.Protocol))
 
 					edge := Edge{
-						ID:         fmt.Sprintf("edge-%d", *edgeID),
+						ID:         EdgeID(sourceWID, portID, DirectionIngress, policy.Namespace+"/"+policy.Name, ruleIdx),
 						Source:     sourceWID,
 						Target:     portID,
-						Label:      fmt.Sprintf("%s:%d", protocol, port),
+						Label:      istioPortLabel(port),
 						Rule:       b.formatIstioRule(rule, ruleIdx),
 						Policy:     policy.Namespace + "/" + policy.Name,
 						PolicyYAML: policyYAML,
+						Direction:  DirectionIngress,
+						Operations: operations,
 						Metadata: map[string]string{
 							"policyType": "AuthorizationPolicy",
 							"action":     policy.Spec.GetAction().String(),
 						},
 					}
+					setOperationMetadata(edge.Metadata, operations)
 					edges = append(edges, edge)
-					*edgeID++
 				}
 			}
 		}
@@ -378,6 +964,171 @@ func (b *Builder) processIstioAuthPolicy(policy *k8s.IstioAuthorizationPolicy, w
 	return edges
 }
 
+// resolveIstioPorts maps the port numbers of an Istio rule onto the workload's declared
+// ports so edges carry the real protocol. With no rule ports, all declared ports are used.
+// A rule port the workload doesn't declare is tried as a port of a Service selecting the
+// workload and mapped through its targetPort; failing that it falls back to TCP, Istio's default.
+func (b *Builder) resolveIstioPorts(w k8s.Workload, rulePorts []int) []k8s.Port {
+	var ports []k8s.Port
+	for _, p := range w.Ports {
+		if len(rulePorts) == 0 || containsPort(rulePorts, int(p.ContainerPort)) {
+			if p.Protocol == "" {
+				p.Protocol = corev1.ProtocolTCP
+			}
+			ports = append(ports, p)
+		}
+	}
+	for _, rp := range rulePorts {
+		declared := false
+		for _, p := range w.Ports {
+			if int(p.ContainerPort) == rp {
+				declared = true
+				break
+			}
+		}
+		if declared {
+			continue
+		}
+		if servicePorts := b.serviceTargetPorts(w, int32(rp)); len(servicePorts) > 0 {
+			ports = append(ports, servicePorts...)
+			continue
+		}
+		ports = append(ports, k8s.Port{ContainerPort: int32(rp), Protocol: corev1.ProtocolTCP})
+	}
+	return ports
+}
+
+// serviceTargetPorts returns the workload container ports that a Service port number
+// targets, considering only Services in the workload's namespace that select it.
+func (b *Builder) serviceTargetPorts(w k8s.Workload, servicePort int32) []k8s.Port {
+	var result []k8s.Port
+	for _, svc := range b.services[w.Namespace] {
+		if len(svc.Selector) == 0 || !b.labelsMatch(w.Labels, svc.Selector) {
+			continue
+		}
+		result = append(result, servicePortTargets(svc, w, servicePort)...)
+	}
+	return result
+}
+
+// withServicePorts returns w with a port for each port number that a Service selecting it
+// targets but its pod spec doesn't declare, and the IDs of those ports' nodes. Named target
+// ports only resolve to declared ports, so they add nothing.
+func (b *Builder) withServicePorts(w k8s.Workload) (k8s.Workload, map[string]bool) {
+	wID := WorkloadID(w.Namespace, w.Name)
+	synthesized := make(map[string]bool)
+	for _, svc := range b.services[w.Namespace] {
+		if len(svc.Selector) == 0 || !b.labelsMatch(w.Labels, svc.Selector) {
+			continue
+		}
+		for _, sp := range svc.Ports {
+			if sp.TargetPort.Type == intstr.String && sp.TargetPort.StrVal != "" {
+				continue
+			}
+			port := k8s.Port{Name: sp.Name, ContainerPort: sp.TargetPort.IntVal, Protocol: sp.Protocol,
+				ServiceName: svc.Name, ServicePort: sp.Port}
+			if port.ContainerPort == 0 {
+				port.ContainerPort = sp.Port // An unset targetPort defaults to the service port
+			}
+			if port.Protocol == "" {
+				port.Protocol = corev1.ProtocolTCP
+			}
+			if slices.ContainsFunc(w.Ports, func(p k8s.Port) bool {
+				return p.ContainerPort == port.ContainerPort && (p.Protocol == port.Protocol || p.Protocol == "" && port.Protocol == corev1.ProtocolTCP)
+			}) {
+				continue
+			}
+			if len(synthesized) == 0 {
+				w.Ports = slices.Clone(w.Ports) // The caller's workloads stay as given
+			}
+			w.Ports = append(w.Ports, port)
+			synthesized[PortID(wID, port.ContainerPort, string(port.Protocol))] = true
+		}
+	}
+	return w, synthesized
+}
+
+// servicePortTargets returns the workload container ports that a port of svc targets; a
+// zero servicePort means every port of svc. The caller checks that svc selects w.
+func servicePortTargets(svc k8s.ServiceInfo, w k8s.Workload, servicePort int32) []k8s.Port {
+	var result []k8s.Port
+	for _, sp := range svc.Ports {
+		if servicePort != 0 && sp.Port != servicePort {
+			continue
+		}
+		for _, p := range w.Ports {
+			if !targetPortMatches(sp, p) {
+				continue
+			}
+			if p.Protocol == "" {
+				p.Protocol = corev1.ProtocolTCP
+			}
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// targetPortMatches reports whether a Service port targets the given container port.
+func targetPortMatches(sp k8s.ServicePortInfo, p k8s.Port) bool {
+	switch {
+	case sp.TargetPort.Type == intstr.String && sp.TargetPort.StrVal != "":
+		return sp.TargetPort.StrVal == p.Name
+	case sp.TargetPort.IntVal != 0:
+		return sp.TargetPort.IntVal == p.ContainerPort
+	default:
+		// An unset targetPort defaults to the service port
+		return sp.Port == p.ContainerPort
+	}
+}
+
+// containsPort reports whether port is in ports.
+func containsPort(ports []int, port int) bool {
+	for _, p := range ports {
+		if p == port {
+			return true
+		}
+	}
+	return false
+}
+
+// istioPortLabel returns the edge label for an Istio-resolved port, preferring the
+// declared port name (e.g. "http:8080") over the protocol (e.g. "UDP:443").
+func istioPortLabel(port k8s.Port) string {
+	if port.Name != "" {
+		return fmt.Sprintf("%s:%d", port.Name, port.ContainerPort)
+	}
+	return fmt.Sprintf("%s:%d", port.Protocol, port.ContainerPort)
+}
+
+// findIstioTargetWorkloads resolves the workloads an Istio policy applies to. Istio's
+// WorkloadSelector is a plain label map with no set-based expressions:
+//   - a nil selector, or one with an empty matchLabels map, selects every workload in the
+//     policy's namespace;
+//   - otherwise a workload is selected only if it carries every key with the exact value.
+//
+// A policy in the Istio root namespace is mesh-wide: the same rules apply to the workloads
+// of every namespace.
+func (b *Builder) findIstioTargetWorkloads(namespace string, selector *k8s.IstioWorkloadSelector, workloadsByNS map[string][]k8s.Workload) []k8s.Workload {
+	if b.istioRootNS != "" && namespace == b.istioRootNS {
+		var result []k8s.Workload
+		for _, ns := range slices.Sorted(maps.Keys(workloadsByNS)) {
+			result = append(result, b.findIstioNamespaceTargets(ns, selector, workloadsByNS)...)
+		}
+		return result
+	}
+	return b.findIstioNamespaceTargets(namespace, selector, workloadsByNS)
+}
+
+// findIstioNamespaceTargets resolves the workloads an Istio selector picks in one namespace.
+func (b *Builder) findIstioNamespaceTargets(namespace string, selector *k8s.IstioWorkloadSelector, workloadsByNS map[string][]k8s.Workload) []k8s.Workload {
+	matchLabels := selector.GetMatchLabels()
+	if len(matchLabels) == 0 {
+		return workloadsByNS[namespace]
+	}
+	return b.findWorkloadsByLabels(namespace, matchLabels, workloadsByNS)
+}
+
 // findWorkloadsByLabels finds workloads that match the given labels.
 func (b *Builder) findWorkloadsByLabels(namespace string, labels map[string]string, workloadsByNS map[string][]k8s.Workload) []k8s.Workload {
 	var result []k8s.Workload
@@ -394,7 +1145,7 @@ func (b *Builder) findWorkloadsByLabels(namespace string, labels map[string]stri
 // labelsMatch checks if workload labels contain all the required labels.
 func (b *Builder) labelsMatch(workloadLabels, requiredLabels map[string]string) bool {
 	for key, value := range requiredLabels {
-		if workloadLabels[key] != value {
+		if actual, ok := workloadLabels[key]; !ok || actual != value {
 			return false
 		}
 	}
@@ -420,6 +1171,14 @@ func (b *Builder) findIstioSourceWorkloads(policyNamespace string, from []*k8s.I
 		return result
 	}
 
+	add := func(w k8s.Workload, source *securityv1beta1.Source) {
+		wID := WorkloadID(w.Namespace, w.Name)
+		if !seen[wID] && !istioSourceExcludes(source, w) {
+			result = append(result, w)
+			seen[wID] = true
+		}
+	}
+
 	for _, f := range from {
 		if f == nil || f.GetSource() == nil {
 			continue
@@ -429,16 +1188,18 @@ func (b *Builder) findIstioSourceWorkloads(policyNamespace string, from []*k8s.I
 
 		// Check principals (service accounts)
 		if len(source.GetPrincipals()) > 0 {
-			// Principals are in the format: cluster.local/ns/<namespace>/sa/<serviceaccount>
-			// For simplicity, we match workloads in namespaces mentioned in principals
+			// Principals are in the format: <trust-domain>/ns/<namespace>/sa/<serviceaccount>
+			// Match the workloads running as that service account, looking in every namespace
+			// when a wildcard covers the namespace
 			for _, principal := range source.GetPrincipals() {
-				ns := extractNamespaceFromPrincipal(principal)
-				if ns != "" {
+				namespaces := []string{principalNamespace(principal)}
+				if namespaces[0] == "" {
+					namespaces = slices.Sorted(maps.Keys(workloadsByNS))
+				}
+				for _, ns := range namespaces {
 					for _, w := range workloadsByNS[ns] {
-						wID := WorkloadID(w.Namespace, w.Name)
-						if !seen[wID] {
-							result = append(result, w)
-							seen[wID] = true
+						if principalMatches(principal, w) {
+							add(w, source)
 						}
 					}
 				}
@@ -449,24 +1210,17 @@ func (b *Builder) findIstioSourceWorkloads(policyNamespace string, from []*k8s.I
 		if len(source.GetNamespaces()) > 0 {
 			for _, ns := range source.GetNamespaces() {
 				for _, w := range workloadsByNS[ns] {
-					wID := WorkloadID(w.Namespace, w.Name)
-					if !seen[wID] {
-						result = append(result, w)
-						seen[wID] = true
-					}
+					add(w, source)
 				}
 			}
 		}
 
-		// If no specific principals or namespaces, check all workloads
+		// If no specific principals or namespaces, check all workloads (minus any
+		// notPrincipals/notNamespaces exclusions)
 		if len(source.GetPrincipals()) == 0 && len(source.GetNamespaces()) == 0 {
 			for _, workloads := range workloadsByNS {
 				for _, w := range workloads {
-					wID := WorkloadID(w.Namespace, w.Name)
-					if !seen[wID] {
-						result = append(result, w)
-						seen[wID] = true
-					}
+					add(w, source)
 				}
 			}
 		}
@@ -475,21 +1229,69 @@ func (b *Builder) findIstioSourceWorkloads(policyNamespace string, from []*k8s.I
 	return result
 }
 
-// extractNamespaceFromPrincipal extracts namespace from an Istio principal.
-func extractNamespaceFromPrincipal(principal string) string {
-	// Format: cluster.local/ns/<namespace>/sa/<serviceaccount>
-	parts := strings.Split(principal, "/")
-	for i, part := range parts {
-		if part == "ns" && i+1 < len(parts) {
-			return parts[i+1]
+// istioSourceExcludes reports whether a source's notNamespaces or notPrincipals rule out
+// the workload, which Istio subtracts from whatever the positive fields match.
+func istioSourceExcludes(source *securityv1beta1.Source, w k8s.Workload) bool {
+	if slices.Contains(source.GetNotNamespaces(), w.Namespace) {
+		return true
+	}
+	for _, principal := range source.GetNotPrincipals() {
+		if principalMatches(principal, w) {
+			return true
 		}
 	}
-	return ""
+	return false
+}
+
+// principalMatches reports whether an Istio principal names the workload's identity,
+// <trust-domain>/ns/<namespace>/sa/<service-account>. Like Istio, a principal may be "*",
+// or start or end with "*" to match by suffix or prefix. Pods without serviceAccountName
+// run as "default". The trust domain is taken from the principal, so only the namespace
+// and service account decide the match.
+func principalMatches(principal string, w k8s.Workload) bool {
+	sa := w.ServiceAccount
+	if sa == "" {
+		sa = "default"
+	}
+	trustDomain, _, _ := strings.Cut(principal, "/")
+	if strings.Contains(trustDomain, "*") {
+		trustDomain = "cluster.local"
+	}
+	return istioStringMatches(principal, trustDomain+"/ns/"+w.Namespace+"/sa/"+sa)
+}
+
+// principalNamespace returns the namespace at the fixed position of an Istio principal,
+// <trust-domain>/ns/<namespace>/sa/<service-account>, or "" when the principal doesn't
+// pin it down, e.g. "*" or "*/sa/web".
+func principalNamespace(principal string) string {
+	parts := strings.Split(principal, "/")
+	if len(parts) < 4 || parts[1] != "ns" || strings.Contains(strings.Join(parts[:3], "/"), "*") {
+		return ""
+	}
+	return parts[2]
+}
+
+// istioStringMatches matches value against an Istio string match pattern: "*" matches any
+// non-empty value, a leading "*" matches by suffix, a trailing "*" by prefix, and anything
+// else must be equal.
+func istioStringMatches(pattern, value string) bool {
+	switch {
+	case pattern == "*":
+		return value != ""
+	case strings.HasPrefix(pattern, "*"):
+		return strings.HasSuffix(value, pattern[1:])
+	case strings.HasSuffix(pattern, "*"):
+		return strings.HasPrefix(value, strings.TrimSuffix(pattern, "*"))
+	default:
+		return pattern == value
+	}
 }
 
-// getIstioAllowedPorts extracts allowed ports from Istio 'to' operations.
-func (b *Builder) getIstioAllowedPorts(to []*k8s.IstioOperation) []int {
-	var ports []int
+// getIstioAllowedPorts extracts allowed ports from Istio 'to' operations. Port names are
+// resolved against the target workload's declared ports and dropped when it has none by
+// that name. restricted reports whether the operations list any ports, so a rule whose
+// names all fail to resolve isn't mistaken for one allowing every port.
+func (b *Builder) getIstioAllowedPorts(to []*k8s.IstioOperation, target k8s.Workload) (ports []int, restricted bool) {
 	seen := make(map[int]bool)
 
 	for _, t := range to {
@@ -497,8 +1299,17 @@ func (b *Builder) getIstioAllowedPorts(to []*k8s.IstioOperation) []int {
 			continue
 		}
 		for _, portStr := range t.GetOperation().GetPorts() {
-			port := 0
-			fmt.Sscanf(portStr, "%d", &port)
+			restricted = true
+			port, err := strconv.Atoi(portStr)
+			if err != nil {
+				port = 0
+				for _, p := range target.Ports {
+					if p.Name == portStr {
+						port = int(p.ContainerPort)
+						break
+					}
+				}
+			}
 			if port > 0 && !seen[port] {
 				ports = append(ports, port)
 				seen[port] = true
@@ -506,7 +1317,53 @@ func (b *Builder) getIstioAllowedPorts(to []*k8s.IstioOperation) []int {
 		}
 	}
 
-	return ports
+	return ports, restricted
+}
+
+// getIstioHTTPOperations extracts the HTTP methods and paths from Istio 'to' operations.
+// Operations that restrict neither methods nor paths are omitted.
+func (b *Builder) getIstioHTTPOperations(to []*k8s.IstioOperation) []HTTPOperation {
+	var ops []HTTPOperation
+	for _, t := range to {
+		if t == nil || t.GetOperation() == nil {
+			continue
+		}
+		op := t.GetOperation()
+		if len(op.GetMethods()) == 0 && len(op.GetPaths()) == 0 {
+			continue
+		}
+		ops = append(ops, HTTPOperation{
+			Methods: op.GetMethods(),
+			Paths:   op.GetPaths(),
+		})
+	}
+	return ops
+}
+
+// setOperationMetadata records the distinct HTTP methods and paths of ops, comma-joined,
+// as the "methods" and "paths" metadata entries, removing an entry that has no values.
+func setOperationMetadata(metadata map[string]string, ops []HTTPOperation) {
+	var methods, paths []string
+	for _, op := range ops {
+		for _, m := range op.Methods {
+			if !slices.Contains(methods, m) {
+				methods = append(methods, m)
+			}
+		}
+		for _, p := range op.Paths {
+			if !slices.Contains(paths, p) {
+				paths = append(paths, p)
+			}
+		}
+	}
+
+	for key, values := range map[string][]string{"methods": methods, "paths": paths} {
+		if len(values) == 0 {
+			delete(metadata, key)
+		} else {
+			metadata[key] = strings.Join(values, ",")
+		}
+	}
 }
 
 // formatIstioRule creates a human-readable description of an Istio rule.
@@ -567,20 +1424,38 @@ func (b *Builder) findMatchingWorkloads(namespace string, selector metav1.LabelS
 	workloads := workloadsByNS[namespace]
 
 	for _, w := range workloads {
-		if b.matchesSelector(w.Labels, selector) {
+		if SelectorMatches(w.Labels, selector) {
 			result = append(result, w)
 		}
 	}
 	return result
 }
 
-// findSourceWorkloads finds workloads that are allowed as sources by the ingress rule.
+// findSourceWorkloads finds the workloads an ingress rule's "from" peers allow traffic from.
 func (b *Builder) findSourceWorkloads(policyNamespace string, from []networkingv1.NetworkPolicyPeer, workloadsByNS map[string][]k8s.Workload) []k8s.Workload {
+	return b.findPeerWorkloads(policyNamespace, from, workloadsByNS)
+}
+
+// findDestinationWorkloads finds the workloads an egress rule's "to" peers allow traffic to.
+func (b *Builder) findDestinationWorkloads(policyNamespace string, to []networkingv1.NetworkPolicyPeer, workloadsByNS map[string][]k8s.Workload) []k8s.Workload {
+	return b.findPeerWorkloads(policyNamespace, to, workloadsByNS)
+}
+
+// findPeerWorkloads resolves NetworkPolicy peers to workloads, the same way for ingress and
+// egress. Peers are ORed; within one peer, namespaceSelector and podSelector are ANDed:
+//   - podSelector only: matching workloads in the policy's namespace
+//   - namespaceSelector only: every workload in the matching namespaces, never the policy's
+//     own namespace unless the selector matches it
+//   - both: matching workloads in the matching namespaces
+//
+// No peers match every workload. ipBlock peers match none, since workloads aren't mapped to
+// pod IPs, and neither does a peer with no selector at all, which the API server rejects.
+func (b *Builder) findPeerWorkloads(policyNamespace string, peers []networkingv1.NetworkPolicyPeer, workloadsByNS map[string][]k8s.Workload) []k8s.Workload {
 	var result []k8s.Workload
 	seen := make(map[string]bool)
 
-	// If 'from' is empty, all sources are allowed
-	if len(from) == 0 {
+	// With no peers, every workload is allowed
+	if len(peers) == 0 {
 		for _, workloads := range workloadsByNS {
 			for _, w := range workloads {
 				wID := WorkloadID(w.Namespace, w.Name)
@@ -593,16 +1468,20 @@ func (b *Builder) findSourceWorkloads(policyNamespace string, from []networkingv
 		return result
 	}
 
-	for _, peer := range from {
-		// Determine which namespaces to check
+	for _, peer := range peers {
+		if peer.IPBlock != nil || (peer.PodSelector == nil && peer.NamespaceSelector == nil) {
+			continue
+		}
+
+		// Namespaces first, then the pod selector within them
 		namespaces := b.getNamespacesForPeer(policyNamespace, peer, workloadsByNS)
 
 		for _, ns := range namespaces {
 			workloads := workloadsByNS[ns]
 			for _, w := range workloads {
-				// Check if pod selector matches (if specified)
+				// Without a pod selector every workload in the namespace matches
 				if peer.PodSelector != nil {
-					if !b.matchesSelector(w.Labels, *peer.PodSelector) {
+					if !SelectorMatches(w.Labels, *peer.PodSelector) {
 						continue
 					}
 				}
@@ -619,7 +1498,8 @@ func (b *Builder) findSourceWorkloads(policyNamespace string, from []networkingv
 	return result
 }
 
-// getNamespacesForPeer determines which namespaces are relevant for a NetworkPolicyPeer.
+// getNamespacesForPeer determines which namespaces are relevant for a NetworkPolicyPeer: the
+// policy's namespace without a namespaceSelector, otherwise only the namespaces it matches.
 func (b *Builder) getNamespacesForPeer(policyNamespace string, peer networkingv1.NetworkPolicyPeer, workloadsByNS map[string][]k8s.Workload) []string {
 	if peer.NamespaceSelector == nil {
 		// No namespace selector means same namespace as the policy
@@ -639,65 +1519,16 @@ func (b *Builder) getNamespacesForPeer(policyNamespace string, peer networkingv1
 	var namespaces []string
 	for ns := range workloadsByNS {
 		nsLabels := b.namespaceLabels[ns]
-		if b.namespaceMatchesSelector(nsLabels, *peer.NamespaceSelector) {
+		if SelectorMatches(nsLabels, *peer.NamespaceSelector) {
 			namespaces = append(namespaces, ns)
 		}
 	}
 	return namespaces
 }
 
-// namespaceMatchesSelector checks if namespace labels match the given LabelSelector.
-func (b *Builder) namespaceMatchesSelector(nsLabels map[string]string, selector metav1.LabelSelector) bool {
-	// Check MatchLabels
-	for key, value := range selector.MatchLabels {
-		if nsLabels[key] != value {
-			return false
-		}
-	}
-
-	// Check MatchExpressions
-	for _, expr := range selector.MatchExpressions {
-		labelValue, hasLabel := nsLabels[expr.Key]
-
-		switch expr.Operator {
-		case metav1.LabelSelectorOpIn:
-			if !hasLabel {
-				return false
-			}
-			found := false
-			for _, v := range expr.Values {
-				if labelValue == v {
-					found = true
-					break
-				}
-			}
-			if !found {
-				return false
-			}
-		case metav1.LabelSelectorOpNotIn:
-			if hasLabel {
-				for _, v := range expr.Values {
-					if labelValue == v {
-						return false
-					}
-				}
-			}
-		case metav1.LabelSelectorOpExists:
-			if !hasLabel {
-				return false
-			}
-		case metav1.LabelSelectorOpDoesNotExist:
-			if hasLabel {
-				return false
-			}
-		}
-	}
-
-	return true
-}
-
-// matchesSelector checks if labels match a LabelSelector.
-func (b *Builder) matchesSelector(labels map[string]string, selector metav1.LabelSelector) bool {
+// SelectorMatches reports whether labels, of a pod or a namespace, match a LabelSelector.
+// An empty selector matches everything, as it does in NetworkPolicy peers.
+func SelectorMatches(labels map[string]string, selector metav1.LabelSelector) bool {
 	// Check MatchLabels
 	for key, value := range selector.MatchLabels {
 		if labels[key] != value {
@@ -776,7 +1607,12 @@ func (b *Builder) portMatches(wPort k8s.Port, pPort networkingv1.NetworkPolicyPo
 	if pPort.Port != nil {
 		// IntOrString can be an int or a string (port name)
 		if pPort.Port.Type == 0 { // Int
-			if int32(pPort.Port.IntVal) != wPort.ContainerPort {
+			// EndPort makes the match an inclusive range [Port, EndPort]
+			if pPort.EndPort != nil {
+				if wPort.ContainerPort < pPort.Port.IntVal || wPort.ContainerPort > *pPort.EndPort {
+					return false
+				}
+			} else if int32(pPort.Port.IntVal) != wPort.ContainerPort {
 				return false
 			}
 		} else { // String (port name)
@@ -791,31 +1627,41 @@ func (b *Builder) portMatches(wPort k8s.Port, pPort networkingv1.NetworkPolicyPo
 
 // formatK8sRule creates a human-readable description of a K8s NetworkPolicy ingress rule.
 func (b *Builder) formatK8sRule(rule networkingv1.NetworkPolicyIngressRule, idx int) string {
+	return fmt.Sprintf("NetworkPolicy Rule %d: %s", idx+1, b.formatPeersAndPorts("from", rule.From, rule.Ports))
+}
+
+// formatK8sEgressRule creates a human-readable description of a K8s NetworkPolicy egress rule.
+func (b *Builder) formatK8sEgressRule(rule networkingv1.NetworkPolicyEgressRule, idx int) string {
+	return fmt.Sprintf("NetworkPolicy Egress Rule %d: %s", idx+1, b.formatPeersAndPorts("to", rule.To, rule.Ports))
+}
+
+// formatPeersAndPorts describes a rule's peers (prefixed with "from" or "to") and ports.
+func (b *Builder) formatPeersAndPorts(peerPrefix string, peers []networkingv1.NetworkPolicyPeer, policyPorts []networkingv1.NetworkPolicyPort) string {
 	var parts []string
 
-	// Describe sources
-	if len(rule.From) == 0 {
-		parts = append(parts, "from: all")
+	// Describe peers
+	if len(peers) == 0 {
+		parts = append(parts, peerPrefix+": all")
 	} else {
-		var sources []string
-		for _, from := range rule.From {
-			sources = append(sources, b.formatPeer(from))
+		var described []string
+		for _, peer := range peers {
+			described = append(described, b.formatPeer(peer))
 		}
-		parts = append(parts, "from: "+strings.Join(sources, ", "))
+		parts = append(parts, peerPrefix+": "+strings.Join(described, ", "))
 	}
 
 	// Describe ports
-	if len(rule.Ports) == 0 {
+	if len(policyPorts) == 0 {
 		parts = append(parts, "ports: all")
 	} else {
 		var ports []string
-		for _, p := range rule.Ports {
+		for _, p := range policyPorts {
 			ports = append(ports, b.formatPolicyPort(p))
 		}
 		parts = append(parts, "ports: "+strings.Join(ports, ", "))
 	}
 
-	return fmt.Sprintf("NetworkPolicy Rule %d: %s", idx+1, strings.Join(parts, "; "))
+	return strings.Join(parts, "; ")
 }
 
 // formatPeer creates a human-readable description of a NetworkPolicyPeer.
@@ -863,6 +1709,9 @@ func (b *Builder) formatPolicyPort(p networkingv1.NetworkPolicyPort) string {
 	}
 
 	if p.Port.Type == 0 {
+		if p.EndPort != nil {
+			return fmt.Sprintf("%s/%d-%d", protocol, p.Port.IntVal, *p.EndPort)
+		}
 		return fmt.Sprintf("%s/%d", protocol, p.Port.IntVal)
 	}
 	return fmt.Sprintf("%s/%s", protocol, p.Port.StrVal)
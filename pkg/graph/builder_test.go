@@ -1,18 +1,40 @@
 package graph
 
 import (
+	"maps"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
 	"testing"
 
 	"github.com/ddl-r-abdulaziz/dnmap/pkg/k8s"
+	securityv1beta1 "istio.io/api/security/v1beta1"
+	istiotypev1beta1 "istio.io/api/type/v1beta1"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
-func TestBuilderMatchesSelector(t *testing.T) {
-	builder := NewBuilder()
+// istioPolicy builds an Istio AuthorizationPolicy wrapped in a k8s.Policy for tests.
+func istioPolicy(namespace, name string, selector map[string]string, rules ...*k8s.IstioRule) k8s.Policy {
+	ap := &k8s.IstioAuthorizationPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+	}
+	if selector != nil {
+		ap.Spec.Selector = &istiotypev1beta1.WorkloadSelector{MatchLabels: selector}
+	}
+	ap.Spec.Rules = rules
+	return k8s.Policy{
+		Name:            name,
+		Namespace:       namespace,
+		Type:            k8s.PolicyTypeIstioAuthorizationPolicy,
+		IstioAuthPolicy: ap,
+	}
+}
 
+func TestSelectorMatches(t *testing.T) {
 	tests := map[string]struct {
 		labels   map[string]string
 		selector metav1.LabelSelector
@@ -98,11 +120,43 @@ func TestBuilderMatchesSelector(t *testing.T) {
 			},
 			expected: true,
 		},
+		"does not exist no match": {
+			labels: map[string]string{"app": "nginx", "deprecated": "true"},
+			selector: metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "deprecated", Operator: metav1.LabelSelectorOpDoesNotExist},
+				},
+			},
+			expected: false,
+		},
+		"notin operator no match": {
+			labels: map[string]string{"env": "prod"},
+			selector: metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "env", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"prod", "staging"}},
+				},
+			},
+			expected: false,
+		},
+		"labels and expressions must both match": {
+			labels: map[string]string{"kubernetes.io/metadata.name": "apps", "team": "web"},
+			selector: metav1.LabelSelector{
+				MatchLabels: map[string]string{"kubernetes.io/metadata.name": "apps"},
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "team", Operator: metav1.LabelSelectorOpIn, Values: []string{"db"}},
+				},
+			},
+			expected: false,
+		},
+		"empty selector matches unlabeled": {
+			selector: metav1.LabelSelector{},
+			expected: true,
+		},
 	}
 
 	for name, tt := range tests {
 		t.Run(name, func(t *testing.T) {
-			result := builder.matchesSelector(tt.labels, tt.selector)
+			result := SelectorMatches(tt.labels, tt.selector)
 			if result != tt.expected {
 				t.Errorf("expected %v, got %v", tt.expected, result)
 			}
@@ -209,6 +263,11 @@ func TestBuilderBuild(t *testing.T) {
 			if len(graph.Edges) != tt.expectedEdges {
 				t.Errorf("expected %d edges, got %d", tt.expectedEdges, len(graph.Edges))
 			}
+			for _, n := range graph.Nodes {
+				if n.Namespace == "" {
+					t.Errorf("node %s has no namespace", n.ID)
+				}
+			}
 		})
 	}
 }
@@ -289,58 +348,589 @@ func TestBuilderBuildFromNetworkPolicies(t *testing.T) {
 	}
 }
 
-func TestBuilderPortMatches(t *testing.T) {
-	builder := NewBuilder()
-	tcp := corev1.ProtocolTCP
-	udp := corev1.ProtocolUDP
+func TestBuilderBuildDedupesEdges(t *testing.T) {
+	workloads := []k8s.Workload{
+		{Name: "frontend", Namespace: "default", Labels: map[string]string{"app": "frontend"}},
+		{
+			Name:      "backend",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "backend"},
+			Ports:     []k8s.Port{{Name: "http", ContainerPort: 8080, Protocol: corev1.ProtocolTCP}},
+		},
+	}
+	allowFrontend := func(name string) k8s.Policy {
+		np := &networkingv1.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: networkingv1.NetworkPolicySpec{
+				PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "backend"}},
+				Ingress: []networkingv1.NetworkPolicyIngressRule{{
+					From: []networkingv1.NetworkPolicyPeer{{
+						PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "frontend"}},
+					}},
+					Ports: []networkingv1.NetworkPolicyPort{{Port: &intstr.IntOrString{Type: intstr.Int, IntVal: 8080}}},
+				}},
+			},
+		}
+		return k8s.Policy{Name: name, Namespace: "default", Type: k8s.PolicyTypeK8sNetworkPolicy, K8sNetworkPolicy: np}
+	}
+
+	graph := NewBuilder().Build(workloads, []k8s.Policy{allowFrontend("allow-a"), allowFrontend("allow-b")})
+
+	if len(graph.Edges) != 1 {
+		t.Fatalf("expected overlapping policies to produce 1 edge, got %d", len(graph.Edges))
+	}
+	e := graph.Edges[0]
+	if e.Source != "default/frontend" || e.Target != "default/backend:TCP/8080" {
+		t.Errorf("unexpected edge %s -> %s", e.Source, e.Target)
+	}
+	if e.Policy != "default/allow-a" {
+		t.Errorf("expected first policy default/allow-a, got %s", e.Policy)
+	}
+	expected := []string{"default/allow-a", "default/allow-b"}
+	if len(e.Policies) != len(expected) || e.Policies[0] != expected[0] || e.Policies[1] != expected[1] {
+		t.Errorf("expected policies %v, got %v", expected, e.Policies)
+	}
+}
+
+func TestBuilderBuildDeterministic(t *testing.T) {
+	var workloads []k8s.Workload
+	for _, ns := range []string{"alpha", "bravo", "charlie", "delta", "echo"} {
+		for _, name := range []string{"web", "api", "db"} {
+			workloads = append(workloads, k8s.Workload{
+				Name:      name,
+				Namespace: ns,
+				Labels:    map[string]string{"app": name},
+				Ports:     []k8s.Port{{Name: "http", ContainerPort: 8080, Protocol: corev1.ProtocolTCP}},
+			})
+		}
+	}
+	// An empty namespace selector makes the sources range over every namespace
+	np := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "allow-all-ns", Namespace: "alpha"},
+		Spec: networkingv1.NetworkPolicySpec{
+			Ingress: []networkingv1.NetworkPolicyIngressRule{{
+				From: []networkingv1.NetworkPolicyPeer{{NamespaceSelector: &metav1.LabelSelector{}}},
+			}},
+		},
+	}
+	policies := []k8s.Policy{{Name: np.Name, Namespace: np.Namespace, Type: k8s.PolicyTypeK8sNetworkPolicy, K8sNetworkPolicy: np}}
+
+	first := NewBuilder().Build(workloads, policies)
+	if len(first.Edges) == 0 {
+		t.Fatal("expected edges")
+	}
+	for i := 0; i < 10; i++ {
+		next := NewBuilder().Build(workloads, policies)
+		if len(next.Edges) != len(first.Edges) {
+			t.Fatalf("expected %d edges, got %d", len(first.Edges), len(next.Edges))
+		}
+		for j := range first.Edges {
+			if next.Edges[j].ID != first.Edges[j].ID {
+				t.Fatalf("edge %d: expected ID %s, got %s", j, first.Edges[j].ID, next.Edges[j].ID)
+			}
+		}
+		for j := range first.Nodes {
+			if next.Nodes[j].ID != first.Nodes[j].ID {
+				t.Fatalf("node %d: expected ID %s, got %s", j, first.Nodes[j].ID, next.Nodes[j].ID)
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, e := range first.Edges {
+		if seen[e.ID] {
+			t.Errorf("duplicate edge ID %s", e.ID)
+		}
+		seen[e.ID] = true
+	}
+}
+
+func TestBuilderBuildSortsNodes(t *testing.T) {
+	workloads := []k8s.Workload{
+		{Name: "web", Namespace: "zeta", Labels: map[string]string{"app": "web"}},
+		{
+			Name:      "api",
+			Namespace: "alpha",
+			Labels:    map[string]string{"app": "api"},
+			Ports: []k8s.Port{
+				{Name: "metrics", ContainerPort: 9090, Protocol: corev1.ProtocolTCP},
+				{Name: "http", ContainerPort: 8080, Protocol: corev1.ProtocolTCP},
+			},
+		},
+		{Name: "db", Namespace: "alpha", Labels: map[string]string{"app": "db"}},
+	}
+	np := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "deny", Namespace: "zeta"},
+		Spec:       networkingv1.NetworkPolicySpec{PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}},
+	}
+	policies := []k8s.Policy{{Name: np.Name, Namespace: np.Namespace, Type: k8s.PolicyTypeK8sNetworkPolicy, K8sNetworkPolicy: np}}
+
+	graph := NewBuilder().Build(workloads, policies)
+
+	var ids []string
+	for _, n := range graph.Nodes {
+		ids = append(ids, n.ID)
+	}
+	expected := []string{"alpha/api:TCP/8080", "alpha/api:TCP/9090", "alpha/api", "alpha/db", "zeta/web"}
+	if strings.Join(ids, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected nodes %v, got %v", expected, ids)
+	}
+
+	// Warnings still land on the right workloads after sorting: only zeta is covered
+	for _, n := range graph.Nodes {
+		if n.Type != NodeTypeWorkload {
+			continue
+		}
+		uncovered := slices.Contains(n.Warnings, WarningUncovered)
+		if uncovered != (n.Namespace == "alpha") {
+			t.Errorf("unexpected warnings on %s: %v", n.ID, n.Warnings)
+		}
+	}
+}
+
+func TestDedupeEdgesOperations(t *testing.T) {
+	get := HTTPOperation{Methods: []string{"GET"}}
+	post := HTTPOperation{Methods: []string{"POST"}}
 
 	tests := map[string]struct {
-		workloadPort k8s.Port
-		policyPort   networkingv1.NetworkPolicyPort
-		expected     bool
+		first, second []HTTPOperation
+		expected      int
 	}{
-		"nil port matches all": {
-			workloadPort: k8s.Port{ContainerPort: 80, Protocol: corev1.ProtocolTCP},
-			policyPort:   networkingv1.NetworkPolicyPort{},
-			expected:     true,
+		"restricted operations are combined": {
+			first:    []HTTPOperation{get},
+			second:   []HTTPOperation{post},
+			expected: 2,
 		},
-		"exact port match": {
-			workloadPort: k8s.Port{ContainerPort: 80, Protocol: corev1.ProtocolTCP},
-			policyPort: networkingv1.NetworkPolicyPort{
-				Port:     &intstr.IntOrString{Type: intstr.Int, IntVal: 80},
-				Protocol: &tcp,
+		"unrestricted edge wins": {
+			first:    []HTTPOperation{get},
+			second:   nil,
+			expected: 0,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			edges := dedupeEdges([]Edge{
+				{Source: "a", Target: "b:TCP/80", Policy: "ns/p1", Operations: tt.first},
+				{Source: "a", Target: "b:TCP/80", Policy: "ns/p2", Operations: tt.second, Direction: DirectionIngress},
+				{Source: "a", Target: "b:TCP/80", Policy: "ns/p3", Direction: DirectionEgress},
+			})
+			if len(edges) != 2 {
+				t.Fatalf("expected ingress and egress edges to stay separate, got %d edges", len(edges))
+			}
+			if len(edges[0].Operations) != tt.expected {
+				t.Errorf("expected %d operations, got %v", tt.expected, edges[0].Operations)
+			}
+		})
+	}
+}
+
+func TestDedupeEdgesKinds(t *testing.T) {
+	tests := map[string]struct {
+		first, second map[string]string
+		expected      int
+	}{
+		"same kind is merged": {
+			first:    map[string]string{"policyType": "AuthorizationPolicy", "action": "ALLOW"},
+			second:   map[string]string{"policyType": "AuthorizationPolicy", "action": "ALLOW"},
+			expected: 1,
+		},
+		"allow and deny stay separate": {
+			first:    map[string]string{"policyType": "AuthorizationPolicy", "action": "ALLOW"},
+			second:   map[string]string{"policyType": "AuthorizationPolicy", "action": "DENY"},
+			expected: 2,
+		},
+		"policy types stay separate": {
+			first:    map[string]string{"policyType": "NetworkPolicy"},
+			second:   map[string]string{"policyType": "AuthorizationPolicy", "action": "ALLOW"},
+			expected: 2,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			edges := dedupeEdges([]Edge{
+				{Source: "a", Target: "b:TCP/80", Policy: "ns/p1", Metadata: tt.first},
+				{Source: "a", Target: "b:TCP/80", Policy: "ns/p2", Metadata: tt.second},
+			})
+			if len(edges) != tt.expected {
+				t.Fatalf("expected %d edges, got %d", tt.expected, len(edges))
+			}
+			if len(edges) == 2 && (len(edges[0].Policies) != 1 || len(edges[1].Policies) != 1) {
+				t.Errorf("expected each edge to keep only its own policy, got %v and %v", edges[0].Policies, edges[1].Policies)
+			}
+		})
+	}
+}
+
+func TestBuilderBuildEgress(t *testing.T) {
+	workloads := []k8s.Workload{
+		{Name: "frontend", Namespace: "default", Labels: map[string]string{"app": "frontend"}},
+		{
+			Name:      "backend",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "backend"},
+			Ports: []k8s.Port{
+				{Name: "http", ContainerPort: 8080, Protocol: corev1.ProtocolTCP},
+				{Name: "metrics", ContainerPort: 9090, Protocol: corev1.ProtocolTCP},
 			},
-			expected: true,
 		},
-		"port number mismatch": {
-			workloadPort: k8s.Port{ContainerPort: 80, Protocol: corev1.ProtocolTCP},
-			policyPort: networkingv1.NetworkPolicyPort{
-				Port:     &intstr.IntOrString{Type: intstr.Int, IntVal: 443},
-				Protocol: &tcp,
+	}
+
+	egressRule := networkingv1.NetworkPolicyEgressRule{
+		To: []networkingv1.NetworkPolicyPeer{
+			{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "backend"}}},
+		},
+		Ports: []networkingv1.NetworkPolicyPort{
+			{Port: &intstr.IntOrString{Type: intstr.Int, IntVal: 8080}},
+		},
+	}
+	ingressRule := networkingv1.NetworkPolicyIngressRule{
+		From: []networkingv1.NetworkPolicyPeer{
+			{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "backend"}}},
+		},
+	}
+
+	tests := map[string]struct {
+		policyTypes       []networkingv1.PolicyType
+		ingress           []networkingv1.NetworkPolicyIngressRule
+		expectedRuleTypes []string
+	}{
+		"egress rules without policyTypes": {
+			expectedRuleTypes: []string{"egress"},
+		},
+		"egress only ignores ingress rules": {
+			policyTypes:       []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+			ingress:           []networkingv1.NetworkPolicyIngressRule{ingressRule},
+			expectedRuleTypes: []string{"egress"},
+		},
+		"ingress only ignores egress rules": {
+			policyTypes:       []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			expectedRuleTypes: nil,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			policy := networkingv1.NetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "frontend-egress", Namespace: "default"},
+				Spec: networkingv1.NetworkPolicySpec{
+					PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "frontend"}},
+					PolicyTypes: tt.policyTypes,
+					Ingress:     tt.ingress,
+					Egress:      []networkingv1.NetworkPolicyEgressRule{egressRule},
+				},
+			}
+
+			graph := NewBuilder().BuildFromNetworkPolicies(workloads, []networkingv1.NetworkPolicy{policy})
+			if len(graph.Edges) != len(tt.expectedRuleTypes) {
+				t.Fatalf("expected %d edges, got %d", len(tt.expectedRuleTypes), len(graph.Edges))
+			}
+			for i, e := range graph.Edges {
+				if e.Metadata["ruleType"] != tt.expectedRuleTypes[i] {
+					t.Errorf("expected ruleType %q, got %q", tt.expectedRuleTypes[i], e.Metadata["ruleType"])
+				}
+				if e.Direction != DirectionEgress {
+					t.Errorf("expected direction %q, got %q", DirectionEgress, e.Direction)
+				}
+				if e.Source != "default/frontend" || e.Target != "default/backend:TCP/8080" {
+					t.Errorf("expected edge default/frontend -> default/backend:TCP/8080, got %s -> %s", e.Source, e.Target)
+				}
+			}
+		})
+	}
+}
+
+func TestBuilderFindDestinationWorkloads(t *testing.T) {
+	workloads := []k8s.Workload{
+		{Name: "api", Namespace: "apps", Labels: map[string]string{"app": "api"}},
+		{Name: "web", Namespace: "apps", Labels: map[string]string{"app": "web"}},
+		{Name: "db", Namespace: "data", Labels: map[string]string{"app": "db"}},
+	}
+	workloadsByNS := make(map[string][]k8s.Workload)
+	for _, w := range workloads {
+		workloadsByNS[w.Namespace] = append(workloadsByNS[w.Namespace], w)
+	}
+	builder := NewBuilder().WithNamespaceLabels([]k8s.NamespaceInfo{
+		{Name: "apps", Labels: map[string]string{"tier": "frontend"}},
+		{Name: "data", Labels: map[string]string{"tier": "backend"}},
+	})
+
+	tests := map[string]struct {
+		peers    []networkingv1.NetworkPolicyPeer
+		expected []string
+	}{
+		"no peers matches every workload": {
+			peers:    nil,
+			expected: []string{"apps/api", "apps/web", "data/db"},
+		},
+		"pod selector in the policy namespace": {
+			peers: []networkingv1.NetworkPolicyPeer{
+				{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
 			},
-			expected: false,
+			expected: []string{"apps/web"},
 		},
-		"protocol mismatch": {
-			workloadPort: k8s.Port{ContainerPort: 53, Protocol: corev1.ProtocolTCP},
-			policyPort: networkingv1.NetworkPolicyPort{
-				Port:     &intstr.IntOrString{Type: intstr.Int, IntVal: 53},
-				Protocol: &udp,
+		"namespace selector": {
+			peers: []networkingv1.NetworkPolicyPeer{
+				{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "backend"}}},
 			},
-			expected: false,
+			expected: []string{"data/db"},
 		},
-		"named port match": {
-			workloadPort: k8s.Port{Name: "http", ContainerPort: 80, Protocol: corev1.ProtocolTCP},
-			policyPort: networkingv1.NetworkPolicyPort{
-				Port:     &intstr.IntOrString{Type: intstr.String, StrVal: "http"},
-				Protocol: &tcp,
+		"ipBlock matches no workloads": {
+			peers: []networkingv1.NetworkPolicyPeer{
+				{IPBlock: &networkingv1.IPBlock{CIDR: "10.0.0.0/8"}},
+			},
+			expected: nil,
+		},
+		"namespace selector matching no namespace": {
+			peers: []networkingv1.NetworkPolicyPeer{
+				{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "none"}}},
+			},
+			expected: nil,
+		},
+		"namespace and pod selector in one peer are ANDed": {
+			peers: []networkingv1.NetworkPolicyPeer{
+				{
+					NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "frontend"}},
+					PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}},
+				},
+			},
+			expected: []string{"apps/api"},
+		},
+		"namespace and pod selector in separate peers are ORed": {
+			peers: []networkingv1.NetworkPolicyPeer{
+				{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "backend"}}},
+				{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}}},
+			},
+			expected: []string{"apps/api", "data/db"},
+		},
+		"empty namespace selector with a pod selector": {
+			peers: []networkingv1.NetworkPolicyPeer{
+				{
+					NamespaceSelector: &metav1.LabelSelector{},
+					PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+				},
+			},
+			expected: []string{"data/db"},
+		},
+		"peer without selectors matches no workloads": {
+			peers:    []networkingv1.NetworkPolicyPeer{{}},
+			expected: nil,
+		},
+		"ipBlock alongside a pod selector": {
+			peers: []networkingv1.NetworkPolicyPeer{
+				{IPBlock: &networkingv1.IPBlock{CIDR: "10.0.0.0/8"}},
+				{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}}},
+			},
+			expected: []string{"apps/api"},
+		},
+	}
+
+	ids := func(workloads []k8s.Workload) []string {
+		var result []string
+		for _, w := range workloads {
+			result = append(result, WorkloadID(w.Namespace, w.Name))
+		}
+		sort.Strings(result)
+		return result
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			destinations := ids(builder.findDestinationWorkloads("apps", tt.peers, workloadsByNS))
+			if strings.Join(destinations, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("expected destinations %v, got %v", tt.expected, destinations)
+			}
+			// Ingress resolves the same peers identically
+			sources := ids(builder.findSourceWorkloads("apps", tt.peers, workloadsByNS))
+			if strings.Join(sources, ",") != strings.Join(destinations, ",") {
+				t.Errorf("expected sources %v to match destinations %v", sources, destinations)
+			}
+		})
+	}
+}
+
+func TestBuilderPeerPodSelectorEmptyVsNil(t *testing.T) {
+	workloads := []k8s.Workload{
+		{Name: "api", Namespace: "apps", Labels: map[string]string{"app": "api"}},
+		{Name: "web", Namespace: "apps", Labels: map[string]string{"app": "web"}},
+		{Name: "db", Namespace: "data", Labels: map[string]string{"app": "db"}},
+		{Name: "cache", Namespace: "data", Labels: map[string]string{"app": "cache"}},
+	}
+	workloadsByNS := make(map[string][]k8s.Workload)
+	for _, w := range workloads {
+		workloadsByNS[w.Namespace] = append(workloadsByNS[w.Namespace], w)
+	}
+	builder := NewBuilder().WithNamespaceLabels([]k8s.NamespaceInfo{
+		{Name: "apps", Labels: map[string]string{"tier": "frontend"}},
+		{Name: "data", Labels: map[string]string{"tier": "backend"}},
+	})
+	backend := &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "backend"}}
+
+	tests := map[string]struct {
+		peer            networkingv1.NetworkPolicyPeer
+		expected        []string
+		expectedSummary string
+	}{
+		"nil pod selector with namespace selector": {
+			peer:            networkingv1.NetworkPolicyPeer{NamespaceSelector: backend},
+			expected:        []string{"data/cache", "data/db"},
+			expectedSummary: "namespaces: map[tier:backend]",
+		},
+		"empty pod selector with namespace selector": {
+			peer:            networkingv1.NetworkPolicyPeer{NamespaceSelector: backend, PodSelector: &metav1.LabelSelector{}},
+			expected:        []string{"data/cache", "data/db"},
+			expectedSummary: "pods: all, namespaces: map[tier:backend]",
+		},
+		"pod selector with namespace selector": {
+			peer: networkingv1.NetworkPolicyPeer{
+				NamespaceSelector: backend,
+				PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+			},
+			expected:        []string{"data/db"},
+			expectedSummary: "pods: map[app:db], namespaces: map[tier:backend]",
+		},
+		"empty pod selector without namespace selector": {
+			peer:            networkingv1.NetworkPolicyPeer{PodSelector: &metav1.LabelSelector{}},
+			expected:        []string{"apps/api", "apps/web"},
+			expectedSummary: "pods: all, namespaces: same as policy",
+		},
+		"nil pod selector with empty namespace selector": {
+			peer:            networkingv1.NetworkPolicyPeer{NamespaceSelector: &metav1.LabelSelector{}},
+			expected:        []string{"apps/api", "apps/web", "data/cache", "data/db"},
+			expectedSummary: "namespaces: all",
+		},
+		"empty pod selector with empty namespace selector": {
+			peer:            networkingv1.NetworkPolicyPeer{NamespaceSelector: &metav1.LabelSelector{}, PodSelector: &metav1.LabelSelector{}},
+			expected:        []string{"apps/api", "apps/web", "data/cache", "data/db"},
+			expectedSummary: "pods: all, namespaces: all",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var sources []string
+			for _, w := range builder.findSourceWorkloads("apps", []networkingv1.NetworkPolicyPeer{tt.peer}, workloadsByNS) {
+				sources = append(sources, WorkloadID(w.Namespace, w.Name))
+			}
+			sort.Strings(sources)
+			if !slices.Equal(sources, tt.expected) {
+				t.Errorf("expected sources %v, got %v", tt.expected, sources)
+			}
+			if summary := builder.formatPeer(tt.peer); summary != tt.expectedSummary {
+				t.Errorf("expected summary %q, got %q", tt.expectedSummary, summary)
+			}
+		})
+	}
+}
+
+func TestPolicyAppliesTo(t *testing.T) {
+	ingressRules := []networkingv1.NetworkPolicyIngressRule{{}}
+	egressRules := []networkingv1.NetworkPolicyEgressRule{{}}
+
+	tests := map[string]struct {
+		policyTypes     []networkingv1.PolicyType
+		ingress         []networkingv1.NetworkPolicyIngressRule
+		egress          []networkingv1.NetworkPolicyEgressRule
+		expectedIngress bool
+		expectedEgress  bool
+	}{
+		"unset without rules": {
+			expectedIngress: true,
+		},
+		"unset with ingress rules": {
+			ingress:         ingressRules,
+			expectedIngress: true,
+		},
+		"unset with egress rules": {
+			egress:          egressRules,
+			expectedIngress: true,
+			expectedEgress:  true,
+		},
+		"ingress with egress rules": {
+			policyTypes:     []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			egress:          egressRules,
+			expectedIngress: true,
+		},
+		"egress with ingress rules": {
+			policyTypes:    []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+			ingress:        ingressRules,
+			expectedEgress: true,
+		},
+		"egress without rules": {
+			policyTypes:    []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+			expectedEgress: true,
+		},
+		"ingress and egress": {
+			policyTypes:     []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+			expectedIngress: true,
+			expectedEgress:  true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			policy := &networkingv1.NetworkPolicy{
+				Spec: networkingv1.NetworkPolicySpec{PolicyTypes: tt.policyTypes, Ingress: tt.ingress, Egress: tt.egress},
+			}
+			if got := policyAppliesTo(policy, networkingv1.PolicyTypeIngress); got != tt.expectedIngress {
+				t.Errorf("expected ingress %v, got %v", tt.expectedIngress, got)
+			}
+			if got := policyAppliesTo(policy, networkingv1.PolicyTypeEgress); got != tt.expectedEgress {
+				t.Errorf("expected egress %v, got %v", tt.expectedEgress, got)
+			}
+		})
+	}
+}
+
+func TestBuilderBuildDefaultDeny(t *testing.T) {
+	workloads := []k8s.Workload{
+		{Name: "api", Namespace: "locked", Labels: map[string]string{"app": "api"}},
+		{Name: "web", Namespace: "open", Labels: map[string]string{"app": "web"}},
+	}
+
+	tests := map[string]struct {
+		policy   networkingv1.NetworkPolicy
+		expected bool
+	}{
+		"empty podSelector with no ingress rules": {
+			policy: networkingv1.NetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "default-deny", Namespace: "locked"},
+				Spec: networkingv1.NetworkPolicySpec{
+					PodSelector: metav1.LabelSelector{},
+					PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+				},
 			},
 			expected: true,
 		},
-		"named port mismatch": {
-			workloadPort: k8s.Port{Name: "http", ContainerPort: 80, Protocol: corev1.ProtocolTCP},
-			policyPort: networkingv1.NetworkPolicyPort{
-				Port:     &intstr.IntOrString{Type: intstr.String, StrVal: "https"},
-				Protocol: &tcp,
+		"empty podSelector with an ingress rule": {
+			policy: networkingv1.NetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "allow-all", Namespace: "open"},
+				Spec: networkingv1.NetworkPolicySpec{
+					PodSelector: metav1.LabelSelector{},
+					PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+					Ingress:     []networkingv1.NetworkPolicyIngressRule{{}},
+				},
+			},
+			expected: false,
+		},
+		"egress-only policy": {
+			policy: networkingv1.NetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "deny-egress", Namespace: "open"},
+				Spec: networkingv1.NetworkPolicySpec{
+					PodSelector: metav1.LabelSelector{},
+					PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+				},
+			},
+			expected: false,
+		},
+		"selected pods only": {
+			policy: networkingv1.NetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "deny-web", Namespace: "open"},
+				Spec: networkingv1.NetworkPolicySpec{
+					PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+					PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+				},
 			},
 			expected: false,
 		},
@@ -348,10 +938,1390 @@ func TestBuilderPortMatches(t *testing.T) {
 
 	for name, tt := range tests {
 		t.Run(name, func(t *testing.T) {
-			result := builder.portMatches(tt.workloadPort, tt.policyPort)
-			if result != tt.expected {
-				t.Errorf("expected %v, got %v", tt.expected, result)
+			graph := NewBuilder().BuildFromNetworkPolicies(workloads, []networkingv1.NetworkPolicy{tt.policy})
+
+			var found *WarningDetail
+			for i, wd := range graph.WarningDetails {
+				if wd.WarningType == WarningDefaultDeny {
+					found = &graph.WarningDetails[i]
+				}
+			}
+			if (found != nil) != tt.expected {
+				t.Fatalf("expected default-deny warning %v, got %+v", tt.expected, graph.WarningDetails)
+			}
+			if found != nil {
+				if found.WorkloadName != "" || found.Namespace != tt.policy.Namespace {
+					t.Errorf("expected namespace-level warning for %s, got %+v", tt.policy.Namespace, *found)
+				}
 			}
 		})
 	}
 }
+
+func TestBuilderBuildUncovered(t *testing.T) {
+	workloads := []k8s.Workload{
+		{Name: "backend", Namespace: "default", Labels: map[string]string{"app": "backend"}},
+		{Name: "orphan", Namespace: "default", Labels: map[string]string{"app": "orphan"}},
+	}
+	policy := networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "allow-backend", Namespace: "default"},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "backend"}},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{
+				{
+					From: []networkingv1.NetworkPolicyPeer{
+						{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "orphan"}}},
+					},
+					Ports: []networkingv1.NetworkPolicyPort{
+						{Port: &intstr.IntOrString{Type: intstr.Int, IntVal: 8080}},
+					},
+				},
+			},
+		},
+	}
+
+	graph := NewBuilder().BuildFromNetworkPolicies(workloads, []networkingv1.NetworkPolicy{policy})
+
+	for _, n := range graph.Nodes {
+		uncovered := false
+		for _, w := range n.Warnings {
+			if w == WarningUncovered {
+				uncovered = true
+			}
+		}
+		if expected := n.ID == "default/orphan"; uncovered != expected {
+			t.Errorf("%s: expected uncovered %v, got %v", n.ID, expected, uncovered)
+		}
+	}
+
+	var details []WarningDetail
+	for _, wd := range graph.WarningDetails {
+		if wd.WarningType == WarningUncovered {
+			details = append(details, wd)
+		}
+	}
+	if len(details) != 1 || details[0].WorkloadID != "default/orphan" {
+		t.Errorf("expected one uncovered warning detail for default/orphan, got %+v", details)
+	}
+}
+
+func TestBuilderBuildBroadCIDR(t *testing.T) {
+	workloads := []k8s.Workload{
+		{Name: "backend", Namespace: "default", Labels: map[string]string{"app": "backend"}},
+	}
+
+	tests := map[string]struct {
+		ingress              []string
+		egress               []string
+		prefix               int // zero keeps the default
+		expectedDescriptions []string
+	}{
+		"any address": {
+			ingress:              []string{"0.0.0.0/0"},
+			expectedDescriptions: []string{"allows ingress from 0.0.0.0/0"},
+		},
+		"prefix at the threshold": {
+			egress:               []string{"10.0.0.0/8"},
+			expectedDescriptions: []string{"allows egress to 10.0.0.0/8"},
+		},
+		"narrow prefix": {
+			ingress: []string{"10.1.0.0/16"},
+		},
+		"custom threshold": {
+			ingress:              []string{"10.1.0.0/16", "10.1.2.0/24"},
+			prefix:               16,
+			expectedDescriptions: []string{"allows ingress from 10.1.0.0/16"},
+		},
+		"disabled": {
+			ingress: []string{"0.0.0.0/0"},
+			prefix:  -1,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			policy := networkingv1.NetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "allow-cidr", Namespace: "default"},
+				Spec: networkingv1.NetworkPolicySpec{
+					PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "backend"}},
+					PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+				},
+			}
+			for _, cidr := range tt.ingress {
+				policy.Spec.Ingress = append(policy.Spec.Ingress, networkingv1.NetworkPolicyIngressRule{
+					From: []networkingv1.NetworkPolicyPeer{{IPBlock: &networkingv1.IPBlock{CIDR: cidr}}},
+				})
+			}
+			for _, cidr := range tt.egress {
+				policy.Spec.Egress = append(policy.Spec.Egress, networkingv1.NetworkPolicyEgressRule{
+					To: []networkingv1.NetworkPolicyPeer{{IPBlock: &networkingv1.IPBlock{CIDR: cidr}}},
+				})
+			}
+
+			builder := NewBuilder()
+			if tt.prefix != 0 {
+				builder.WithBroadCIDRPrefix(tt.prefix)
+			}
+			graph := builder.BuildFromNetworkPolicies(workloads, []networkingv1.NetworkPolicy{policy})
+
+			var descriptions []string
+			for _, wd := range graph.WarningDetails {
+				if wd.WarningType == WarningBroadCIDR {
+					descriptions = append(descriptions, wd.Description)
+				}
+			}
+			if !slices.Equal(descriptions, tt.expectedDescriptions) {
+				t.Errorf("expected broad-cidr details %v, got %v", tt.expectedDescriptions, descriptions)
+			}
+
+			flagged := false
+			for _, n := range graph.Nodes {
+				if n.ID == "default/backend" {
+					flagged = slices.Contains(n.Warnings, WarningBroadCIDR)
+				}
+			}
+			if expected := len(tt.expectedDescriptions) > 0; flagged != expected {
+				t.Errorf("expected backend flagged %v, got %v", expected, flagged)
+			}
+		})
+	}
+}
+
+func TestBuilderBuildHostNetwork(t *testing.T) {
+	workloads := []k8s.Workload{
+		{Name: "agent", Namespace: "default", HostNetwork: true, Ports: []k8s.Port{{ContainerPort: 9100, Protocol: corev1.ProtocolTCP, HostPort: 9100}}},
+		{Name: "web", Namespace: "default", Ports: []k8s.Port{{ContainerPort: 8080, Protocol: corev1.ProtocolTCP}}},
+	}
+
+	graph := NewBuilder().Build(workloads, nil)
+
+	for _, n := range graph.Nodes {
+		switch n.ID {
+		case "default/agent":
+			if !n.HostNetwork || !slices.Contains(n.Warnings, WarningHostNetwork) {
+				t.Errorf("expected agent marked and warned as host-networked, got %+v", n)
+			}
+		case "default/web":
+			if n.HostNetwork || slices.Contains(n.Warnings, WarningHostNetwork) {
+				t.Errorf("expected web not marked as host-networked, got %+v", n)
+			}
+		case PortID("default/agent", 9100, "TCP"):
+			if n.HostPort != 9100 {
+				t.Errorf("expected host port 9100, got %d", n.HostPort)
+			}
+		}
+	}
+
+	var details []WarningDetail
+	for _, wd := range graph.WarningDetails {
+		if wd.WarningType == WarningHostNetwork {
+			details = append(details, wd)
+		}
+	}
+	if len(details) != 1 || details[0].WorkloadID != "default/agent" {
+		t.Errorf("expected one host-network warning detail for default/agent, got %+v", details)
+	}
+}
+
+func TestBuilderBuildAllowAllIngress(t *testing.T) {
+	workloads := []k8s.Workload{
+		{Name: "web", Namespace: "default", Labels: map[string]string{"app": "web"},
+			Ports: []k8s.Port{{ContainerPort: 8080, Protocol: corev1.ProtocolTCP}, {ContainerPort: 9090, Protocol: corev1.ProtocolTCP}}},
+		{Name: "api", Namespace: "default", Labels: map[string]string{"app": "api"}},
+		{Name: "worker", Namespace: "jobs", Labels: map[string]string{"app": "worker"}},
+	}
+	policies := []k8s.Policy{{
+		Name:      "allow-all-web",
+		Namespace: "default",
+		Type:      k8s.PolicyTypeK8sNetworkPolicy,
+		K8sNetworkPolicy: &networkingv1.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "allow-all-web", Namespace: "default"},
+			Spec: networkingv1.NetworkPolicySpec{
+				PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+				Ingress: []networkingv1.NetworkPolicyIngressRule{{
+					Ports: []networkingv1.NetworkPolicyPort{{Port: &intstr.IntOrString{Type: intstr.Int, IntVal: 8080}}},
+				}},
+			},
+		},
+	}}
+
+	graph := NewBuilder().Build(workloads, policies)
+
+	var anyNodes []Node
+	for _, n := range graph.Nodes {
+		if n.Type == NodeTypeAny {
+			anyNodes = append(anyNodes, n)
+		}
+		if n.ID == "default/web" && !slices.Contains(n.Warnings, WarningNoSelector) {
+			t.Errorf("expected web flagged with %s, got %v", WarningNoSelector, n.Warnings)
+		}
+	}
+	if len(anyNodes) != 1 || anyNodes[0].ID != AnyNodeID {
+		t.Fatalf("expected one ANY node, got %+v", anyNodes)
+	}
+
+	// One edge from ANY instead of one from each of the other workloads
+	if len(graph.Edges) != 1 {
+		t.Fatalf("expected 1 edge, got %d: %+v", len(graph.Edges), graph.Edges)
+	}
+	e := graph.Edges[0]
+	if e.Source != AnyNodeID || e.Target != PortID("default/web", 8080, "TCP") {
+		t.Errorf("expected edge from ANY to web's 8080 port, got %s -> %s", e.Source, e.Target)
+	}
+	if e.Metadata["warning"] != string(WarningNoSelector) {
+		t.Errorf("expected edge tagged with %s, got metadata %v", WarningNoSelector, e.Metadata)
+	}
+
+	// Without allow-all rules there is no ANY node
+	for _, n := range NewBuilder().Build(workloads, nil).Nodes {
+		if n.Type == NodeTypeAny {
+			t.Error("expected no ANY node without allow-all rules")
+		}
+	}
+}
+
+func TestBuilderBuildDuplicatePorts(t *testing.T) {
+	// A deployment whose two containers both declare 8080/TCP, plus 8080/UDP
+	workloads := []k8s.Workload{
+		{Name: "web", Namespace: "default", Labels: map[string]string{"app": "web"}, Ports: []k8s.Port{
+			{Name: "http", ContainerPort: 8080, Protocol: corev1.ProtocolTCP},
+			{Name: "sidecar-http", ContainerPort: 8080, Protocol: corev1.ProtocolTCP},
+			{ContainerPort: 8080, Protocol: corev1.ProtocolUDP},
+		}},
+		{Name: "client", Namespace: "default", Labels: map[string]string{"app": "client"}},
+	}
+	policies := []k8s.Policy{{
+		Name:      "allow-client",
+		Namespace: "default",
+		Type:      k8s.PolicyTypeK8sNetworkPolicy,
+		K8sNetworkPolicy: &networkingv1.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "allow-client", Namespace: "default"},
+			Spec: networkingv1.NetworkPolicySpec{
+				PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+				Ingress: []networkingv1.NetworkPolicyIngressRule{{
+					From: []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "client"}}}},
+				}},
+			},
+		},
+	}}
+
+	graph := NewBuilder().Build(workloads, policies)
+
+	labels := make(map[string][]string) // port ID -> labels of its nodes
+	for _, n := range graph.Nodes {
+		if n.Type == NodeTypePort {
+			labels[n.ID] = append(labels[n.ID], n.Label)
+		}
+	}
+	tcp, udp := PortID("default/web", 8080, "TCP"), PortID("default/web", 8080, "UDP")
+	if len(labels) != 2 || !slices.Equal(labels[tcp], []string{"http"}) || len(labels[udp]) != 1 {
+		t.Errorf("expected one http node for 8080/TCP and one for 8080/UDP, got %v", labels)
+	}
+
+	targets := make(map[string]int)
+	for _, e := range graph.Edges {
+		targets[e.Target]++
+	}
+	if len(graph.Edges) != 2 || targets[tcp] != 1 || targets[udp] != 1 {
+		t.Errorf("expected one edge to each port, got %v", targets)
+	}
+}
+
+func TestBuilderBuildServiceOnlyPorts(t *testing.T) {
+	// The deployment declares 8080 but its Service also targets 9090 and 53/UDP
+	workloads := []k8s.Workload{
+		{Name: "web", Namespace: "default", Labels: map[string]string{"app": "web"}, Ports: []k8s.Port{
+			{Name: "http", ContainerPort: 8080, Protocol: corev1.ProtocolTCP},
+		}},
+		{Name: "client", Namespace: "default", Labels: map[string]string{"app": "client"}},
+	}
+	services := []k8s.ServiceInfo{{
+		Name:      "web",
+		Namespace: "default",
+		Selector:  map[string]string{"app": "web"},
+		Ports: []k8s.ServicePortInfo{
+			{Name: "http", Port: 80, TargetPort: intstr.FromInt32(8080)},
+			{Name: "metrics", Port: 9090},
+			{Name: "dns", Port: 53, Protocol: corev1.ProtocolUDP},
+			{Name: "admin", Port: 81, TargetPort: intstr.FromString("admin")},
+		},
+	}}
+	policies := []k8s.Policy{{
+		Name:      "allow-client",
+		Namespace: "default",
+		Type:      k8s.PolicyTypeK8sNetworkPolicy,
+		K8sNetworkPolicy: &networkingv1.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "allow-client", Namespace: "default"},
+			Spec: networkingv1.NetworkPolicySpec{
+				PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+				Ingress: []networkingv1.NetworkPolicyIngressRule{{
+					From:  []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "client"}}}},
+					Ports: []networkingv1.NetworkPolicyPort{{Port: &intstr.IntOrString{Type: intstr.Int, IntVal: 9090}}},
+				}},
+			},
+		},
+	}}
+
+	graph := NewBuilder().WithServices(services).Build(workloads, policies)
+
+	sources := make(map[string]string) // port ID -> metadata source
+	for _, n := range graph.Nodes {
+		if n.Type == NodeTypePort {
+			sources[n.ID] = n.Metadata["source"]
+		}
+	}
+	expected := map[string]string{
+		PortID("default/web", 8080, "TCP"): "",
+		PortID("default/web", 9090, "TCP"): "service",
+		PortID("default/web", 53, "UDP"):   "service",
+	}
+	if !maps.Equal(sources, expected) {
+		t.Errorf("expected port sources %v, got %v", expected, sources)
+	}
+
+	if len(graph.Edges) != 1 || graph.Edges[0].Target != PortID("default/web", 9090, "TCP") {
+		t.Errorf("expected one edge to the Service-only port, got %+v", graph.Edges)
+	}
+	if len(workloads[0].Ports) != 1 {
+		t.Errorf("expected the input workload's ports to be left alone, got %+v", workloads[0].Ports)
+	}
+}
+
+func TestBuilderBuildNamespaces(t *testing.T) {
+	workloads := []k8s.Workload{
+		{Name: "api", Namespace: "prod"},
+		{Name: "web", Namespace: "dev"},
+	}
+	namespaces := []k8s.NamespaceInfo{
+		{Name: "prod", Labels: map[string]string{"environment": "production"}},
+		{Name: "empty", Labels: map[string]string{"environment": "staging"}},
+	}
+
+	graph := NewBuilder().WithNamespaceLabels(namespaces).Build(workloads, nil)
+
+	expected := []NamespaceNode{
+		{Name: "dev"},
+		{Name: "empty", Labels: map[string]string{"environment": "staging"}},
+		{Name: "prod", Labels: map[string]string{"environment": "production"}},
+	}
+	equal := slices.EqualFunc(graph.Namespaces, expected, func(a, b NamespaceNode) bool {
+		return a.Name == b.Name && maps.Equal(a.Labels, b.Labels)
+	})
+	if !equal {
+		t.Errorf("expected namespaces %+v, got %+v", expected, graph.Namespaces)
+	}
+}
+
+func TestBuilderPortMatches(t *testing.T) {
+	builder := NewBuilder()
+	tcp := corev1.ProtocolTCP
+	udp := corev1.ProtocolUDP
+	endPort := int32(9000)
+
+	tests := map[string]struct {
+		workloadPort k8s.Port
+		policyPort   networkingv1.NetworkPolicyPort
+		expected     bool
+	}{
+		"nil port matches all": {
+			workloadPort: k8s.Port{ContainerPort: 80, Protocol: corev1.ProtocolTCP},
+			policyPort:   networkingv1.NetworkPolicyPort{},
+			expected:     true,
+		},
+		"exact port match": {
+			workloadPort: k8s.Port{ContainerPort: 80, Protocol: corev1.ProtocolTCP},
+			policyPort: networkingv1.NetworkPolicyPort{
+				Port:     &intstr.IntOrString{Type: intstr.Int, IntVal: 80},
+				Protocol: &tcp,
+			},
+			expected: true,
+		},
+		"port number mismatch": {
+			workloadPort: k8s.Port{ContainerPort: 80, Protocol: corev1.ProtocolTCP},
+			policyPort: networkingv1.NetworkPolicyPort{
+				Port:     &intstr.IntOrString{Type: intstr.Int, IntVal: 443},
+				Protocol: &tcp,
+			},
+			expected: false,
+		},
+		"protocol mismatch": {
+			workloadPort: k8s.Port{ContainerPort: 53, Protocol: corev1.ProtocolTCP},
+			policyPort: networkingv1.NetworkPolicyPort{
+				Port:     &intstr.IntOrString{Type: intstr.Int, IntVal: 53},
+				Protocol: &udp,
+			},
+			expected: false,
+		},
+		"named port match": {
+			workloadPort: k8s.Port{Name: "http", ContainerPort: 80, Protocol: corev1.ProtocolTCP},
+			policyPort: networkingv1.NetworkPolicyPort{
+				Port:     &intstr.IntOrString{Type: intstr.String, StrVal: "http"},
+				Protocol: &tcp,
+			},
+			expected: true,
+		},
+		"named port mismatch": {
+			workloadPort: k8s.Port{Name: "http", ContainerPort: 80, Protocol: corev1.ProtocolTCP},
+			policyPort: networkingv1.NetworkPolicyPort{
+				Port:     &intstr.IntOrString{Type: intstr.String, StrVal: "https"},
+				Protocol: &tcp,
+			},
+			expected: false,
+		},
+		"port inside range": {
+			workloadPort: k8s.Port{ContainerPort: 8500, Protocol: corev1.ProtocolTCP},
+			policyPort: networkingv1.NetworkPolicyPort{
+				Port:     &intstr.IntOrString{Type: intstr.Int, IntVal: 8000},
+				EndPort:  &endPort,
+				Protocol: &tcp,
+			},
+			expected: true,
+		},
+		"port at range end": {
+			workloadPort: k8s.Port{ContainerPort: 9000, Protocol: corev1.ProtocolTCP},
+			policyPort: networkingv1.NetworkPolicyPort{
+				Port:     &intstr.IntOrString{Type: intstr.Int, IntVal: 8000},
+				EndPort:  &endPort,
+				Protocol: &tcp,
+			},
+			expected: true,
+		},
+		"port outside range": {
+			workloadPort: k8s.Port{ContainerPort: 9001, Protocol: corev1.ProtocolTCP},
+			policyPort: networkingv1.NetworkPolicyPort{
+				Port:     &intstr.IntOrString{Type: intstr.Int, IntVal: 8000},
+				EndPort:  &endPort,
+				Protocol: &tcp,
+			},
+			expected: false,
+		},
+		"range with protocol mismatch": {
+			workloadPort: k8s.Port{ContainerPort: 8500, Protocol: corev1.ProtocolUDP},
+			policyPort: networkingv1.NetworkPolicyPort{
+				Port:     &intstr.IntOrString{Type: intstr.Int, IntVal: 8000},
+				EndPort:  &endPort,
+				Protocol: &tcp,
+			},
+			expected: false,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			result := builder.portMatches(tt.workloadPort, tt.policyPort)
+			if result != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestBuilderBuildSourceFile(t *testing.T) {
+	workloads := []k8s.Workload{
+		{Name: "frontend", Namespace: "default", Labels: map[string]string{"app": "frontend"}},
+		{
+			Name:      "backend",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "backend"},
+			Ports:     []k8s.Port{{Name: "http", ContainerPort: 8080, Protocol: corev1.ProtocolTCP}},
+		},
+	}
+	netpol := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "allow-frontend", Namespace: "default"},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "backend"}},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{
+				{
+					From: []networkingv1.NetworkPolicyPeer{
+						{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "frontend"}}},
+					},
+				},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		sourceFile string
+	}{
+		"live cluster policy": {
+			sourceFile: "",
+		},
+		"policy loaded from file": {
+			sourceFile: "manifests/netpol.yaml",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			policies := []k8s.Policy{
+				{
+					Name:             netpol.Name,
+					Namespace:        netpol.Namespace,
+					Type:             k8s.PolicyTypeK8sNetworkPolicy,
+					K8sNetworkPolicy: netpol,
+					SourceFile:       tt.sourceFile,
+				},
+			}
+
+			graph := NewBuilder().Build(workloads, policies)
+			if len(graph.Edges) != 1 {
+				t.Fatalf("expected 1 edge, got %d", len(graph.Edges))
+			}
+			if got := graph.Edges[0].Metadata["sourceFile"]; got != tt.sourceFile {
+				t.Errorf("expected sourceFile %q, got %q", tt.sourceFile, got)
+			}
+		})
+	}
+}
+
+func TestBuilderIstioHTTPOperations(t *testing.T) {
+	workloads := []k8s.Workload{
+		{Name: "client", Namespace: "default", Labels: map[string]string{"app": "client"}},
+		{
+			Name:      "api",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "api"},
+			Ports:     []k8s.Port{{Name: "http", ContainerPort: 8080, Protocol: corev1.ProtocolTCP}},
+		},
+	}
+
+	tests := map[string]struct {
+		to                 []*securityv1beta1.Rule_To
+		expectedOperations []HTTPOperation
+		expectedMethods    string
+		expectedPaths      string
+	}{
+		"methods only": {
+			to: []*securityv1beta1.Rule_To{
+				{Operation: &securityv1beta1.Operation{Methods: []string{"GET"}}},
+			},
+			expectedOperations: []HTTPOperation{
+				{Methods: []string{"GET"}},
+			},
+			expectedMethods: "GET",
+		},
+		"methods and paths": {
+			to: []*securityv1beta1.Rule_To{
+				{Operation: &securityv1beta1.Operation{Methods: []string{"GET"}, Paths: []string{"/api/v1/*"}}},
+				{Operation: &securityv1beta1.Operation{Methods: []string{"POST"}, Paths: []string{"/admin"}}},
+			},
+			expectedOperations: []HTTPOperation{
+				{Methods: []string{"GET"}, Paths: []string{"/api/v1/*"}},
+				{Methods: []string{"POST"}, Paths: []string{"/admin"}},
+			},
+			expectedMethods: "GET,POST",
+			expectedPaths:   "/api/v1/*,/admin",
+		},
+		"ports only": {
+			to: []*securityv1beta1.Rule_To{
+				{Operation: &securityv1beta1.Operation{Ports: []string{"8080"}}},
+			},
+			expectedOperations: nil,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			policy := istioPolicy("default", "allow-api", map[string]string{"app": "api"}, &securityv1beta1.Rule{
+				From: []*securityv1beta1.Rule_From{
+					{Source: &securityv1beta1.Source{Namespaces: []string{"default"}}},
+				},
+				To: tt.to,
+			})
+
+			graph := NewBuilder().Build(workloads, []k8s.Policy{policy})
+			if len(graph.Edges) != 1 {
+				t.Fatalf("expected 1 edge, got %d", len(graph.Edges))
+			}
+
+			ops := graph.Edges[0].Operations
+			if len(ops) != len(tt.expectedOperations) {
+				t.Fatalf("expected %d operations, got %d", len(tt.expectedOperations), len(ops))
+			}
+			for i, op := range ops {
+				expected := tt.expectedOperations[i]
+				if len(op.Methods) != len(expected.Methods) || op.Methods[0] != expected.Methods[0] {
+					t.Errorf("expected methods %v, got %v", expected.Methods, op.Methods)
+				}
+				if len(op.Paths) != len(expected.Paths) || (len(op.Paths) > 0 && op.Paths[0] != expected.Paths[0]) {
+					t.Errorf("expected paths %v, got %v", expected.Paths, op.Paths)
+				}
+			}
+
+			metadata := graph.Edges[0].Metadata
+			if metadata["methods"] != tt.expectedMethods {
+				t.Errorf("expected methods metadata %q, got %q", tt.expectedMethods, metadata["methods"])
+			}
+			if metadata["paths"] != tt.expectedPaths {
+				t.Errorf("expected paths metadata %q, got %q", tt.expectedPaths, metadata["paths"])
+			}
+			if _, ok := metadata["paths"]; ok && tt.expectedPaths == "" {
+				t.Errorf("expected no paths metadata, got %q", metadata["paths"])
+			}
+		})
+	}
+}
+
+func TestBuilderFindIstioTargetWorkloads(t *testing.T) {
+	builder := NewBuilder()
+	workloadsByNS := map[string][]k8s.Workload{
+		"default": {
+			{Name: "api-prod", Namespace: "default", Labels: map[string]string{"app": "api", "env": "prod"}},
+			{Name: "api-dev", Namespace: "default", Labels: map[string]string{"app": "api", "env": "dev"}},
+			{Name: "web-prod", Namespace: "default", Labels: map[string]string{"app": "web", "env": "prod"}},
+		},
+		"other": {
+			{Name: "api-prod", Namespace: "other", Labels: map[string]string{"app": "api", "env": "prod"}},
+		},
+	}
+
+	tests := map[string]struct {
+		selector *k8s.IstioWorkloadSelector
+		expected []string
+	}{
+		"nil selector matches all in namespace": {
+			selector: nil,
+			expected: []string{"api-prod", "api-dev", "web-prod"},
+		},
+		"empty matchLabels matches all in namespace": {
+			selector: &istiotypev1beta1.WorkloadSelector{MatchLabels: map[string]string{}},
+			expected: []string{"api-prod", "api-dev", "web-prod"},
+		},
+		"two-key selector matches one workload": {
+			selector: &istiotypev1beta1.WorkloadSelector{MatchLabels: map[string]string{"app": "api", "env": "prod"}},
+			expected: []string{"api-prod"},
+		},
+		"empty value requires the key": {
+			selector: &istiotypev1beta1.WorkloadSelector{MatchLabels: map[string]string{"tier": ""}},
+			expected: nil,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			result := builder.findIstioTargetWorkloads("default", tt.selector, workloadsByNS)
+			if len(result) != len(tt.expected) {
+				t.Fatalf("expected %d workloads, got %d", len(tt.expected), len(result))
+			}
+			for i, w := range result {
+				if w.Name != tt.expected[i] || w.Namespace != "default" {
+					t.Errorf("expected default/%s, got %s/%s", tt.expected[i], w.Namespace, w.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestBuilderIstioRootNamespace(t *testing.T) {
+	workloads := []k8s.Workload{
+		{Name: "monitor", Namespace: "ops", Labels: map[string]string{"app": "monitor"}},
+		{Name: "api", Namespace: "apps", Labels: map[string]string{"app": "api", "metrics": "on"},
+			Ports: []k8s.Port{{ContainerPort: 9090, Protocol: corev1.ProtocolTCP}}},
+		{Name: "db", Namespace: "data", Labels: map[string]string{"app": "db", "metrics": "on"},
+			Ports: []k8s.Port{{ContainerPort: 9090, Protocol: corev1.ProtocolTCP}}},
+		{Name: "web", Namespace: "apps", Labels: map[string]string{"app": "web"},
+			Ports: []k8s.Port{{ContainerPort: 9090, Protocol: corev1.ProtocolTCP}}},
+	}
+	// A mesh-wide ALLOW letting the monitor scrape every workload labeled metrics=on
+	policy := istioPolicy("istio-system", "allow-metrics", map[string]string{"metrics": "on"}, &securityv1beta1.Rule{
+		From: []*securityv1beta1.Rule_From{
+			{Source: &securityv1beta1.Source{Namespaces: []string{"ops"}}},
+		},
+	})
+
+	tests := map[string]struct {
+		builder         *Builder
+		expectedTargets []string
+	}{
+		"default root namespace": {
+			builder:         NewBuilder(),
+			expectedTargets: []string{PortID("apps/api", 9090, "TCP"), PortID("data/db", 9090, "TCP")},
+		},
+		"other root namespace": {
+			builder: NewBuilder().WithIstioRootNamespace("mesh-root"),
+		},
+		"disabled": {
+			builder: NewBuilder().WithIstioRootNamespace(""),
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			graph := tt.builder.Build(workloads, []k8s.Policy{policy})
+
+			var targets []string
+			for _, e := range graph.Edges {
+				if e.Source != "ops/monitor" {
+					t.Errorf("unexpected edge source %s", e.Source)
+				}
+				targets = append(targets, e.Target)
+			}
+			sort.Strings(targets)
+			if !slices.Equal(targets, tt.expectedTargets) {
+				t.Errorf("expected targets %v, got %v", tt.expectedTargets, targets)
+			}
+		})
+	}
+}
+
+func TestBuilderFindIstioSourceWorkloadsPrincipals(t *testing.T) {
+	builder := NewBuilder()
+	workloadsByNS := map[string][]k8s.Workload{
+		"apps": {
+			{Name: "web", Namespace: "apps", ServiceAccount: "web"},
+			{Name: "web-canary", Namespace: "apps", ServiceAccount: "web"},
+			{Name: "worker", Namespace: "apps", ServiceAccount: "worker"},
+			{Name: "legacy", Namespace: "apps"},
+		},
+		"other": {
+			{Name: "web", Namespace: "other", ServiceAccount: "web"},
+		},
+		"sa": {
+			{Name: "job", Namespace: "sa", ServiceAccount: "ns"},
+		},
+	}
+
+	tests := map[string]struct {
+		principals []string
+		expected   []string
+	}{
+		"service account matches only its workloads": {
+			principals: []string{"cluster.local/ns/apps/sa/web"},
+			expected:   []string{"apps/web", "apps/web-canary"},
+		},
+		"unset serviceAccountName is default": {
+			principals: []string{"cluster.local/ns/apps/sa/default"},
+			expected:   []string{"apps/legacy"},
+		},
+		"wildcard service account matches namespace": {
+			principals: []string{"cluster.local/ns/apps/sa/*"},
+			expected:   []string{"apps/web", "apps/web-canary", "apps/worker", "apps/legacy"},
+		},
+		"several principals": {
+			principals: []string{"cluster.local/ns/apps/sa/worker", "cluster.local/ns/other/sa/web"},
+			expected:   []string{"apps/worker", "other/web"},
+		},
+		"unknown service account": {
+			principals: []string{"cluster.local/ns/apps/sa/missing"},
+			expected:   nil,
+		},
+		"namespace named like a path segment": {
+			principals: []string{"cluster.local/ns/sa/sa/ns"},
+			expected:   []string{"sa/job"},
+		},
+		"other trust domain": {
+			principals: []string{"example.org/ns/apps/sa/worker"},
+			expected:   []string{"apps/worker"},
+		},
+		"prefix wildcard across namespaces": {
+			principals: []string{"cluster.local/ns/*"},
+			expected:   []string{"apps/web", "apps/web-canary", "apps/worker", "apps/legacy", "other/web", "sa/job"},
+		},
+		"prefix wildcard inside a namespace": {
+			principals: []string{"cluster.local/ns/ap*"},
+			expected:   []string{"apps/web", "apps/web-canary", "apps/worker", "apps/legacy"},
+		},
+		"suffix wildcard": {
+			principals: []string{"*/sa/web"},
+			expected:   []string{"apps/web", "apps/web-canary", "other/web"},
+		},
+		"any principal": {
+			principals: []string{"*"},
+			expected:   []string{"apps/web", "apps/web-canary", "apps/worker", "apps/legacy", "other/web", "sa/job"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			from := []*k8s.IstioSource{{Source: &securityv1beta1.Source{Principals: tt.principals}}}
+			result := builder.findIstioSourceWorkloads("apps", from, workloadsByNS)
+			if len(result) != len(tt.expected) {
+				t.Fatalf("expected %d workloads, got %d", len(tt.expected), len(result))
+			}
+			for i, w := range result {
+				if id := WorkloadID(w.Namespace, w.Name); id != tt.expected[i] {
+					t.Errorf("expected %s at %d, got %s", tt.expected[i], i, id)
+				}
+			}
+		})
+	}
+}
+
+func TestBuilderFindIstioSourceWorkloadsExclusions(t *testing.T) {
+	builder := NewBuilder()
+	workloadsByNS := map[string][]k8s.Workload{
+		"frontend": {{Name: "web", Namespace: "frontend", ServiceAccount: "web"}},
+		"batch": {
+			{Name: "jobs", Namespace: "batch", ServiceAccount: "jobs"},
+			{Name: "cron", Namespace: "batch", ServiceAccount: "cron"},
+		},
+		"sandbox": {{Name: "scratch", Namespace: "sandbox"}},
+	}
+
+	tests := map[string]struct {
+		source   *securityv1beta1.Source
+		expected []string
+	}{
+		"notNamespaces removes one of three namespaces": {
+			source: &securityv1beta1.Source{
+				Namespaces:    []string{"frontend", "batch", "sandbox"},
+				NotNamespaces: []string{"sandbox"},
+			},
+			expected: []string{"batch/cron", "batch/jobs", "frontend/web"},
+		},
+		"only notNamespaces means all except": {
+			source:   &securityv1beta1.Source{NotNamespaces: []string{"batch"}},
+			expected: []string{"frontend/web", "sandbox/scratch"},
+		},
+		"notPrincipals removes a service account": {
+			source: &securityv1beta1.Source{
+				Namespaces:    []string{"batch"},
+				NotPrincipals: []string{"cluster.local/ns/batch/sa/cron"},
+			},
+			expected: []string{"batch/jobs"},
+		},
+		"only notPrincipals means all except": {
+			source:   &securityv1beta1.Source{NotPrincipals: []string{"cluster.local/ns/sandbox/sa/default"}},
+			expected: []string{"batch/cron", "batch/jobs", "frontend/web"},
+		},
+		"principals minus notNamespaces": {
+			source: &securityv1beta1.Source{
+				Principals:    []string{"cluster.local/ns/frontend/sa/web", "cluster.local/ns/batch/sa/jobs"},
+				NotNamespaces: []string{"frontend"},
+			},
+			expected: []string{"batch/jobs"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			from := []*k8s.IstioSource{{Source: tt.source}}
+			var result []string
+			for _, w := range builder.findIstioSourceWorkloads("frontend", from, workloadsByNS) {
+				result = append(result, WorkloadID(w.Namespace, w.Name))
+			}
+			sort.Strings(result)
+			if strings.Join(result, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestBuilderIstioPolicyConflicts(t *testing.T) {
+	workloads := []k8s.Workload{
+		{Name: "client", Namespace: "clients", Labels: map[string]string{"app": "client"}},
+		{Name: "other", Namespace: "others", Labels: map[string]string{"app": "other"}},
+		{
+			Name:      "api",
+			Namespace: "apps",
+			Labels:    map[string]string{"app": "api"},
+			Ports: []k8s.Port{
+				{Name: "http", ContainerPort: 8080, Protocol: corev1.ProtocolTCP},
+				{Name: "metrics", ContainerPort: 9090, Protocol: corev1.ProtocolTCP},
+			},
+		},
+	}
+	rule := func(namespace, port string) *securityv1beta1.Rule {
+		return &securityv1beta1.Rule{
+			From: []*securityv1beta1.Rule_From{{Source: &securityv1beta1.Source{Namespaces: []string{namespace}}}},
+			To:   []*securityv1beta1.Rule_To{{Operation: &securityv1beta1.Operation{Ports: []string{port}}}},
+		}
+	}
+	deny := func(policy k8s.Policy) k8s.Policy {
+		policy.IstioAuthPolicy.Spec.Action = securityv1beta1.AuthorizationPolicy_DENY
+		return policy
+	}
+	selector := map[string]string{"app": "api"}
+	allowClients := istioPolicy("apps", "allow-clients", selector, rule("clients", "8080"))
+
+	tests := map[string]struct {
+		policies         []k8s.Policy
+		expectedPolicies []string
+	}{
+		"deny overrides allow for the same source and port": {
+			policies:         []k8s.Policy{allowClients, deny(istioPolicy("apps", "deny-clients", selector, rule("clients", "8080")))},
+			expectedPolicies: []string{"apps/allow-clients, apps/deny-clients"},
+		},
+		"deny on another port": {
+			policies: []k8s.Policy{allowClients, deny(istioPolicy("apps", "deny-metrics", selector, rule("clients", "9090")))},
+		},
+		"deny for other sources": {
+			policies: []k8s.Policy{allowClients, deny(istioPolicy("apps", "deny-others", selector, rule("others", "8080")))},
+		},
+		"two allow policies": {
+			policies: []k8s.Policy{allowClients, istioPolicy("apps", "allow-again", selector, rule("clients", "8080"))},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			graph := NewBuilder().Build(workloads, tt.policies)
+
+			var conflicts []string
+			for _, wd := range graph.WarningDetails {
+				if wd.WarningType != WarningPolicyConflict {
+					continue
+				}
+				if wd.WorkloadID != "apps/api" {
+					t.Errorf("expected conflict on apps/api, got %s", wd.WorkloadID)
+				}
+				conflicts = append(conflicts, wd.PolicyName)
+			}
+			if strings.Join(conflicts, ";") != strings.Join(tt.expectedPolicies, ";") {
+				t.Errorf("expected conflicts %v, got %v", tt.expectedPolicies, conflicts)
+			}
+
+			for _, n := range graph.Nodes {
+				if n.ID == "apps/api" && slices.Contains(n.Warnings, WarningPolicyConflict) != (len(tt.expectedPolicies) > 0) {
+					t.Errorf("unexpected node warnings %v", n.Warnings)
+				}
+			}
+		})
+	}
+}
+
+func TestBuilderIstioPortLabels(t *testing.T) {
+	workloads := []k8s.Workload{
+		{Name: "client", Namespace: "default", Labels: map[string]string{"app": "client"}},
+		{
+			Name:      "edge",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "edge"},
+			Ports: []k8s.Port{
+				{Name: "http", ContainerPort: 8080, Protocol: corev1.ProtocolTCP},
+				{ContainerPort: 443, Protocol: corev1.ProtocolUDP},
+				{ContainerPort: 3868, Protocol: corev1.ProtocolSCTP},
+				{Name: "dns", ContainerPort: 53, Protocol: corev1.ProtocolUDP},
+				{Name: "dns-tcp", ContainerPort: 53, Protocol: corev1.ProtocolTCP},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		ports          []string
+		expectedLabels map[string]string // target port ID -> label
+	}{
+		"named port": {
+			ports:          []string{"8080"},
+			expectedLabels: map[string]string{"default/edge:TCP/8080": "http:8080"},
+		},
+		"declared UDP port": {
+			ports:          []string{"443"},
+			expectedLabels: map[string]string{"default/edge:UDP/443": "UDP:443"},
+		},
+		"declared SCTP port": {
+			ports:          []string{"3868"},
+			expectedLabels: map[string]string{"default/edge:SCTP/3868": "SCTP:3868"},
+		},
+		"port declared for UDP and TCP": {
+			ports: []string{"53"},
+			expectedLabels: map[string]string{
+				"default/edge:UDP/53": "dns:53",
+				"default/edge:TCP/53": "dns-tcp:53",
+			},
+		},
+		"all declared ports": {
+			ports: nil,
+			expectedLabels: map[string]string{
+				"default/edge:TCP/8080":  "http:8080",
+				"default/edge:UDP/443":   "UDP:443",
+				"default/edge:SCTP/3868": "SCTP:3868",
+				"default/edge:UDP/53":    "dns:53",
+				"default/edge:TCP/53":    "dns-tcp:53",
+			},
+		},
+		"undeclared port falls back to TCP": {
+			ports:          []string{"9090"},
+			expectedLabels: map[string]string{"default/edge:TCP/9090": "TCP:9090"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			rule := &securityv1beta1.Rule{
+				From: []*securityv1beta1.Rule_From{
+					{Source: &securityv1beta1.Source{Namespaces: []string{"default"}}},
+				},
+			}
+			if tt.ports != nil {
+				rule.To = []*securityv1beta1.Rule_To{
+					{Operation: &securityv1beta1.Operation{Ports: tt.ports}},
+				}
+			}
+			policy := istioPolicy("default", "allow-edge", map[string]string{"app": "edge"}, rule)
+
+			graph := NewBuilder().Build(workloads, []k8s.Policy{policy})
+			if len(graph.Edges) != len(tt.expectedLabels) {
+				t.Fatalf("expected %d edges, got %d", len(tt.expectedLabels), len(graph.Edges))
+			}
+			for _, e := range graph.Edges {
+				expected, ok := tt.expectedLabels[e.Target]
+				if !ok {
+					t.Errorf("unexpected edge target %s", e.Target)
+					continue
+				}
+				if e.Label != expected {
+					t.Errorf("edge to %s: expected label %q, got %q", e.Target, expected, e.Label)
+				}
+			}
+		})
+	}
+}
+
+func TestBuilderIstioServicePorts(t *testing.T) {
+	workloads := []k8s.Workload{
+		{Name: "client", Namespace: "default", Labels: map[string]string{"app": "client"}},
+		{
+			Name:      "api",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "api"},
+			Ports: []k8s.Port{
+				{Name: "http", ContainerPort: 8080, Protocol: corev1.ProtocolTCP},
+				{Name: "grpc", ContainerPort: 9090, Protocol: corev1.ProtocolTCP},
+			},
+		},
+	}
+	services := []k8s.ServiceInfo{
+		{
+			Name:      "api",
+			Namespace: "default",
+			Selector:  map[string]string{"app": "api"},
+			Ports: []k8s.ServicePortInfo{
+				{Name: "http", Port: 80, TargetPort: intstr.FromInt32(8080)},
+				{Name: "grpc", Port: 90, TargetPort: intstr.FromString("grpc")},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		port           string
+		expectedTarget string
+	}{
+		"service port with numeric targetPort": {
+			port:           "80",
+			expectedTarget: "default/api:TCP/8080",
+		},
+		"service port with named targetPort": {
+			port:           "90",
+			expectedTarget: "default/api:TCP/9090",
+		},
+		"container port takes precedence": {
+			port:           "8080",
+			expectedTarget: "default/api:TCP/8080",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			policy := istioPolicy("default", "allow-api", map[string]string{"app": "api"}, &securityv1beta1.Rule{
+				From: []*securityv1beta1.Rule_From{
+					{Source: &securityv1beta1.Source{Namespaces: []string{"default"}}},
+				},
+				To: []*securityv1beta1.Rule_To{
+					{Operation: &securityv1beta1.Operation{Ports: []string{tt.port}}},
+				},
+			})
+
+			graph := NewBuilder().WithServices(services).Build(workloads, []k8s.Policy{policy})
+			if len(graph.Edges) != 1 {
+				t.Fatalf("expected 1 edge, got %d", len(graph.Edges))
+			}
+			if graph.Edges[0].Target != tt.expectedTarget {
+				t.Errorf("expected target %s, got %s", tt.expectedTarget, graph.Edges[0].Target)
+			}
+		})
+	}
+}
+
+func TestBuilderIstioNamedPorts(t *testing.T) {
+	workloads := []k8s.Workload{
+		{Name: "client", Namespace: "default", Labels: map[string]string{"app": "client"}},
+		{
+			Name:      "api",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "api"},
+			Ports: []k8s.Port{
+				{Name: "http", ContainerPort: 8080, Protocol: corev1.ProtocolTCP},
+				{Name: "grpc", ContainerPort: 9090, Protocol: corev1.ProtocolTCP},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		ports           []string
+		expectedTargets []string
+	}{
+		"port by name": {
+			ports:           []string{"http"},
+			expectedTargets: []string{"default/api:TCP/8080"},
+		},
+		"names and numbers": {
+			ports:           []string{"http", "9090"},
+			expectedTargets: []string{"default/api:TCP/8080", "default/api:TCP/9090"},
+		},
+		"name the workload doesn't declare": {
+			ports:           []string{"metrics"},
+			expectedTargets: nil,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			policy := istioPolicy("default", "allow-api", map[string]string{"app": "api"}, &securityv1beta1.Rule{
+				From: []*securityv1beta1.Rule_From{
+					{Source: &securityv1beta1.Source{Namespaces: []string{"default"}}},
+				},
+				To: []*securityv1beta1.Rule_To{
+					{Operation: &securityv1beta1.Operation{Ports: tt.ports}},
+				},
+			})
+
+			graph := NewBuilder().Build(workloads, []k8s.Policy{policy})
+			var targets []string
+			for _, e := range graph.Edges {
+				targets = append(targets, e.Target)
+			}
+			slices.Sort(targets)
+			if !slices.Equal(targets, tt.expectedTargets) {
+				t.Errorf("expected targets %v, got %v", tt.expectedTargets, targets)
+			}
+		})
+	}
+}
+
+func TestBuilderMTLSModes(t *testing.T) {
+	peerAuth := func(namespace, name string, selector map[string]string, mode securityv1beta1.PeerAuthentication_MutualTLS_Mode) k8s.Policy {
+		pa := &k8s.IstioPeerAuthentication{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		}
+		if selector != nil {
+			pa.Spec.Selector = &istiotypev1beta1.WorkloadSelector{MatchLabels: selector}
+		}
+		pa.Spec.Mtls = &securityv1beta1.PeerAuthentication_MutualTLS{Mode: mode}
+		return k8s.Policy{
+			Name:          name,
+			Namespace:     namespace,
+			Type:          k8s.PolicyTypeIstioPeerAuthentication,
+			IstioPeerAuth: pa,
+		}
+	}
+
+	workloads := []k8s.Workload{
+		{Name: "api", Namespace: "default", Labels: map[string]string{"app": "api"}},
+		{Name: "legacy", Namespace: "default", Labels: map[string]string{"app": "legacy"}},
+		{Name: "db", Namespace: "data", Labels: map[string]string{"app": "db"}},
+	}
+	policies := []k8s.Policy{
+		peerAuth("default", "default", nil, securityv1beta1.PeerAuthentication_MutualTLS_STRICT),
+		peerAuth("default", "legacy", map[string]string{"app": "legacy"}, securityv1beta1.PeerAuthentication_MutualTLS_PERMISSIVE),
+		peerAuth("data", "unset", nil, securityv1beta1.PeerAuthentication_MutualTLS_UNSET),
+	}
+
+	graph := NewBuilder().Build(workloads, policies)
+
+	expected := map[string]string{
+		"default/api":    "STRICT",
+		"default/legacy": "PERMISSIVE",
+		"data/db":        "",
+	}
+	for _, n := range graph.Nodes {
+		mode, ok := expected[n.ID]
+		if !ok {
+			continue
+		}
+		if n.Metadata["mtlsMode"] != mode {
+			t.Errorf("%s: expected mtlsMode %q, got %q", n.ID, mode, n.Metadata["mtlsMode"])
+		}
+	}
+
+	// The workload's own labels must not be modified
+	if _, ok := workloads[0].Labels["mtlsMode"]; ok {
+		t.Error("mtlsMode leaked into workload labels")
+	}
+}
+
+func TestBuilderBuildIgnoredWorkloads(t *testing.T) {
+	allowFrontend := k8s.Policy{
+		Name:      "allow-frontend",
+		Namespace: "default",
+		Type:      k8s.PolicyTypeK8sNetworkPolicy,
+		K8sNetworkPolicy: &networkingv1.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "allow-frontend", Namespace: "default"},
+			Spec: networkingv1.NetworkPolicySpec{
+				PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "backend"}},
+				Ingress: []networkingv1.NetworkPolicyIngressRule{
+					{
+						From: []networkingv1.NetworkPolicyPeer{
+							{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "frontend"}}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		ignore        string
+		expectedNodes int
+		expectedEdges int
+		expectedStub  string
+	}{
+		"nothing ignored": {
+			expectedNodes: 5, // frontend, backend + port, unrelated + port
+			expectedEdges: 1,
+		},
+		"ignored but referenced becomes stub": {
+			ignore:        "frontend",
+			expectedNodes: 5,
+			expectedEdges: 1,
+			expectedStub:  "default/frontend",
+		},
+		"ignored and unreferenced is dropped": {
+			ignore:        "unrelated",
+			expectedNodes: 3,
+			expectedEdges: 1,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			workloads := []k8s.Workload{
+				{Name: "frontend", Namespace: "default", Labels: map[string]string{"app": "frontend"}},
+				{
+					Name:      "backend",
+					Namespace: "default",
+					Labels:    map[string]string{"app": "backend"},
+					Ports:     []k8s.Port{{ContainerPort: 8080, Protocol: corev1.ProtocolTCP}},
+				},
+				{
+					Name:      "unrelated",
+					Namespace: "default",
+					Labels:    map[string]string{"app": "unrelated"},
+					Ports:     []k8s.Port{{ContainerPort: 9090, Protocol: corev1.ProtocolTCP}},
+				},
+			}
+			for i := range workloads {
+				workloads[i].Ignored = workloads[i].Name == tt.ignore
+			}
+
+			graph := NewBuilder().Build(workloads, []k8s.Policy{allowFrontend})
+
+			if len(graph.Nodes) != tt.expectedNodes {
+				t.Errorf("expected %d nodes, got %d", tt.expectedNodes, len(graph.Nodes))
+			}
+			if len(graph.Edges) != tt.expectedEdges {
+				t.Errorf("expected %d edges, got %d", tt.expectedEdges, len(graph.Edges))
+			}
+			for _, n := range graph.Nodes {
+				if n.Stub != (n.ID == tt.expectedStub) {
+					t.Errorf("node %q: expected stub=%v, got %v", n.ID, n.ID == tt.expectedStub, n.Stub)
+				}
+			}
+		})
+	}
+}
+
+func TestBuilderIncremental(t *testing.T) {
+	workloads := []k8s.Workload{
+		{Name: "client", Namespace: "clients", Labels: map[string]string{"app": "client"}},
+		{Name: "api", Namespace: "apps", Labels: map[string]string{"app": "api"}, Ports: []k8s.Port{
+			{Name: "http", ContainerPort: 8080, Protocol: corev1.ProtocolTCP},
+			{Name: "metrics", ContainerPort: 9090, Protocol: corev1.ProtocolTCP},
+		}},
+		{Name: "db", Namespace: "apps", Labels: map[string]string{"app": "db"}, Ports: []k8s.Port{
+			{Name: "sql", ContainerPort: 5432, Protocol: corev1.ProtocolTCP},
+		}},
+	}
+	netpol := func(name string, target string, ports ...int32) k8s.Policy {
+		rule := networkingv1.NetworkPolicyIngressRule{
+			From: []networkingv1.NetworkPolicyPeer{{NamespaceSelector: &metav1.LabelSelector{}}},
+		}
+		for _, p := range ports {
+			rule.Ports = append(rule.Ports, networkingv1.NetworkPolicyPort{Port: &intstr.IntOrString{Type: intstr.Int, IntVal: p}})
+		}
+		return k8s.Policy{
+			Name:      name,
+			Namespace: "apps",
+			Type:      k8s.PolicyTypeK8sNetworkPolicy,
+			K8sNetworkPolicy: &networkingv1.NetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "apps"},
+				Spec: networkingv1.NetworkPolicySpec{
+					PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": target}},
+					Ingress:     []networkingv1.NetworkPolicyIngressRule{rule},
+				},
+			},
+		}
+	}
+	rule := &securityv1beta1.Rule{
+		From: []*securityv1beta1.Rule_From{{Source: &securityv1beta1.Source{Namespaces: []string{"clients"}}}},
+		To:   []*securityv1beta1.Rule_To{{Operation: &securityv1beta1.Operation{Ports: []string{"8080"}}}},
+	}
+	allow := istioPolicy("apps", "allow-clients", map[string]string{"app": "api"}, rule)
+	deny := istioPolicy("apps", "deny-clients", map[string]string{"app": "api"}, rule)
+	deny.IstioAuthPolicy.Spec.Action = securityv1beta1.AuthorizationPolicy_DENY
+
+	tests := map[string]struct {
+		initial  []k8s.Policy
+		update   func(b *Builder) *NetworkGraph
+		expected []k8s.Policy // policies a full rebuild is given
+	}{
+		"add a policy": {
+			initial:  []k8s.Policy{netpol("api", "api", 8080)},
+			update:   func(b *Builder) *NetworkGraph { return b.AddPolicy(netpol("db", "db", 5432)) },
+			expected: []k8s.Policy{netpol("api", "api", 8080), netpol("db", "db", 5432)},
+		},
+		"change a policy in place": {
+			initial:  []k8s.Policy{netpol("api", "api", 8080), allow},
+			update:   func(b *Builder) *NetworkGraph { return b.AddPolicy(netpol("api", "api", 8080, 9090)) },
+			expected: []k8s.Policy{netpol("api", "api", 8080, 9090), allow},
+		},
+		"drop a policy's ports": {
+			initial:  []k8s.Policy{netpol("api", "api", 8080)},
+			update:   func(b *Builder) *NetworkGraph { return b.AddPolicy(netpol("api", "api")) },
+			expected: []k8s.Policy{netpol("api", "api")},
+		},
+		"remove a policy": {
+			initial:  []k8s.Policy{netpol("api", "api", 8080), netpol("db", "db", 5432)},
+			update:   func(b *Builder) *NetworkGraph { return b.RemovePolicy(netpol("db", "db")) },
+			expected: []k8s.Policy{netpol("api", "api", 8080)},
+		},
+		"add a conflicting deny": {
+			initial:  []k8s.Policy{allow},
+			update:   func(b *Builder) *NetworkGraph { return b.AddPolicy(deny) },
+			expected: []k8s.Policy{allow, deny},
+		},
+		"remove a conflicting deny": {
+			initial:  []k8s.Policy{allow, deny},
+			update:   func(b *Builder) *NetworkGraph { return b.RemovePolicy(deny) },
+			expected: []k8s.Policy{allow},
+		},
+		"remove an unknown policy": {
+			initial:  []k8s.Policy{allow},
+			update:   func(b *Builder) *NetworkGraph { return b.RemovePolicy(deny) },
+			expected: []k8s.Policy{allow},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			builder := NewBuilder()
+			before := builder.Build(workloads, tt.initial)
+			got := tt.update(builder)
+
+			if expected := NewBuilder().Build(workloads, tt.expected); !reflect.DeepEqual(got, expected) {
+				t.Errorf("incremental graph differs from a full rebuild:\ngot      %+v\nexpected %+v", got, expected)
+			}
+			if expected := NewBuilder().Build(workloads, tt.initial); !reflect.DeepEqual(before, expected) {
+				t.Errorf("update changed the graph returned before it:\ngot      %+v\nexpected %+v", before, expected)
+			}
+		})
+	}
+
+	t.Run("without a build", func(t *testing.T) {
+		got := NewBuilder().AddPolicy(allow)
+		if expected := NewBuilder().Build(nil, []k8s.Policy{allow}); !reflect.DeepEqual(got, expected) {
+			t.Errorf("expected %+v, got %+v", expected, got)
+		}
+	})
+}
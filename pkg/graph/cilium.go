@@ -0,0 +1,283 @@
+package graph
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ddl-r-abdulaziz/dnmap/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/yaml"
+)
+
+// ciliumNamespaceLabel is the label Cilium endpoint selectors use to name a pod's namespace.
+const ciliumNamespaceLabel = "io.kubernetes.pod.namespace"
+
+// processCiliumNetworkPolicy creates edges for the endpoint-selector rules of a
+// CiliumNetworkPolicy. Ingress rules draw edges from the peers to the selected workloads,
+// egress rules from the selected workloads to the peers. Rules with L7 (HTTP, Kafka, DNS)
+// or FQDN conditions are graphed by port and reported as WarningCiliumL7, since only their
+// L3/L4 part is shown.
+func (b *Builder) processCiliumNetworkPolicy(policy *k8s.CiliumNetworkPolicy, workloadsByNS map[string][]k8s.Workload) ([]Edge, []WarningDetail) {
+	var edges []Edge
+	var warningDetails []WarningDetail
+
+	if policy == nil {
+		return edges, warningDetails
+	}
+
+	policyFullName := policy.Namespace + "/" + policy.Name
+
+	// Generate policy YAML once (elide managedFields)
+	policyYAML := ""
+	policyCopy := *policy
+	policyCopy.ManagedFields = nil
+	if yamlBytes, err := yaml.Marshal(policyCopy); err == nil {
+		policyYAML = string(yamlBytes)
+	}
+
+	warned := make(map[string]bool)
+	warnL7 := func(w k8s.Workload) {
+		wID := WorkloadID(w.Namespace, w.Name)
+		if warned[wID] {
+			return
+		}
+		warned[wID] = true
+		warningDetails = append(warningDetails, WarningDetail{
+			WorkloadID:   wID,
+			WorkloadName: w.Name,
+			Namespace:    w.Namespace,
+			PolicyName:   policyFullName,
+			WarningType:  WarningCiliumL7,
+		})
+	}
+
+	newEdge := func(source string, target k8s.Workload, port k8s.Port, ruleIdx int, rule, direction string) Edge {
+		protocol := string(port.Protocol)
+		if protocol == "" {
+			protocol = "TCP"
+		}
+		portID := PortID(WorkloadID(target.Namespace, target.Name), port.ContainerPort, protocol)
+		return Edge{
+			ID:         EdgeID(source, portID, direction, policyFullName, ruleIdx),
+			Source:     source,
+			Target:     portID,
+			Label:      fmt.Sprintf("%s:%d", protocol, port.ContainerPort),
+			Rule:       rule,
+			Policy:     policyFullName,
+			PolicyYAML: policyYAML,
+			Direction:  direction,
+			Metadata: map[string]string{
+				"policyType": "CiliumNetworkPolicy",
+				"ruleType":   direction,
+			},
+		}
+	}
+
+	for _, rule := range policy.Rules() {
+		selected := b.findCiliumEndpoints(policy.Namespace, rule.EndpointSelector, workloadsByNS)
+
+		for ruleIdx, ingress := range rule.Ingress {
+			sources := b.findCiliumPeers(policy.Namespace, ingress.FromEndpoints, ingress.FromEntities,
+				len(ingress.FromCIDR) > 0 || len(ingress.FromCIDRSet) > 0, workloadsByNS)
+			description := formatCiliumRule("Rule", ruleIdx, "from", ingress.FromEndpoints, ingress.FromEntities, ingress.ToPorts)
+			l7 := hasCiliumL7Rules(ingress.ToPorts)
+
+			for _, target := range selected {
+				if l7 {
+					warnL7(target)
+				}
+				targetWID := WorkloadID(target.Namespace, target.Name)
+				ports := b.getAllowedPorts(target, ciliumPolicyPorts(ingress.ToPorts))
+				for _, source := range sources {
+					sourceWID := WorkloadID(source.Namespace, source.Name)
+					if sourceWID == targetWID {
+						continue
+					}
+					for _, port := range ports {
+						edges = append(edges, newEdge(sourceWID, target, port, ruleIdx, description, DirectionIngress))
+					}
+				}
+			}
+		}
+
+		for ruleIdx, egress := range rule.Egress {
+			destinations := b.findCiliumPeers(policy.Namespace, egress.ToEndpoints, egress.ToEntities,
+				len(egress.ToCIDR) > 0 || len(egress.ToCIDRSet) > 0 || len(egress.ToFQDNs) > 0, workloadsByNS)
+			description := formatCiliumRule("Egress Rule", ruleIdx, "to", egress.ToEndpoints, egress.ToEntities, egress.ToPorts)
+			l7 := hasCiliumL7Rules(egress.ToPorts) || len(egress.ToFQDNs) > 0
+
+			for _, source := range selected {
+				if l7 {
+					warnL7(source)
+				}
+				sourceWID := WorkloadID(source.Namespace, source.Name)
+				for _, destination := range destinations {
+					if WorkloadID(destination.Namespace, destination.Name) == sourceWID {
+						continue
+					}
+					for _, port := range b.getAllowedPorts(destination, ciliumPolicyPorts(egress.ToPorts)) {
+						edges = append(edges, newEdge(sourceWID, destination, port, ruleIdx, description, DirectionEgress))
+					}
+				}
+			}
+		}
+	}
+
+	return edges, warningDetails
+}
+
+// findCiliumEndpoints returns the workloads a Cilium endpoint selector matches. Selectors
+// apply to the policy's namespace unless they name another through ciliumNamespaceLabel.
+func (b *Builder) findCiliumEndpoints(policyNamespace string, selector metav1.LabelSelector, workloadsByNS map[string][]k8s.Workload) []k8s.Workload {
+	selector, namespace := ciliumSelector(selector)
+	if namespace == "" {
+		namespace = policyNamespace
+	}
+	return b.findMatchingWorkloads(namespace, selector, workloadsByNS)
+}
+
+// findCiliumPeers returns the workloads matched by a rule's endpoint selectors and
+// entities. A rule without endpoints or entities allows every workload, unless it only
+// names external peers (CIDRs or FQDNs), which match none.
+func (b *Builder) findCiliumPeers(policyNamespace string, endpoints []metav1.LabelSelector, entities []string, external bool, workloadsByNS map[string][]k8s.Workload) []k8s.Workload {
+	var result []k8s.Workload
+	seen := make(map[string]bool)
+	add := func(workloads []k8s.Workload) {
+		for _, w := range workloads {
+			wID := WorkloadID(w.Namespace, w.Name)
+			if !seen[wID] {
+				seen[wID] = true
+				result = append(result, w)
+			}
+		}
+	}
+
+	allWorkloads := len(endpoints) == 0 && len(entities) == 0 && !external
+	for _, entity := range entities {
+		// "cluster" and "all" cover every pod; other entities (world, host, ...) are outside the map
+		if entity == "cluster" || entity == "all" {
+			allWorkloads = true
+		}
+	}
+	if allWorkloads {
+		for _, workloads := range workloadsByNS {
+			add(workloads)
+		}
+		return result
+	}
+
+	for _, selector := range endpoints {
+		add(b.findCiliumEndpoints(policyNamespace, selector, workloadsByNS))
+	}
+	return result
+}
+
+// ciliumSelector converts a Cilium endpoint selector to a plain pod label selector,
+// dropping the "k8s:" and "any:" label source prefixes, and returns the namespace it
+// names through ciliumNamespaceLabel, if any.
+func ciliumSelector(selector metav1.LabelSelector) (metav1.LabelSelector, string) {
+	var namespace string
+	result := metav1.LabelSelector{MatchLabels: make(map[string]string, len(selector.MatchLabels))}
+	for key, value := range selector.MatchLabels {
+		key = trimCiliumLabelSource(key)
+		if key == ciliumNamespaceLabel {
+			namespace = value
+			continue
+		}
+		result.MatchLabels[key] = value
+	}
+	for _, expr := range selector.MatchExpressions {
+		expr.Key = trimCiliumLabelSource(expr.Key)
+		result.MatchExpressions = append(result.MatchExpressions, expr)
+	}
+	return result, namespace
+}
+
+// trimCiliumLabelSource removes the source prefix Cilium allows on label keys.
+func trimCiliumLabelSource(key string) string {
+	for _, prefix := range []string{"k8s:", "any:"} {
+		if strings.HasPrefix(key, prefix) {
+			return strings.TrimPrefix(key, prefix)
+		}
+	}
+	return key
+}
+
+// ciliumPolicyPorts converts Cilium port rules to NetworkPolicy ports so they match
+// workload ports the same way; no ports, or a port of 0, allows every port.
+func ciliumPolicyPorts(rules []k8s.CiliumPortRule) []networkingv1.NetworkPolicyPort {
+	var ports []networkingv1.NetworkPolicyPort
+	for _, rule := range rules {
+		if len(rule.Ports) == 0 {
+			return nil
+		}
+		for _, p := range rule.Ports {
+			if p.Port == "" || p.Port == "0" {
+				return nil
+			}
+			var port networkingv1.NetworkPolicyPort
+			if p.Protocol != "" && p.Protocol != "ANY" {
+				protocol := corev1.Protocol(p.Protocol)
+				port.Protocol = &protocol
+			}
+			portValue := intstr.Parse(p.Port)
+			port.Port = &portValue
+			if p.EndPort != 0 {
+				endPort := p.EndPort
+				port.EndPort = &endPort
+			}
+			ports = append(ports, port)
+		}
+	}
+	return ports
+}
+
+// hasCiliumL7Rules reports whether any port rule carries L7 rules.
+func hasCiliumL7Rules(rules []k8s.CiliumPortRule) bool {
+	for _, rule := range rules {
+		if !rule.Rules.Empty() {
+			return true
+		}
+	}
+	return false
+}
+
+// formatCiliumRule creates a human-readable description of a Cilium ingress or egress rule.
+func formatCiliumRule(kind string, idx int, peerPrefix string, endpoints []metav1.LabelSelector, entities []string, ports []k8s.CiliumPortRule) string {
+	var peers []string
+	for i := range endpoints {
+		peers = append(peers, "endpoints: "+metav1.FormatLabelSelector(&endpoints[i]))
+	}
+	if len(entities) > 0 {
+		peers = append(peers, "entities: "+strings.Join(entities, ", "))
+	}
+	peerDescription := "all"
+	if len(peers) > 0 {
+		peerDescription = strings.Join(peers, ", ")
+	}
+
+	var portDescriptions []string
+	for _, rule := range ports {
+		for _, p := range rule.Ports {
+			protocol := p.Protocol
+			if protocol == "" {
+				protocol = "ANY"
+			}
+			port := p.Port
+			if p.EndPort != 0 {
+				port += "-" + strconv.Itoa(int(p.EndPort))
+			}
+			portDescriptions = append(portDescriptions, protocol+"/"+port)
+		}
+	}
+	portDescription := "all"
+	if len(portDescriptions) > 0 {
+		portDescription = strings.Join(portDescriptions, ", ")
+	}
+
+	return fmt.Sprintf("CiliumNetworkPolicy %s %d: %s: %s; ports: %s", kind, idx+1, peerPrefix, peerDescription, portDescription)
+}
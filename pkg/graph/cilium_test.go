@@ -0,0 +1,170 @@
+package graph
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/ddl-r-abdulaziz/dnmap/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestBuilderCiliumNetworkPolicy(t *testing.T) {
+	workloads := []k8s.Workload{
+		{Name: "web", Namespace: "apps", Labels: map[string]string{"app": "web"}},
+		{
+			Name:      "api",
+			Namespace: "apps",
+			Labels:    map[string]string{"app": "api"},
+			Ports: []k8s.Port{
+				{Name: "http", ContainerPort: 8080, Protocol: corev1.ProtocolTCP},
+				{Name: "grpc", ContainerPort: 9090, Protocol: corev1.ProtocolTCP},
+			},
+		},
+		{
+			Name:      "db",
+			Namespace: "data",
+			Labels:    map[string]string{"app": "db"},
+			Ports:     []k8s.Port{{Name: "postgres", ContainerPort: 5432, Protocol: corev1.ProtocolTCP}},
+		},
+	}
+	apiSelector := metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}}
+
+	tests := map[string]struct {
+		rule             k8s.CiliumRule
+		expectedEdges    []string
+		expectedWarnings []string
+	}{
+		"ingress from endpoints on a port": {
+			rule: k8s.CiliumRule{
+				EndpointSelector: apiSelector,
+				Ingress: []k8s.CiliumIngressRule{{
+					FromEndpoints: []metav1.LabelSelector{{MatchLabels: map[string]string{"k8s:app": "web"}}},
+					ToPorts:       []k8s.CiliumPortRule{{Ports: []k8s.CiliumPortProtocol{{Port: "8080", Protocol: "TCP"}}}},
+				}},
+			},
+			expectedEdges: []string{"apps/web->apps/api:TCP/8080 ingress"},
+		},
+		"ingress without ports allows all ports": {
+			rule: k8s.CiliumRule{
+				EndpointSelector: apiSelector,
+				Ingress: []k8s.CiliumIngressRule{{
+					FromEndpoints: []metav1.LabelSelector{{MatchLabels: map[string]string{"app": "web"}}},
+				}},
+			},
+			expectedEdges: []string{"apps/web->apps/api:TCP/8080 ingress", "apps/web->apps/api:TCP/9090 ingress"},
+		},
+		"ingress from another namespace": {
+			rule: k8s.CiliumRule{
+				EndpointSelector: apiSelector,
+				Ingress: []k8s.CiliumIngressRule{{
+					FromEndpoints: []metav1.LabelSelector{{MatchLabels: map[string]string{
+						"k8s:io.kubernetes.pod.namespace": "data",
+						"app":                             "db",
+					}}},
+					ToPorts: []k8s.CiliumPortRule{{Ports: []k8s.CiliumPortProtocol{{Port: "grpc"}}}},
+				}},
+			},
+			expectedEdges: []string{"data/db->apps/api:TCP/9090 ingress"},
+		},
+		"egress to endpoints": {
+			rule: k8s.CiliumRule{
+				EndpointSelector: apiSelector,
+				Egress: []k8s.CiliumEgressRule{{
+					ToEndpoints: []metav1.LabelSelector{{MatchLabels: map[string]string{
+						"io.kubernetes.pod.namespace": "data",
+					}}},
+					ToPorts: []k8s.CiliumPortRule{{Ports: []k8s.CiliumPortProtocol{{Port: "5432", Protocol: "ANY"}}}},
+				}},
+			},
+			expectedEdges: []string{"apps/api->data/db:TCP/5432 egress"},
+		},
+		"cluster entity allows every workload": {
+			rule: k8s.CiliumRule{
+				EndpointSelector: apiSelector,
+				Ingress: []k8s.CiliumIngressRule{{
+					FromEntities: []string{"cluster"},
+					ToPorts:      []k8s.CiliumPortRule{{Ports: []k8s.CiliumPortProtocol{{Port: "8080"}}}},
+				}},
+			},
+			expectedEdges: []string{"apps/web->apps/api:TCP/8080 ingress", "data/db->apps/api:TCP/8080 ingress"},
+		},
+		"CIDR-only ingress matches no workloads": {
+			rule: k8s.CiliumRule{
+				EndpointSelector: apiSelector,
+				Ingress:          []k8s.CiliumIngressRule{{FromCIDR: []string{"10.0.0.0/8"}}},
+			},
+			expectedEdges: nil,
+		},
+		"L7 rules are graphed by port and warned": {
+			rule: k8s.CiliumRule{
+				EndpointSelector: apiSelector,
+				Ingress: []k8s.CiliumIngressRule{{
+					FromEndpoints: []metav1.LabelSelector{{MatchLabels: map[string]string{"app": "web"}}},
+					ToPorts: []k8s.CiliumPortRule{{
+						Ports: []k8s.CiliumPortProtocol{{Port: "8080", Protocol: "TCP"}},
+						Rules: &k8s.CiliumL7Rules{HTTP: []map[string]any{{"method": "GET", "path": "/"}}},
+					}},
+				}},
+			},
+			expectedEdges:    []string{"apps/web->apps/api:TCP/8080 ingress"},
+			expectedWarnings: []string{"apps/api"},
+		},
+		"FQDN egress is warned": {
+			rule: k8s.CiliumRule{
+				EndpointSelector: apiSelector,
+				Egress: []k8s.CiliumEgressRule{{
+					ToFQDNs: []map[string]any{{"matchName": "example.com"}},
+				}},
+			},
+			expectedEdges:    nil,
+			expectedWarnings: []string{"apps/api"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			policy := k8s.Policy{
+				Name:      "cnp",
+				Namespace: "apps",
+				Type:      k8s.PolicyTypeCiliumNetworkPolicy,
+				CiliumNetworkPolicy: &k8s.CiliumNetworkPolicy{
+					ObjectMeta: metav1.ObjectMeta{Name: "cnp", Namespace: "apps"},
+					Spec:       &tt.rule,
+				},
+			}
+
+			graph := NewBuilder().Build(workloads, []k8s.Policy{policy})
+
+			var edges []string
+			for _, e := range graph.Edges {
+				edges = append(edges, e.Source+"->"+e.Target+" "+e.Direction)
+				if e.Policy != "apps/cnp" || e.Metadata["policyType"] != "CiliumNetworkPolicy" {
+					t.Errorf("expected edge granted by CiliumNetworkPolicy apps/cnp, got %s (%v)", e.Policy, e.Metadata)
+				}
+			}
+			sort.Strings(edges)
+			if strings.Join(edges, ",") != strings.Join(tt.expectedEdges, ",") {
+				t.Errorf("expected edges %v, got %v", tt.expectedEdges, edges)
+			}
+
+			var warned []string
+			for _, wd := range graph.WarningDetails {
+				if wd.WarningType == WarningCiliumL7 {
+					warned = append(warned, wd.WorkloadID)
+				}
+			}
+			if strings.Join(warned, ",") != strings.Join(tt.expectedWarnings, ",") {
+				t.Errorf("expected %s warnings for %v, got %v", WarningCiliumL7, tt.expectedWarnings, warned)
+			}
+
+			// The selected workload counts as covered by a policy
+			for _, wd := range graph.WarningDetails {
+				if wd.WarningType == WarningUncovered && wd.WorkloadID == "apps/api" {
+					t.Error("expected apps/api to be covered by the CiliumNetworkPolicy")
+				}
+			}
+		})
+	}
+}
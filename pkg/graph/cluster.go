@@ -0,0 +1,87 @@
+package graph
+
+// ClusterID prefixes a node or edge ID with the cluster it came from, so graphs from
+// several clusters can be combined without ID collisions.
+func ClusterID(cluster, id string) string {
+	if cluster == "" || id == "" {
+		return id
+	}
+	return cluster + "/" + id
+}
+
+// WithCluster returns a copy of g with every node, edge, and warning ID prefixed by
+// cluster and every node's Cluster set. An empty cluster returns g unchanged.
+func WithCluster(g *NetworkGraph, cluster string) *NetworkGraph {
+	if g == nil || cluster == "" {
+		return g
+	}
+
+	prefixed := &NetworkGraph{
+		Nodes:          make([]Node, 0, len(g.Nodes)),
+		Edges:          make([]Edge, 0, len(g.Edges)),
+		Namespaces:     make([]NamespaceNode, 0, len(g.Namespaces)),
+		WarningDetails: make([]WarningDetail, 0, len(g.WarningDetails)),
+		Truncation:     g.Truncation,
+		Baseline:       g.Baseline,
+		PolicyCounts:   g.PolicyCounts,
+	}
+
+	for _, n := range g.Nodes {
+		n.ID = ClusterID(cluster, n.ID)
+		n.Parent = ClusterID(cluster, n.Parent)
+		n.Cluster = cluster
+		if len(n.Members) > 0 {
+			members := make([]string, len(n.Members))
+			for i, m := range n.Members {
+				members[i] = ClusterID(cluster, m)
+			}
+			n.Members = members
+		}
+		prefixed.Nodes = append(prefixed.Nodes, n)
+	}
+
+	for _, e := range g.Edges {
+		e.ID = ClusterID(cluster, e.ID)
+		e.Source = ClusterID(cluster, e.Source)
+		e.Target = ClusterID(cluster, e.Target)
+		prefixed.Edges = append(prefixed.Edges, e)
+	}
+
+	for _, ns := range g.Namespaces {
+		ns.Cluster = cluster
+		prefixed.Namespaces = append(prefixed.Namespaces, ns)
+	}
+
+	for _, wd := range g.WarningDetails {
+		wd.WorkloadID = ClusterID(cluster, wd.WorkloadID)
+		prefixed.WarningDetails = append(prefixed.WarningDetails, wd)
+	}
+
+	return prefixed
+}
+
+// Combine concatenates graphs into one, summing their policy counts. Graphs from different
+// clusters should be passed through WithCluster first so their IDs don't collide.
+func Combine(graphs ...*NetworkGraph) *NetworkGraph {
+	combined := &NetworkGraph{
+		Nodes:          make([]Node, 0),
+		Edges:          make([]Edge, 0),
+		WarningDetails: make([]WarningDetail, 0),
+	}
+	for _, g := range graphs {
+		if g == nil {
+			continue
+		}
+		combined.Nodes = append(combined.Nodes, g.Nodes...)
+		combined.Edges = append(combined.Edges, g.Edges...)
+		combined.Namespaces = append(combined.Namespaces, g.Namespaces...)
+		combined.WarningDetails = append(combined.WarningDetails, g.WarningDetails...)
+		for policyType, n := range g.PolicyCounts {
+			if combined.PolicyCounts == nil {
+				combined.PolicyCounts = make(map[string]int)
+			}
+			combined.PolicyCounts[policyType] += n
+		}
+	}
+	return combined
+}
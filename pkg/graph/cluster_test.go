@@ -0,0 +1,66 @@
+package graph
+
+import (
+	"testing"
+)
+
+func TestWithClusterCombine(t *testing.T) {
+	// The same workloads and edge IDs exist in both clusters
+	newGraph := func() *NetworkGraph {
+		return &NetworkGraph{
+			Nodes: []Node{
+				{ID: "ns/a", Type: NodeTypeWorkload, Namespace: "ns"},
+				{ID: "ns/b", Type: NodeTypeWorkload, Namespace: "ns"},
+				{ID: "ns/b:TCP/80", Type: NodeTypePort, Parent: "ns/b"},
+			},
+			Edges: []Edge{
+				{ID: "edge-0", Source: "ns/a", Target: "ns/b:TCP/80"},
+			},
+			Namespaces: []NamespaceNode{
+				{Name: "ns", Labels: map[string]string{"team": "core"}},
+			},
+			WarningDetails: []WarningDetail{
+				{WorkloadID: "ns/a", WorkloadName: "a", Namespace: "ns", WarningType: WarningUncovered},
+			},
+		}
+	}
+
+	combined := Combine(WithCluster(newGraph(), "east"), WithCluster(newGraph(), "west"))
+
+	if len(combined.Nodes) != 6 || len(combined.Edges) != 2 || len(combined.WarningDetails) != 2 {
+		t.Fatalf("expected 6 nodes, 2 edges, 2 warnings; got %d, %d, %d",
+			len(combined.Nodes), len(combined.Edges), len(combined.WarningDetails))
+	}
+
+	ids := make(map[string]bool)
+	for _, n := range combined.Nodes {
+		if ids[n.ID] {
+			t.Errorf("duplicate node ID %s", n.ID)
+		}
+		ids[n.ID] = true
+		if n.Cluster == "" {
+			t.Errorf("node %s has no cluster", n.ID)
+		}
+		if n.Type == NodeTypePort && n.Parent != ClusterID(n.Cluster, "ns/b") {
+			t.Errorf("port %s has parent %s", n.ID, n.Parent)
+		}
+	}
+
+	east := combined.Edges[0]
+	if east.ID != "east/edge-0" || east.Source != "east/ns/a" || east.Target != "east/ns/b:TCP/80" {
+		t.Errorf("unexpected east edge %+v", east)
+	}
+	if combined.Edges[1].ID == east.ID {
+		t.Error("edge IDs collide across clusters")
+	}
+	if combined.WarningDetails[1].WorkloadID != "west/ns/a" {
+		t.Errorf("expected west/ns/a warning, got %s", combined.WarningDetails[1].WorkloadID)
+	}
+	if len(combined.Namespaces) != 2 || combined.Namespaces[0].Cluster != "east" || combined.Namespaces[1].Cluster != "west" {
+		t.Errorf("expected ns in east and west, got %+v", combined.Namespaces)
+	}
+
+	if g := newGraph(); WithCluster(g, "") != g {
+		t.Error("expected graph unchanged without a cluster")
+	}
+}
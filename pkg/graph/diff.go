@@ -0,0 +1,140 @@
+package graph
+
+import (
+	"slices"
+	"strings"
+)
+
+// DiffStatus marks how an edge changed relative to a baseline graph.
+type DiffStatus string
+
+const (
+	DiffAdded   DiffStatus = "added"
+	DiffRemoved DiffStatus = "removed"
+)
+
+// DiffSummary counts the edge changes relative to a baseline graph.
+type DiffSummary struct {
+	Added   int `json:"added"`
+	Removed int `json:"removed"`
+}
+
+// EdgeKey returns a stable identity for an edge. Sequential edge IDs are not stable
+// between builds, so edges are compared on source, target, direction, action, and the set
+// of granting policies, whatever order dedupeEdges listed them in.
+func EdgeKey(e Edge) string {
+	direction := e.Direction
+	if direction == "" {
+		direction = DirectionIngress
+	}
+	policies := slices.Clone(e.Policies)
+	if len(policies) == 0 {
+		policies = []string{e.Policy}
+	}
+	slices.Sort(policies)
+	policies = slices.Compact(policies)
+	return e.Source + "|" + e.Target + "|" + direction + "|" + e.Metadata["action"] + "|" + strings.Join(policies, ",")
+}
+
+// MarkDiff returns a copy of current whose edges are marked relative to baseline: edges
+// missing from the baseline are marked DiffAdded, and baseline edges that no longer exist
+// are appended as DiffRemoved ghosts when both of their endpoints are still in the graph.
+func MarkDiff(baseline, current *NetworkGraph) *NetworkGraph {
+	if baseline == nil || current == nil {
+		return current
+	}
+
+	marked := *current
+	marked.Edges = make([]Edge, 0, len(current.Edges))
+	summary := &DiffSummary{}
+
+	baselineKeys := make(map[string]bool, len(baseline.Edges))
+	for _, e := range baseline.Edges {
+		baselineKeys[EdgeKey(e)] = true
+	}
+	currentKeys := make(map[string]bool, len(current.Edges))
+	for _, e := range current.Edges {
+		currentKeys[EdgeKey(e)] = true
+		if !baselineKeys[EdgeKey(e)] {
+			e.Diff = DiffAdded
+			summary.Added++
+		}
+		marked.Edges = append(marked.Edges, e)
+	}
+
+	nodeIDs := make(map[string]bool, len(current.Nodes))
+	for _, n := range current.Nodes {
+		nodeIDs[n.ID] = true
+	}
+	for _, e := range baseline.Edges {
+		if currentKeys[EdgeKey(e)] {
+			continue
+		}
+		summary.Removed++
+		if nodeIDs[e.Source] && nodeIDs[e.Target] {
+			e.ID = "removed-" + e.ID
+			e.Diff = DiffRemoved
+			marked.Edges = append(marked.Edges, e)
+		}
+	}
+
+	marked.Baseline = summary
+	return &marked
+}
+
+// GraphDiff lists what changed between two graphs. Nodes are compared by ID, edges by
+// EdgeKey, and warnings by workload, policy, and type.
+type GraphDiff struct {
+	AddedNodes      []Node          `json:"addedNodes,omitempty"`
+	RemovedNodes    []Node          `json:"removedNodes,omitempty"`
+	AddedEdges      []Edge          `json:"addedEdges,omitempty"`
+	RemovedEdges    []Edge          `json:"removedEdges,omitempty"`
+	AddedWarnings   []WarningDetail `json:"addedWarnings,omitempty"`
+	RemovedWarnings []WarningDetail `json:"removedWarnings,omitempty"`
+}
+
+// Empty reports whether the two graphs had no differences.
+func (d *GraphDiff) Empty() bool {
+	return len(d.AddedNodes) == 0 && len(d.RemovedNodes) == 0 &&
+		len(d.AddedEdges) == 0 && len(d.RemovedEdges) == 0 &&
+		len(d.AddedWarnings) == 0 && len(d.RemovedWarnings) == 0
+}
+
+// Diff compares two graphs. Added items are listed in newGraph's order and removed
+// items in oldGraph's order.
+func Diff(oldGraph, newGraph *NetworkGraph) *GraphDiff {
+	diff := &GraphDiff{}
+
+	diff.AddedNodes, diff.RemovedNodes = diffBy(oldGraph.Nodes, newGraph.Nodes, func(n Node) string { return n.ID })
+	diff.AddedEdges, diff.RemovedEdges = diffBy(oldGraph.Edges, newGraph.Edges, EdgeKey)
+	diff.AddedWarnings, diff.RemovedWarnings = diffBy(oldGraph.WarningDetails, newGraph.WarningDetails, warningKey)
+
+	return diff
+}
+
+// warningKey returns a stable identity for a warning.
+func warningKey(w WarningDetail) string {
+	return w.WorkloadID + "|" + w.Namespace + "|" + w.PolicyName + "|" + string(w.WarningType)
+}
+
+// diffBy returns the items of newItems whose key is missing from oldItems, and the items
+// of oldItems whose key is missing from newItems.
+func diffBy[T any](oldItems, newItems []T, key func(T) string) (added, removed []T) {
+	oldKeys := make(map[string]bool, len(oldItems))
+	for _, item := range oldItems {
+		oldKeys[key(item)] = true
+	}
+	newKeys := make(map[string]bool, len(newItems))
+	for _, item := range newItems {
+		newKeys[key(item)] = true
+		if !oldKeys[key(item)] {
+			added = append(added, item)
+		}
+	}
+	for _, item := range oldItems {
+		if !newKeys[key(item)] {
+			removed = append(removed, item)
+		}
+	}
+	return added, removed
+}
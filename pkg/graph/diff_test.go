@@ -0,0 +1,175 @@
+package graph
+
+import (
+	"testing"
+)
+
+func TestEdgeKey(t *testing.T) {
+	base := Edge{ID: "edge-0", Source: "ns/a", Target: "ns/b:TCP/80", Policy: "ns/p1", Policies: []string{"ns/p1", "ns/p2"}}
+
+	tests := map[string]struct {
+		edge     Edge
+		expected bool // whether the key matches base's
+	}{
+		"different ID": {
+			edge:     Edge{ID: "edge-9", Source: "ns/a", Target: "ns/b:TCP/80", Policy: "ns/p1", Policies: []string{"ns/p1", "ns/p2"}},
+			expected: true,
+		},
+		"explicit ingress direction": {
+			edge:     Edge{Source: "ns/a", Target: "ns/b:TCP/80", Policy: "ns/p1", Policies: []string{"ns/p1", "ns/p2"}, Direction: DirectionIngress},
+			expected: true,
+		},
+		"policies in another order": {
+			edge:     Edge{Source: "ns/a", Target: "ns/b:TCP/80", Policy: "ns/p2", Policies: []string{"ns/p2", "ns/p1"}},
+			expected: true,
+		},
+		"egress direction": {
+			edge:     Edge{Source: "ns/a", Target: "ns/b:TCP/80", Policy: "ns/p1", Policies: []string{"ns/p1", "ns/p2"}, Direction: DirectionEgress},
+			expected: false,
+		},
+		"policy dropped": {
+			edge:     Edge{Source: "ns/a", Target: "ns/b:TCP/80", Policy: "ns/p1", Policies: []string{"ns/p1"}},
+			expected: false,
+		},
+		"deny action": {
+			edge: Edge{
+				Source:   "ns/a",
+				Target:   "ns/b:TCP/80",
+				Policy:   "ns/p1",
+				Policies: []string{"ns/p1", "ns/p2"},
+				Metadata: map[string]string{"action": "DENY"},
+			},
+			expected: false,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if result := EdgeKey(tt.edge) == EdgeKey(base); result != tt.expected {
+				t.Errorf("expected keys to match = %v, got %v (%q vs %q)", tt.expected, result, EdgeKey(tt.edge), EdgeKey(base))
+			}
+		})
+	}
+}
+
+func TestMarkDiff(t *testing.T) {
+	nodes := []Node{
+		{ID: "ns/a", Type: NodeTypeWorkload},
+		{ID: "ns/b", Type: NodeTypeWorkload},
+		{ID: "ns/b:TCP/80", Type: NodeTypePort, Parent: "ns/b"},
+		{ID: "ns/c", Type: NodeTypeWorkload},
+		{ID: "ns/c:TCP/80", Type: NodeTypePort, Parent: "ns/c"},
+	}
+	baseline := &NetworkGraph{
+		Nodes: nodes,
+		Edges: []Edge{
+			{ID: "edge-0", Source: "ns/a", Target: "ns/b:TCP/80", Policy: "ns/p1"},
+			{ID: "edge-1", Source: "ns/a", Target: "ns/c:TCP/80", Policy: "ns/p2"},
+			{ID: "edge-2", Source: "ns/gone", Target: "ns/c:TCP/80", Policy: "ns/p3"},
+		},
+	}
+	current := &NetworkGraph{
+		Nodes: nodes,
+		Edges: []Edge{
+			// Same connection under a different sequential ID is unchanged
+			{ID: "edge-5", Source: "ns/a", Target: "ns/b:TCP/80", Policy: "ns/p1"},
+			{ID: "edge-6", Source: "ns/b", Target: "ns/c:TCP/80", Policy: "ns/p4"},
+		},
+	}
+
+	marked := MarkDiff(baseline, current)
+
+	expected := map[string]DiffStatus{
+		"edge-5":         "",
+		"edge-6":         DiffAdded,
+		"removed-edge-1": DiffRemoved,
+	}
+	if len(marked.Edges) != len(expected) {
+		t.Fatalf("expected %d edges, got %d: %+v", len(expected), len(marked.Edges), marked.Edges)
+	}
+	for _, e := range marked.Edges {
+		status, ok := expected[e.ID]
+		if !ok {
+			t.Errorf("unexpected edge %s", e.ID)
+			continue
+		}
+		if e.Diff != status {
+			t.Errorf("edge %s: expected diff %q, got %q", e.ID, status, e.Diff)
+		}
+	}
+
+	// Removed edges whose endpoints disappeared still count in the summary
+	if marked.Baseline == nil || marked.Baseline.Added != 1 || marked.Baseline.Removed != 2 {
+		t.Errorf("expected summary {1 2}, got %+v", marked.Baseline)
+	}
+
+	// The current graph must not be mutated
+	if current.Edges[1].Diff != "" || current.Baseline != nil {
+		t.Error("MarkDiff mutated the current graph")
+	}
+
+	if MarkDiff(nil, current) != current {
+		t.Error("expected current graph unchanged without a baseline")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	oldGraph := &NetworkGraph{
+		Nodes: []Node{
+			{ID: "ns/a", Type: NodeTypeWorkload},
+			{ID: "ns/b", Type: NodeTypeWorkload},
+			{ID: "ns/b:TCP/80", Type: NodeTypePort, Parent: "ns/b"},
+		},
+		Edges: []Edge{
+			{ID: "edge-0", Source: "ns/a", Target: "ns/b:TCP/80", Policy: "ns/p1"},
+			{ID: "edge-1", Source: "ns/b", Target: "ns/b:TCP/80", Policy: "ns/p2"},
+		},
+		WarningDetails: []WarningDetail{
+			{WorkloadID: "ns/b", Namespace: "ns", PolicyName: "ns/p1", WarningType: WarningNoPorts},
+		},
+	}
+	newGraph := &NetworkGraph{
+		Nodes: []Node{
+			{ID: "ns/a", Type: NodeTypeWorkload},
+			{ID: "ns/b", Type: NodeTypeWorkload},
+			{ID: "ns/b:TCP/80", Type: NodeTypePort, Parent: "ns/b"},
+			{ID: "ns/c", Type: NodeTypeWorkload},
+		},
+		Edges: []Edge{
+			// Same connection under a different sequential ID is unchanged
+			{ID: "edge-3", Source: "ns/a", Target: "ns/b:TCP/80", Policy: "ns/p1"},
+			{ID: "edge-4", Source: "ns/c", Target: "ns/b:TCP/80", Policy: "ns/p3"},
+		},
+		WarningDetails: []WarningDetail{
+			{WorkloadID: "ns/c", Namespace: "ns", WarningType: WarningUncovered},
+		},
+	}
+
+	diff := Diff(oldGraph, newGraph)
+
+	if len(diff.AddedNodes) != 1 || diff.AddedNodes[0].ID != "ns/c" {
+		t.Errorf("expected added node ns/c, got %+v", diff.AddedNodes)
+	}
+	if len(diff.RemovedNodes) != 0 {
+		t.Errorf("expected no removed nodes, got %+v", diff.RemovedNodes)
+	}
+	if len(diff.AddedEdges) != 1 || diff.AddedEdges[0].ID != "edge-4" {
+		t.Errorf("expected added edge edge-4, got %+v", diff.AddedEdges)
+	}
+	if len(diff.RemovedEdges) != 1 || diff.RemovedEdges[0].ID != "edge-1" {
+		t.Errorf("expected removed edge edge-1, got %+v", diff.RemovedEdges)
+	}
+	if len(diff.AddedWarnings) != 1 || diff.AddedWarnings[0].WarningType != WarningUncovered {
+		t.Errorf("expected added uncovered warning, got %+v", diff.AddedWarnings)
+	}
+	if len(diff.RemovedWarnings) != 1 || diff.RemovedWarnings[0].WarningType != WarningNoPorts {
+		t.Errorf("expected removed no-ports warning, got %+v", diff.RemovedWarnings)
+	}
+	if diff.Empty() {
+		t.Error("expected a non-empty diff")
+	}
+
+	if !Diff(newGraph, newGraph).Empty() {
+		t.Error("expected an empty diff for identical graphs")
+	}
+}
@@ -0,0 +1,141 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ddl-r-abdulaziz/dnmap/pkg/k8s"
+	"sigs.k8s.io/yaml"
+)
+
+// gatewayAPIGroup is the API group of Gateway API resources.
+const gatewayAPIGroup = "gateway.networking.k8s.io"
+
+// GatewayID generates a unique ID for a Gateway node, distinct from any workload ID.
+func GatewayID(namespace, name string) string {
+	return "gateway:" + namespace + "/" + name
+}
+
+// NewGatewayNode creates a node for a Gateway that HTTPRoutes attach to.
+func NewGatewayNode(namespace, name string) Node {
+	return Node{
+		ID:        GatewayID(namespace, name),
+		Label:     name,
+		Type:      NodeTypeGateway,
+		Namespace: namespace,
+		Kind:      "Gateway",
+	}
+}
+
+// processHTTPRoute returns a node for each Gateway the route attaches to and edges from
+// those Gateways to the container ports its Service backends forward to. Backends are
+// resolved through the Services given to WithServices; other backend kinds are skipped.
+func (b *Builder) processHTTPRoute(route *k8s.HTTPRoute, workloadsByNS map[string][]k8s.Workload) ([]Node, []Edge) {
+	var nodes []Node
+	var edges []Edge
+
+	if route == nil {
+		return nodes, edges
+	}
+
+	for _, parent := range route.Spec.ParentRefs {
+		if (parent.Group != "" && parent.Group != gatewayAPIGroup) || (parent.Kind != "" && parent.Kind != "Gateway") {
+			continue
+		}
+		gatewayNS := parent.Namespace
+		if gatewayNS == "" {
+			gatewayNS = route.Namespace
+		}
+		nodes = append(nodes, NewGatewayNode(gatewayNS, parent.Name))
+	}
+	if len(nodes) == 0 {
+		return nodes, edges
+	}
+
+	routeName := route.Namespace + "/" + route.Name
+
+	// Generate route YAML once (elide managedFields)
+	routeYAML := ""
+	routeCopy := *route
+	routeCopy.ManagedFields = nil
+	if yamlBytes, err := yaml.Marshal(routeCopy); err == nil {
+		routeYAML = string(yamlBytes)
+	}
+
+	for ruleIdx, rule := range route.Spec.Rules {
+		for _, ref := range rule.BackendRefs {
+			if ref.Group != "" || (ref.Kind != "" && ref.Kind != "Service") {
+				continue
+			}
+			backendNS := ref.Namespace
+			if backendNS == "" {
+				backendNS = route.Namespace
+			}
+
+			for _, target := range b.serviceBackends(backendNS, ref.Name, ref.Port, workloadsByNS) {
+				targetWID := WorkloadID(target.workload.Namespace, target.workload.Name)
+				for _, gateway := range nodes {
+					for _, port := range target.ports {
+						portID := PortID(targetWID, port.ContainerPort, string(port.Protocol))
+						edges = append(edges, Edge{
+							ID:         EdgeID(gateway.ID, portID, DirectionIngress, routeName, ruleIdx),
+							Source:     gateway.ID,
+							Target:     portID,
+							Label:      istioPortLabel(port),
+							Rule:       formatHTTPRouteRule(route, ref, ruleIdx),
+							Policy:     routeName,
+							PolicyYAML: routeYAML,
+							Direction:  DirectionIngress,
+							Metadata: map[string]string{
+								"policyType": "HTTPRoute",
+								"ruleType":   "ingress",
+							},
+						})
+					}
+				}
+			}
+		}
+	}
+
+	return nodes, edges
+}
+
+// serviceBackend is a workload selected by a route's backend Service, with the container
+// ports the backend port targets.
+type serviceBackend struct {
+	workload k8s.Workload
+	ports    []k8s.Port
+}
+
+// serviceBackends resolves a Service backend to the workloads it selects and the container
+// ports its port targets; a zero port means every port of the Service.
+func (b *Builder) serviceBackends(namespace, name string, port int32, workloadsByNS map[string][]k8s.Workload) []serviceBackend {
+	var result []serviceBackend
+	for _, svc := range b.services[namespace] {
+		if svc.Name != name || len(svc.Selector) == 0 {
+			continue
+		}
+		for _, w := range workloadsByNS[namespace] {
+			if !b.labelsMatch(w.Labels, svc.Selector) {
+				continue
+			}
+			if ports := servicePortTargets(svc, w, port); len(ports) > 0 {
+				result = append(result, serviceBackend{workload: w, ports: ports})
+			}
+		}
+	}
+	return result
+}
+
+// formatHTTPRouteRule creates a human-readable description of an HTTPRoute backend.
+func formatHTTPRouteRule(route *k8s.HTTPRoute, ref k8s.BackendRef, idx int) string {
+	hosts := "all"
+	if len(route.Spec.Hostnames) > 0 {
+		hosts = strings.Join(route.Spec.Hostnames, ", ")
+	}
+	backend := ref.Name
+	if ref.Port != 0 {
+		backend = fmt.Sprintf("%s:%d", ref.Name, ref.Port)
+	}
+	return fmt.Sprintf("HTTPRoute Rule %d: hosts: %s; backend: %s", idx+1, hosts, backend)
+}
@@ -0,0 +1,141 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/ddl-r-abdulaziz/dnmap/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestBuilderHTTPRoute(t *testing.T) {
+	workloads := []k8s.Workload{
+		{
+			Name:      "web",
+			Namespace: "apps",
+			Labels:    map[string]string{"app": "web"},
+			Ports: []k8s.Port{
+				{Name: "http", ContainerPort: 8080, Protocol: corev1.ProtocolTCP},
+				{Name: "metrics", ContainerPort: 9090, Protocol: corev1.ProtocolTCP},
+			},
+		},
+		{
+			Name:      "api",
+			Namespace: "apps",
+			Labels:    map[string]string{"app": "api"},
+			Ports:     []k8s.Port{{Name: "http", ContainerPort: 3000, Protocol: corev1.ProtocolTCP}},
+		},
+	}
+	services := []k8s.ServiceInfo{
+		{
+			Name:      "web",
+			Namespace: "apps",
+			Selector:  map[string]string{"app": "web"},
+			Ports: []k8s.ServicePortInfo{
+				{Name: "http", Port: 80, TargetPort: intstr.FromString("http")},
+				{Name: "metrics", Port: 9090},
+			},
+		},
+		{
+			Name:      "api",
+			Namespace: "apps",
+			Selector:  map[string]string{"app": "api"},
+			Ports:     []k8s.ServicePortInfo{{Port: 80, TargetPort: intstr.FromInt32(3000)}},
+		},
+	}
+
+	tests := map[string]struct {
+		spec            k8s.HTTPRouteSpec
+		expectedGateway string
+		expectedTargets []string
+	}{
+		"service port resolved to container port": {
+			spec: k8s.HTTPRouteSpec{
+				ParentRefs: []k8s.ParentReference{{Name: "public", Namespace: "gateways"}},
+				Rules:      []k8s.HTTPRouteRule{{BackendRefs: []k8s.BackendRef{{Name: "web", Port: 80}}}},
+			},
+			expectedGateway: "gateway:gateways/public",
+			expectedTargets: []string{"apps/web:TCP/8080"},
+		},
+		"several rules and backends": {
+			spec: k8s.HTTPRouteSpec{
+				ParentRefs: []k8s.ParentReference{{Name: "internal"}},
+				Rules: []k8s.HTTPRouteRule{
+					{BackendRefs: []k8s.BackendRef{{Name: "web", Port: 80}}},
+					{BackendRefs: []k8s.BackendRef{{Name: "api", Port: 80}}},
+				},
+			},
+			expectedGateway: "gateway:apps/internal",
+			expectedTargets: []string{"apps/web:TCP/8080", "apps/api:TCP/3000"},
+		},
+		"unknown service": {
+			spec: k8s.HTTPRouteSpec{
+				ParentRefs: []k8s.ParentReference{{Name: "public"}},
+				Rules:      []k8s.HTTPRouteRule{{BackendRefs: []k8s.BackendRef{{Name: "missing", Port: 80}}}},
+			},
+			expectedGateway: "gateway:apps/public",
+			expectedTargets: nil,
+		},
+		"non-Service backend skipped": {
+			spec: k8s.HTTPRouteSpec{
+				ParentRefs: []k8s.ParentReference{{Name: "public"}},
+				Rules:      []k8s.HTTPRouteRule{{BackendRefs: []k8s.BackendRef{{Group: "example.com", Kind: "Bucket", Name: "web"}}}},
+			},
+			expectedGateway: "gateway:apps/public",
+			expectedTargets: nil,
+		},
+		"non-Gateway parent skipped": {
+			spec: k8s.HTTPRouteSpec{
+				ParentRefs: []k8s.ParentReference{{Kind: "Service", Group: "", Name: "mesh"}},
+				Rules:      []k8s.HTTPRouteRule{{BackendRefs: []k8s.BackendRef{{Name: "web", Port: 80}}}},
+			},
+			expectedTargets: nil,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			policy := k8s.Policy{
+				Name:      "route",
+				Namespace: "apps",
+				Type:      k8s.PolicyTypeHTTPRoute,
+				HTTPRoute: &k8s.HTTPRoute{
+					ObjectMeta: metav1.ObjectMeta{Name: "route", Namespace: "apps"},
+					Spec:       tt.spec,
+				},
+			}
+
+			graph := NewBuilder().WithServices(services).Build(workloads, []k8s.Policy{policy})
+
+			var gateways []Node
+			for _, n := range graph.Nodes {
+				if n.Type == NodeTypeGateway {
+					gateways = append(gateways, n)
+				}
+			}
+			if tt.expectedGateway == "" {
+				if len(gateways) != 0 {
+					t.Errorf("expected no gateway nodes, got %+v", gateways)
+				}
+			} else if len(gateways) != 1 || gateways[0].ID != tt.expectedGateway || gateways[0].Kind != "Gateway" {
+				t.Errorf("expected gateway node %s, got %+v", tt.expectedGateway, gateways)
+			}
+
+			if len(graph.Edges) != len(tt.expectedTargets) {
+				t.Fatalf("expected %d edges, got %d: %+v", len(tt.expectedTargets), len(graph.Edges), graph.Edges)
+			}
+			for i, e := range graph.Edges {
+				if e.Source != tt.expectedGateway {
+					t.Errorf("expected edge from %s, got %s", tt.expectedGateway, e.Source)
+				}
+				if e.Target != tt.expectedTargets[i] {
+					t.Errorf("expected edge[%d] to %s, got %s", i, tt.expectedTargets[i], e.Target)
+				}
+				if e.Policy != "apps/route" || e.Metadata["policyType"] != "HTTPRoute" {
+					t.Errorf("expected edge granted by HTTPRoute apps/route, got %s (%v)", e.Policy, e.Metadata)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package graph
+
+import (
+	"maps"
+	"sort"
+)
+
+// MergedWorkloadID generates a unique ID for a meta-node that groups workloads sharing a label value.
+func MergedWorkloadID(namespace, labelKey, labelValue string) string {
+	return namespace + "/" + labelKey + "=" + labelValue
+}
+
+// MergeByLabel collapses workloads in the same namespace that share the same value for
+// labelKey into a single meta-node. The meta-node lists the merged workload IDs in Members,
+// their ports are combined, and edges that become identical after merging are collapsed.
+// Workloads without the label, or whose label value is unique in the namespace, are left as is.
+// The merged workloads, their ports and their original edges are kept in MergedNodes and
+// MergedEdges, so the HTML map can expand a meta-node back into its members.
+func MergeByLabel(g *NetworkGraph, labelKey string) *NetworkGraph {
+	if g == nil || labelKey == "" {
+		return g
+	}
+
+	// Group workload nodes by namespace and label value
+	groups := make(map[string][]Node) // meta ID -> member nodes
+	for _, n := range g.Nodes {
+		if n.Type != NodeTypeWorkload {
+			continue
+		}
+		value, ok := n.Metadata[labelKey]
+		if !ok || value == "" {
+			continue
+		}
+		metaID := ClusterID(n.Cluster, MergedWorkloadID(n.Namespace, labelKey, value))
+		groups[metaID] = append(groups[metaID], n)
+	}
+
+	// Only groups with more than one member are merged
+	remap := make(map[string]string) // original workload ID -> meta ID
+	for metaID, members := range groups {
+		if len(members) < 2 {
+			delete(groups, metaID)
+			continue
+		}
+		for _, m := range members {
+			remap[m.ID] = metaID
+		}
+	}
+	if len(remap) == 0 {
+		return g
+	}
+
+	merged := &NetworkGraph{
+		Nodes:          make([]Node, 0, len(g.Nodes)),
+		Edges:          make([]Edge, 0, len(g.Edges)),
+		Namespaces:     g.Namespaces,
+		WarningDetails: g.WarningDetails,
+		Truncation:     g.Truncation,
+		PolicyCounts:   g.PolicyCounts,
+	}
+
+	// Add nodes, replacing each merged group with its meta-node at the position of its first member
+	portRemap := make(map[string]string) // original port ID -> merged port ID
+	added := make(map[string]bool)
+	for _, n := range g.Nodes {
+		switch n.Type {
+		case NodeTypeWorkload:
+			metaID, ok := remap[n.ID]
+			if !ok {
+				merged.Nodes = append(merged.Nodes, n)
+				continue
+			}
+			member := n
+			member.Group = metaID
+			merged.MergedNodes = append(merged.MergedNodes, member)
+			if added[metaID] {
+				continue
+			}
+			added[metaID] = true
+			merged.Nodes = append(merged.Nodes, newMergedNode(metaID, labelKey, groups[metaID]))
+		case NodeTypePort:
+			metaID, ok := remap[n.Parent]
+			if !ok {
+				merged.Nodes = append(merged.Nodes, n)
+				continue
+			}
+			merged.MergedNodes = append(merged.MergedNodes, n)
+			port := n
+			port.ID = PortID(metaID, n.Port, n.Protocol)
+			port.Parent = metaID
+			portRemap[n.ID] = port.ID
+			if added[port.ID] {
+				continue
+			}
+			added[port.ID] = true
+			merged.Nodes = append(merged.Nodes, port)
+		default:
+			merged.Nodes = append(merged.Nodes, n)
+		}
+	}
+
+	portParent := make(map[string]string) // port ID -> parent workload ID
+	for _, n := range merged.Nodes {
+		if n.Type == NodeTypePort {
+			portParent[n.ID] = n.Parent
+		}
+	}
+
+	// Rewrite edges against the merged IDs, dropping self-references and merging duplicates
+	for _, e := range g.Edges {
+		_, fromMember := remap[e.Source]
+		_, toMember := remap[e.Target]
+		_, toMemberPort := portRemap[e.Target]
+		if fromMember || toMember || toMemberPort {
+			original := e
+			original.Metadata = maps.Clone(e.Metadata) // dedupeEdges may update the merged edge's
+			merged.MergedEdges = append(merged.MergedEdges, original)
+		}
+
+		if metaID, ok := remap[e.Source]; ok {
+			e.Source = metaID
+		}
+		if portID, ok := portRemap[e.Target]; ok {
+			e.Target = portID
+		} else if metaID, ok := remap[e.Target]; ok {
+			e.Target = metaID
+		}
+		// Members of the same group talking to each other become a self-reference
+		if portParent[e.Target] == e.Source || e.Target == e.Source {
+			continue
+		}
+		merged.Edges = append(merged.Edges, e)
+	}
+	merged.Edges = dedupeEdges(merged.Edges)
+
+	return merged
+}
+
+// newMergedNode creates the meta-node representing a group of merged workloads.
+func newMergedNode(metaID, labelKey string, members []Node) Node {
+	first := members[0]
+	memberIDs := make([]string, 0, len(members))
+	warnSet := make(map[WarningType]bool)
+	for _, m := range members {
+		memberIDs = append(memberIDs, m.ID)
+		for _, w := range m.Warnings {
+			warnSet[w] = true
+		}
+	}
+	sort.Strings(memberIDs)
+
+	var warnings []WarningType
+	for w := range warnSet {
+		warnings = append(warnings, w)
+	}
+	sort.Slice(warnings, func(i, j int) bool { return warnings[i] < warnings[j] })
+
+	return Node{
+		ID:        metaID,
+		Label:     first.Metadata[labelKey],
+		Type:      NodeTypeWorkload,
+		Namespace: first.Namespace,
+		Cluster:   first.Cluster,
+		Kind:      first.Kind,
+		Warnings:  warnings,
+		Members:   memberIDs,
+		Metadata:  map[string]string{labelKey: first.Metadata[labelKey]},
+	}
+}
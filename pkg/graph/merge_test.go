@@ -0,0 +1,103 @@
+package graph
+
+import (
+	"testing"
+)
+
+func TestMergeByLabel(t *testing.T) {
+	const key = "app.kubernetes.io/name"
+
+	base := func() *NetworkGraph {
+		return &NetworkGraph{
+			Nodes: []Node{
+				{ID: "ns/client", Label: "client", Type: NodeTypeWorkload, Namespace: "ns", Kind: "Deployment"},
+				{ID: "ns/api-a", Label: "api-a", Type: NodeTypeWorkload, Namespace: "ns", Kind: "Deployment", Metadata: map[string]string{key: "api"}, Warnings: []WarningType{WarningNoPorts}},
+				{ID: "ns/api-a:TCP/8080", Type: NodeTypePort, Parent: "ns/api-a", Port: 8080, Protocol: "TCP"},
+				{ID: "ns/api-b", Label: "api-b", Type: NodeTypeWorkload, Namespace: "ns", Kind: "Deployment", Metadata: map[string]string{key: "api"}},
+				{ID: "ns/api-b:TCP/8080", Type: NodeTypePort, Parent: "ns/api-b", Port: 8080, Protocol: "TCP"},
+				{ID: "ns/db", Label: "db", Type: NodeTypeWorkload, Namespace: "ns", Kind: "StatefulSet", Metadata: map[string]string{key: "db"}},
+			},
+			Edges: []Edge{
+				{ID: "edge-0", Source: "ns/client", Target: "ns/api-a:TCP/8080", Policy: "ns/allow", Rule: "r1"},
+				{ID: "edge-1", Source: "ns/client", Target: "ns/api-b:TCP/8080", Policy: "ns/allow", Rule: "r1"},
+				{ID: "edge-2", Source: "ns/api-a", Target: "ns/api-b:TCP/8080", Policy: "ns/allow", Rule: "r1"},
+			},
+		}
+	}
+
+	tests := map[string]struct {
+		labelKey            string
+		expectedNodes       int
+		expectedEdges       int
+		expectedMembers     int
+		expectedMergedNodes int
+		expectedMergedEdges int
+	}{
+		"merges workloads sharing a label value": {
+			labelKey:            key,
+			expectedNodes:       4, // client, api meta-node, merged port, db
+			expectedEdges:       1, // client -> api:8080 (duplicate and self edges collapsed)
+			expectedMembers:     2,
+			expectedMergedNodes: 4, // api-a, api-b and their ports
+			expectedMergedEdges: 3,
+		},
+		"unknown label leaves graph unchanged": {
+			labelKey:      "missing",
+			expectedNodes: 6,
+			expectedEdges: 3,
+		},
+		"empty label key leaves graph unchanged": {
+			labelKey:      "",
+			expectedNodes: 6,
+			expectedEdges: 3,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := MergeByLabel(base(), tt.labelKey)
+
+			if len(g.Nodes) != tt.expectedNodes {
+				t.Errorf("expected %d nodes, got %d", tt.expectedNodes, len(g.Nodes))
+			}
+			if len(g.Edges) != tt.expectedEdges {
+				t.Errorf("expected %d edges, got %d", tt.expectedEdges, len(g.Edges))
+			}
+			if len(g.MergedNodes) != tt.expectedMergedNodes || len(g.MergedEdges) != tt.expectedMergedEdges {
+				t.Errorf("expected %d merged nodes and %d merged edges, got %d and %d",
+					tt.expectedMergedNodes, tt.expectedMergedEdges, len(g.MergedNodes), len(g.MergedEdges))
+			}
+			if tt.expectedMembers == 0 {
+				return
+			}
+
+			metaID := MergedWorkloadID("ns", tt.labelKey, "api")
+			var meta *Node
+			for i := range g.Nodes {
+				if g.Nodes[i].ID == metaID {
+					meta = &g.Nodes[i]
+				}
+			}
+			if meta == nil {
+				t.Fatalf("expected meta-node %q", metaID)
+			}
+			if len(meta.Members) != tt.expectedMembers {
+				t.Errorf("expected %d members, got %d", tt.expectedMembers, len(meta.Members))
+			}
+			if len(meta.Warnings) != 1 || meta.Warnings[0] != WarningNoPorts {
+				t.Errorf("expected member warnings to carry over, got %v", meta.Warnings)
+			}
+			if g.Edges[0].Target != PortID(metaID, 8080, "TCP") {
+				t.Errorf("expected edge to target merged port, got %q", g.Edges[0].Target)
+			}
+			for _, n := range g.MergedNodes {
+				if n.Type == NodeTypeWorkload && n.Group != metaID {
+					t.Errorf("expected member %q to record group %q, got %q", n.ID, metaID, n.Group)
+				}
+			}
+			if g.MergedEdges[0].Target != "ns/api-a:TCP/8080" {
+				t.Errorf("expected merged edges to keep their original target, got %q", g.MergedEdges[0].Target)
+			}
+		})
+	}
+}
@@ -1,7 +1,13 @@
 // Package graph provides data structures and logic for building network graphs.
 package graph
 
-import "github.com/ddl-r-abdulaziz/dnmap/pkg/k8s"
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+
+	"github.com/ddl-r-abdulaziz/dnmap/pkg/k8s"
+)
 
 // NodeType represents the type of a graph node.
 type NodeType string
@@ -9,8 +15,16 @@ type NodeType string
 const (
 	NodeTypeWorkload NodeType = "workload"
 	NodeTypePort     NodeType = "port"
+	// NodeTypeGateway is a Gateway API Gateway; its edges come from the HTTPRoutes attached to it
+	NodeTypeGateway NodeType = "gateway"
+	// NodeTypeAny stands for every possible source, in or outside the cluster; ingress rules
+	// with no from peers get one edge from it instead of one from each workload
+	NodeTypeAny NodeType = "any"
 )
 
+// AnyNodeID is the ID of the NodeTypeAny node.
+const AnyNodeID = "any"
+
 // WarningType represents the type of policy warning.
 type WarningType string
 
@@ -19,24 +33,104 @@ const (
 	WarningNoPorts WarningType = "no-ports"
 	// WarningNoSelector indicates a rule that allows from all sources (no pod/namespace selector)
 	WarningNoSelector WarningType = "no-selector"
+	// WarningDefaultDeny indicates a namespace whose NetworkPolicy denies all ingress by default
+	// (empty podSelector, no ingress rules). Reported per namespace with no workload.
+	WarningDefaultDeny WarningType = "default-deny"
+	// WarningUncovered indicates a workload that no NetworkPolicy or AuthorizationPolicy selects
+	WarningUncovered WarningType = "uncovered"
+	// WarningCiliumL7 indicates a CiliumNetworkPolicy rule with L7 (HTTP, Kafka, DNS) or FQDN
+	// conditions, which the map only shows by port
+	WarningCiliumL7 WarningType = "cilium-l7"
+	// WarningPolicyConflict indicates a workload where a DENY AuthorizationPolicy overrides an
+	// ALLOW one for the same source and port
+	WarningPolicyConflict WarningType = "policy-conflict"
+	// WarningBroadCIDR indicates a NetworkPolicy ipBlock peer whose prefix is so short that it
+	// admits most of the address space, e.g. 0.0.0.0/0
+	WarningBroadCIDR WarningType = "broad-cidr"
+	// WarningHostNetwork indicates a workload whose pods use the node's network namespace,
+	// where many CNIs don't enforce NetworkPolicy
+	WarningHostNetwork WarningType = "host-network"
 )
 
+// WarningTypes lists every warning type the builder reports. Add new types here so they
+// are covered by the description test.
+var WarningTypes = []WarningType{
+	WarningNoPorts,
+	WarningNoSelector,
+	WarningDefaultDeny,
+	WarningUncovered,
+	WarningCiliumL7,
+	WarningPolicyConflict,
+	WarningBroadCIDR,
+	WarningHostNetwork,
+}
+
+// String returns the warning type's identifier, e.g. "no-ports".
+func (t WarningType) String() string {
+	return string(t)
+}
+
+// Description returns a one-line explanation of the warning type for reports and exports.
+// Types missing from the switch fall back to their identifier.
+func (t WarningType) Description() string {
+	switch t {
+	case WarningNoPorts:
+		return "Rule allows all ports (no port restriction)"
+	case WarningNoSelector:
+		return "Rule allows from all sources (no selector)"
+	case WarningDefaultDeny:
+		return "Namespace denies all ingress by default (empty podSelector, no ingress rules)"
+	case WarningUncovered:
+		return "No NetworkPolicy or AuthorizationPolicy selects this workload"
+	case WarningCiliumL7:
+		return "CiliumNetworkPolicy L7 or FQDN rules are only shown by port"
+	case WarningPolicyConflict:
+		return "DENY AuthorizationPolicy overrides an ALLOW policy for the same sources"
+	case WarningBroadCIDR:
+		return "Rule allows a very broad ipBlock CIDR"
+	case WarningHostNetwork:
+		return "Pods use the host network, which NetworkPolicy may not restrict"
+	default:
+		return string(t)
+	}
+}
+
 // Node represents a node in the network graph.
 type Node struct {
-	ID          string            `json:"id"`
-	Label       string            `json:"label"`
-	Type        NodeType          `json:"type"`
-	Namespace   string            `json:"namespace"`
-	Kind        string            `json:"kind"`             // For workload nodes: Deployment, StatefulSet, etc.
-	Parent      string            `json:"parent,omitempty"` // For port nodes: the parent workload ID
-	Port        int32             `json:"port,omitempty"`
-	Protocol    string            `json:"protocol,omitempty"`
-	ServiceName string            `json:"serviceName,omitempty"` // For port nodes: the K8s Service name
-	ServicePort int32             `json:"servicePort,omitempty"` // For port nodes: the service port
-	Warnings    []WarningType     `json:"warnings,omitempty"`    // Policy warnings for this node
-	Metadata    map[string]string `json:"metadata,omitempty"`
+	ID             string            `json:"id"`
+	Label          string            `json:"label"`
+	Type           NodeType          `json:"type"`
+	Namespace      string            `json:"namespace"`
+	Kind           string            `json:"kind"`             // For workload nodes: Deployment, StatefulSet, etc.
+	Parent         string            `json:"parent,omitempty"` // For port nodes: the parent workload ID
+	Port           int32             `json:"port,omitempty"`
+	Protocol       string            `json:"protocol,omitempty"`
+	ServiceName    string            `json:"serviceName,omitempty"`    // For port nodes: the K8s Service name
+	ServicePort    int32             `json:"servicePort,omitempty"`    // For port nodes: the service port
+	HostPort       int32             `json:"hostPort,omitempty"`       // For port nodes: the port bound on the node's network
+	Warnings       []WarningType     `json:"warnings,omitempty"`       // Policy warnings for this node
+	Members        []string          `json:"members,omitempty"`        // For merged workload nodes: the IDs of the merged workloads
+	Group          string            `json:"group,omitempty"`          // For workloads in MergedNodes: the meta-node they were merged into
+	Stub           bool              `json:"stub,omitempty"`           // For workload nodes: excluded from the map but referenced by an edge
+	Cluster        string            `json:"cluster,omitempty"`        // Cluster (kube context) the node came from, when scanning several
+	ServiceAccount string            `json:"serviceAccount,omitempty"` // For workload nodes: the service account its pods run as
+	HostNetwork    bool              `json:"hostNetwork,omitempty"`    // For workload nodes: pods share the node's network namespace
+	Metadata       map[string]string `json:"metadata,omitempty"`
+}
+
+// HTTPOperation describes an L7 operation allowed by an Istio rule.
+// An empty Methods or Paths list means any method or path.
+type HTTPOperation struct {
+	Methods []string `json:"methods,omitempty"`
+	Paths   []string `json:"paths,omitempty"`
 }
 
+// Edge directions, relative to the workload whose policy rule produced the edge.
+const (
+	DirectionIngress = "ingress"
+	DirectionEgress  = "egress"
+)
+
 // Edge represents a connection between nodes in the network graph.
 type Edge struct {
 	ID         string            `json:"id"`
@@ -45,10 +139,31 @@ type Edge struct {
 	Label      string            `json:"label"`
 	Rule       string            `json:"rule"`                 // The network policy rule that allows this connection
 	Policy     string            `json:"policy"`               // Name of the network policy
+	Policies   []string          `json:"policies,omitempty"`   // Every policy granting this connection, Policy first
 	PolicyYAML string            `json:"policyYaml,omitempty"` // Full policy YAML
+	Direction  string            `json:"direction"`            // DirectionIngress or DirectionEgress; empty means ingress
+	Operations []HTTPOperation   `json:"operations,omitempty"` // For Istio edges: allowed HTTP methods and paths
+	Diff       DiffStatus        `json:"diff,omitempty"`       // Change relative to a pinned baseline, if any
 	Metadata   map[string]string `json:"metadata,omitempty"`
 }
 
+// MarshalJSON encodes the edge, reporting an unset Direction as DirectionIngress.
+func (e Edge) MarshalJSON() ([]byte, error) {
+	type edgeJSON Edge
+	if e.Direction == "" {
+		e.Direction = DirectionIngress
+	}
+	return json.Marshal(edgeJSON(e))
+}
+
+// NamespaceNode describes a namespace of the graph, so renderers can group or color
+// workloads by namespace labels such as team or environment.
+type NamespaceNode struct {
+	Name    string            `json:"name"`
+	Cluster string            `json:"cluster,omitempty"` // Cluster (kube context) the namespace came from, when scanning several
+	Labels  map[string]string `json:"labels,omitempty"`
+}
+
 // WarningDetail provides detailed information about a policy warning.
 type WarningDetail struct {
 	WorkloadID   string      `json:"workloadId"`
@@ -56,13 +171,27 @@ type WarningDetail struct {
 	Namespace    string      `json:"namespace"`
 	PolicyName   string      `json:"policyName"`
 	WarningType  WarningType `json:"warningType"`
+	Description  string      `json:"description,omitempty"` // What triggered this instance, e.g. the offending CIDR
+}
+
+// Truncation describes how a graph was reduced to fit a size limit.
+type Truncation struct {
+	ShownWorkloads int `json:"shownWorkloads"`
+	TotalWorkloads int `json:"totalWorkloads"`
 }
 
 // NetworkGraph represents the complete network graph.
 type NetworkGraph struct {
 	Nodes          []Node          `json:"nodes"`
 	Edges          []Edge          `json:"edges"`
+	Namespaces     []NamespaceNode `json:"namespaces,omitempty"` // Namespaces of the graph's workloads, with their labels
 	WarningDetails []WarningDetail `json:"warningDetails,omitempty"`
+	Truncation     *Truncation     `json:"truncation,omitempty"`   // Set when the graph was truncated to a maximum size
+	Baseline       *DiffSummary    `json:"baseline,omitempty"`     // Set when edges are marked against a pinned baseline
+	MergedNodes    []Node          `json:"mergedNodes,omitempty"`  // Workloads folded into meta-nodes, with their ports, so viewers can expand them
+	MergedEdges    []Edge          `json:"mergedEdges,omitempty"`  // Edges touching MergedNodes, before they were rewritten to the meta-nodes
+	PolicyCounts   map[string]int  `json:"policyCounts,omitempty"` // Policies the graph was built from, per policy type
+	Context        string          `json:"context,omitempty"`      // Kube context(s) the graph was scanned from, comma-separated; empty for manifests
 }
 
 // WorkloadID generates a unique ID for a workload node.
@@ -75,6 +204,14 @@ func PortID(workloadID string, port int32, protocol string) string {
 	return workloadID + ":" + protocol + "/" + itoa(port)
 }
 
+// EdgeID generates a stable ID for an edge from the nodes it connects, its direction and
+// the policy rule that grants it, so the same cluster state always yields the same IDs.
+func EdgeID(source, target, direction, policy string, ruleIdx int) string {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s|%s|%s|%s|%d", source, target, direction, policy, ruleIdx)
+	return fmt.Sprintf("edge-%016x", h.Sum64())
+}
+
 // itoa converts int32 to string without importing strconv.
 func itoa(n int32) string {
 	if n == 0 {
@@ -94,12 +231,35 @@ func itoa(n int32) string {
 // NewNode creates a workload node.
 func NewWorkloadNode(w k8s.Workload) Node {
 	return Node{
-		ID:        WorkloadID(w.Namespace, w.Name),
-		Label:     w.Name,
-		Type:      NodeTypeWorkload,
-		Namespace: w.Namespace,
-		Kind:      string(w.Type),
-		Metadata:  w.Labels,
+		ID:             WorkloadID(w.Namespace, w.Name),
+		Label:          w.Name,
+		Type:           NodeTypeWorkload,
+		Namespace:      w.Namespace,
+		Kind:           string(w.Type),
+		ServiceAccount: w.ServiceAccount,
+		HostNetwork:    w.HostNetwork,
+		Metadata:       workloadMetadata(w),
+	}
+}
+
+// workloadMetadata copies the workload's labels, so later additions (e.g. mtlsMode) don't
+// write through to the workload. The service account and hostNetwork flag have their own
+// Node fields, so pod labels of the same name can't shadow them.
+func workloadMetadata(w k8s.Workload) map[string]string {
+	metadata := make(map[string]string, len(w.Labels))
+	for k, v := range w.Labels {
+		metadata[k] = v
+	}
+	return metadata
+}
+
+// NewAnyNode creates the node that allow-all ingress edges come from.
+func NewAnyNode() Node {
+	return Node{
+		ID:    AnyNodeID,
+		Label: "ANY",
+		Type:  NodeTypeAny,
+		Kind:  "Any",
 	}
 }
 
@@ -124,5 +284,6 @@ func NewPortNode(workloadID string, p k8s.Port) Node {
 		Protocol:    protocol,
 		ServiceName: p.ServiceName,
 		ServicePort: p.ServicePort,
+		HostPort:    p.HostPort,
 	}
 }
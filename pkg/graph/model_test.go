@@ -1,6 +1,7 @@
 package graph
 
 import (
+	"maps"
 	"testing"
 
 	"github.com/ddl-r-abdulaziz/dnmap/pkg/k8s"
@@ -107,21 +108,24 @@ func TestItoa(t *testing.T) {
 
 func TestNewWorkloadNode(t *testing.T) {
 	tests := map[string]struct {
-		workload     k8s.Workload
-		expectedID   string
-		expectedType NodeType
-		expectedKind string
+		workload               k8s.Workload
+		expectedID             string
+		expectedType           NodeType
+		expectedKind           string
+		expectedServiceAccount string
 	}{
 		"deployment": {
 			workload: k8s.Workload{
-				Name:      "nginx",
-				Namespace: "default",
-				Type:      k8s.WorkloadTypeDeployment,
-				Labels:    map[string]string{"app": "nginx"},
+				Name:           "nginx",
+				Namespace:      "default",
+				Type:           k8s.WorkloadTypeDeployment,
+				Labels:         map[string]string{"app": "nginx", "serviceAccount": "from-label"},
+				ServiceAccount: "nginx",
 			},
-			expectedID:   "default/nginx",
-			expectedType: NodeTypeWorkload,
-			expectedKind: "Deployment",
+			expectedID:             "default/nginx",
+			expectedType:           NodeTypeWorkload,
+			expectedKind:           "Deployment",
+			expectedServiceAccount: "nginx",
 		},
 		"statefulset": {
 			workload: k8s.Workload{
@@ -148,6 +152,16 @@ func TestNewWorkloadNode(t *testing.T) {
 			if node.Kind != tt.expectedKind {
 				t.Errorf("expected Kind %q, got %q", tt.expectedKind, node.Kind)
 			}
+			if node.ServiceAccount != tt.expectedServiceAccount {
+				t.Errorf("expected ServiceAccount %q, got %q", tt.expectedServiceAccount, node.ServiceAccount)
+			}
+			if !maps.Equal(node.Metadata, tt.workload.Labels) {
+				t.Errorf("expected metadata %v to hold exactly the labels, got %v", tt.workload.Labels, node.Metadata)
+			}
+			node.Metadata["mtlsMode"] = "STRICT"
+			if _, ok := tt.workload.Labels["mtlsMode"]; ok {
+				t.Error("metadata writes must not leak into the workload labels")
+			}
 		})
 	}
 }
@@ -196,3 +210,18 @@ func TestNewPortNode(t *testing.T) {
 	}
 }
 
+func TestWarningTypeDescription(t *testing.T) {
+	seen := make(map[string]WarningType)
+	for _, wt := range WarningTypes {
+		t.Run(string(wt), func(t *testing.T) {
+			description := wt.Description()
+			if description == "" || description == string(wt) {
+				t.Errorf("expected a description for %s, got %q", wt, description)
+			}
+			if other, ok := seen[description]; ok {
+				t.Errorf("expected a distinct description, %s shares %q with %s", wt, description, other)
+			}
+			seen[description] = wt
+		})
+	}
+}
@@ -0,0 +1,291 @@
+package graph
+
+import (
+	"slices"
+	"sort"
+
+	securityv1beta1 "istio.io/api/security/v1beta1"
+)
+
+// TransitiveSources returns the IDs of all workloads that have a directed path to the
+// target workload, i.e. every workload that can reach it directly or through other
+// workloads. An edge from the ANY node stands for an edge from every workload, so it
+// makes every workload a source. DENY edges reach nothing, and sources that aren't
+// workloads, such as Gateways, are left out. The target itself is never included, and cycles are
+// handled. The result is sorted for stable output.
+func TransitiveSources(g *NetworkGraph, workloadID string) []string {
+	if g == nil {
+		return nil
+	}
+
+	// Index direct sources per target workload
+	portParent := portParents(g)
+	anyNodes := anyNodeIDs(g)
+	var workloads []string
+	isWorkload := make(map[string]bool)
+	for _, n := range g.Nodes {
+		if n.Type == NodeTypeWorkload {
+			workloads = append(workloads, n.ID)
+			isWorkload[n.ID] = true
+		}
+	}
+	directSources := make(map[string][]string) // workload ID -> workloads with an edge into it
+	for _, e := range g.Edges {
+		if isDeny(e) {
+			continue
+		}
+		target := edgeTargetWorkload(e, portParent)
+		if anyNodes[e.Source] {
+			directSources[target] = append(directSources[target], workloads...)
+			continue
+		}
+		if isWorkload[e.Source] {
+			directSources[target] = append(directSources[target], e.Source)
+		}
+	}
+
+	// Walk upstream breadth-first
+	visited := map[string]bool{workloadID: true}
+	queue := []string{workloadID}
+	var result []string
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, src := range directSources[current] {
+			if visited[src] {
+				continue
+			}
+			visited[src] = true
+			result = append(result, src)
+			queue = append(queue, src)
+		}
+	}
+
+	sort.Strings(result)
+	return result
+}
+
+// Reachable reports whether the source workload may connect to the target workload on
+// port. A port of 0 asks about any port. A DENY AuthorizationPolicy matching the connection
+// refuses it; otherwise an ingress edge must grant it, unless no policy selects the target,
+// in which case Kubernetes allows everything by default.
+func Reachable(g *NetworkGraph, sourceID, targetID string, port int32) bool {
+	if len(ReachingEdges(g, sourceID, targetID, port)) > 0 {
+		return true
+	}
+	return len(DenyingEdges(g, sourceID, targetID, port)) == 0 && !TargetCovered(g, targetID)
+}
+
+// ReachingEdges returns the ingress edges from the source workload to the target
+// workload's port nodes matching port that grant the connection, in graph order; their
+// policies are the ones allowing it. A port of 0 matches every port, and an edge to the
+// target workload itself rather than one of its ports allows all ports. Edges from an ANY
+// node reach from every source. DENY edges never grant access, and an edge whose port a
+// DENY edge refuses is left out.
+func ReachingEdges(g *NetworkGraph, sourceID, targetID string, port int32) []Edge {
+	allows, denies := matchingIngressEdges(g, sourceID, targetID, port)
+	return slices.DeleteFunc(allows, func(e Edge) bool {
+		return slices.ContainsFunc(denies, func(d Edge) bool {
+			return d.Target == targetID || d.Target == e.Target
+		})
+	})
+}
+
+// DenyingEdges returns the DENY ingress edges from the source workload to the target
+// workload's port nodes matching port, in graph order. DENY edges limited to some HTTP
+// operations are left out, since other requests on the port still get through.
+func DenyingEdges(g *NetworkGraph, sourceID, targetID string, port int32) []Edge {
+	_, denies := matchingIngressEdges(g, sourceID, targetID, port)
+	return denies
+}
+
+// TargetCovered reports whether any access policy selects the workload, i.e. whether its
+// node lacks the WarningUncovered warning. Unknown workloads count as covered.
+func TargetCovered(g *NetworkGraph, workloadID string) bool {
+	if g == nil {
+		return true
+	}
+	for _, n := range g.Nodes {
+		if n.ID == workloadID && n.Type == NodeTypeWorkload {
+			return !slices.Contains(n.Warnings, WarningUncovered)
+		}
+	}
+	return true
+}
+
+// matchingIngressEdges returns the ingress edges from the source workload to the target
+// workload's port nodes matching port, split into the ones granting the connection and the
+// unconditional DENY edges refusing it.
+func matchingIngressEdges(g *NetworkGraph, sourceID, targetID string, port int32) (allows, denies []Edge) {
+	if g == nil {
+		return nil, nil
+	}
+
+	targetPorts := make(map[string]int32) // port node ID -> port number
+	anyNodes := anyNodeIDs(g)
+	for _, n := range g.Nodes {
+		if n.Type == NodeTypePort && n.Parent == targetID {
+			targetPorts[n.ID] = n.Port
+		}
+	}
+
+	for _, e := range g.Edges {
+		if e.Source != sourceID && !anyNodes[e.Source] {
+			continue
+		}
+		if e.Direction != "" && e.Direction != DirectionIngress {
+			continue
+		}
+		if e.Target != targetID {
+			if p, ok := targetPorts[e.Target]; !ok || (port != 0 && p != port) {
+				continue
+			}
+		}
+		switch e.Metadata["action"] {
+		case "", securityv1beta1.AuthorizationPolicy_ALLOW.String():
+			allows = append(allows, e)
+		case securityv1beta1.AuthorizationPolicy_DENY.String():
+			if len(e.Operations) == 0 {
+				denies = append(denies, e)
+			}
+		}
+	}
+	return allows, denies
+}
+
+// MaxPaths caps how many paths FindPaths returns, so dense graphs can't explode.
+const MaxPaths = 100
+
+// FindPaths returns the allowed paths from the source workload to the target workload,
+// each a list of workload IDs starting with sourceID and ending with targetID. Paths have
+// at most maxDepth hops, never visit a workload twice, and are returned shortest first
+// (then lexically) up to MaxPaths. Workloads reached from an ANY node are a hop away from
+// every workload. A hop only counts when an edge grants it and no DENY edge refuses it, as
+// for ReachingEdges.
+func FindPaths(g *NetworkGraph, sourceID, targetID string, maxDepth int) [][]string {
+	if g == nil || sourceID == targetID || maxDepth <= 0 {
+		return nil
+	}
+
+	// Index the workloads each workload may reach directly, the same way ReachingEdges
+	// grants a connection: DENY edges never grant a hop, and an edge whose port an
+	// unconditional DENY edge refuses is left out
+	portParent := portParents(g)
+	anyNodes := anyNodeIDs(g)
+	deniedFrom := make(map[string][]string) // port or workload ID -> sources of DENY edges into it
+	for _, e := range g.Edges {
+		if isDeny(e) && len(e.Operations) == 0 && (e.Direction == "" || e.Direction == DirectionIngress) {
+			deniedFrom[e.Target] = append(deniedFrom[e.Target], e.Source)
+		}
+	}
+	refused := func(source string, e Edge) bool {
+		for _, id := range []string{e.Target, edgeTargetWorkload(e, portParent)} {
+			for _, s := range deniedFrom[id] {
+				if s == source || anyNodes[s] {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	seen := make(map[string]map[string]bool)
+	next := make(map[string][]string) // workload ID -> workloads it can reach directly
+	var fromAny []Edge                // edges every workload may take
+	for _, e := range g.Edges {
+		if isDeny(e) {
+			continue
+		}
+		if anyNodes[e.Source] {
+			fromAny = append(fromAny, e)
+			continue
+		}
+		target := edgeTargetWorkload(e, portParent)
+		if e.Source == target || seen[e.Source][target] || refused(e.Source, e) {
+			continue
+		}
+		if seen[e.Source] == nil {
+			seen[e.Source] = make(map[string]bool)
+		}
+		seen[e.Source][target] = true
+		next[e.Source] = append(next[e.Source], target)
+	}
+	if len(fromAny) > 0 {
+		for _, n := range g.Nodes {
+			if n.Type != NodeTypeWorkload {
+				continue
+			}
+			for _, e := range fromAny {
+				if !refused(n.ID, e) {
+					next[n.ID] = append(next[n.ID], edgeTargetWorkload(e, portParent))
+				}
+			}
+		}
+	}
+	for source, targets := range next {
+		sort.Strings(targets)
+		next[source] = slices.Compact(slices.DeleteFunc(targets, func(t string) bool { return t == source }))
+	}
+
+	// Breadth-first over partial paths, so shorter paths are found first
+	var result [][]string
+	queue := [][]string{{sourceID}}
+	for len(queue) > 0 && len(result) < MaxPaths {
+		path := queue[0]
+		queue = queue[1:]
+		if len(path)-1 >= maxDepth {
+			continue
+		}
+		for _, n := range next[path[len(path)-1]] {
+			if slices.Contains(path, n) {
+				continue
+			}
+			extended := append(slices.Clip(path), n)
+			if n == targetID {
+				result = append(result, extended)
+				if len(result) == MaxPaths {
+					break
+				}
+				continue
+			}
+			queue = append(queue, extended)
+		}
+	}
+	return result
+}
+
+// isDeny reports whether the edge comes from a DENY AuthorizationPolicy.
+func isDeny(e Edge) bool {
+	return e.Metadata["action"] == securityv1beta1.AuthorizationPolicy_DENY.String()
+}
+
+// anyNodeIDs returns the IDs of the graph's ANY nodes; combined clusters have one each.
+func anyNodeIDs(g *NetworkGraph) map[string]bool {
+	ids := make(map[string]bool)
+	for _, n := range g.Nodes {
+		if n.Type == NodeTypeAny {
+			ids[n.ID] = true
+		}
+	}
+	return ids
+}
+
+// portParents maps each port node ID to its workload.
+func portParents(g *NetworkGraph) map[string]string {
+	portParent := make(map[string]string)
+	for _, n := range g.Nodes {
+		if n.Type == NodeTypePort {
+			portParent[n.ID] = n.Parent
+		}
+	}
+	return portParent
+}
+
+// edgeTargetWorkload returns the workload an edge leads to: the parent of its target
+// port, or the target itself when it isn't a port.
+func edgeTargetWorkload(e Edge, portParent map[string]string) string {
+	if parent, ok := portParent[e.Target]; ok {
+		return parent
+	}
+	return e.Target
+}
@@ -0,0 +1,457 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestTransitiveSources(t *testing.T) {
+	// a -> b -> c -> d, and d -> b forms a cycle; e is denied access to a, and a gateway
+	// routes to a
+	g := &NetworkGraph{
+		Nodes: []Node{
+			{ID: "ns/a", Type: NodeTypeWorkload},
+			{ID: "ns/b", Type: NodeTypeWorkload},
+			{ID: "ns/b:TCP/80", Type: NodeTypePort, Parent: "ns/b"},
+			{ID: "ns/c", Type: NodeTypeWorkload},
+			{ID: "ns/c:TCP/80", Type: NodeTypePort, Parent: "ns/c"},
+			{ID: "ns/d", Type: NodeTypeWorkload},
+			{ID: "ns/d:TCP/80", Type: NodeTypePort, Parent: "ns/d"},
+			{ID: "ns/e", Type: NodeTypeWorkload},
+			{ID: "ns/gateway", Type: NodeTypeGateway},
+		},
+		Edges: []Edge{
+			{Source: "ns/a", Target: "ns/b:TCP/80"},
+			{Source: "ns/b", Target: "ns/c:TCP/80"},
+			{Source: "ns/c", Target: "ns/d:TCP/80"},
+			{Source: "ns/d", Target: "ns/b:TCP/80"},
+			{Source: "ns/e", Target: "ns/a", Metadata: map[string]string{"action": "DENY"}},
+			{Source: "ns/gateway", Target: "ns/a"},
+		},
+	}
+
+	tests := map[string]struct {
+		target   string
+		expected []string
+	}{
+		"end of chain": {
+			target:   "ns/d",
+			expected: []string{"ns/a", "ns/b", "ns/c"},
+		},
+		"inside cycle": {
+			target:   "ns/b",
+			expected: []string{"ns/a", "ns/c", "ns/d"},
+		},
+		"denied and non-workload sources": {
+			target:   "ns/a",
+			expected: nil,
+		},
+		"unknown workload": {
+			target:   "ns/missing",
+			expected: nil,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			result := TransitiveSources(g, tt.target)
+			if len(result) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, result)
+			}
+			for i, id := range result {
+				if id != tt.expected[i] {
+					t.Errorf("expected source[%d] = %q, got %q", i, tt.expected[i], id)
+				}
+			}
+		})
+	}
+}
+
+func TestTransitiveSourcesFromAny(t *testing.T) {
+	// ANY -> a -> b; c and d are otherwise unrelated
+	g := &NetworkGraph{
+		Nodes: []Node{
+			{ID: "ns/a", Type: NodeTypeWorkload},
+			{ID: "ns/a:TCP/80", Type: NodeTypePort, Parent: "ns/a"},
+			{ID: "ns/b", Type: NodeTypeWorkload},
+			{ID: "ns/b:TCP/80", Type: NodeTypePort, Parent: "ns/b"},
+			{ID: "ns/c", Type: NodeTypeWorkload},
+			{ID: "ns/d", Type: NodeTypeWorkload},
+			{ID: "ns/d:TCP/80", Type: NodeTypePort, Parent: "ns/d"},
+			{ID: AnyNodeID, Type: NodeTypeAny},
+		},
+		Edges: []Edge{
+			{Source: AnyNodeID, Target: "ns/a:TCP/80"},
+			{Source: "ns/a", Target: "ns/b:TCP/80"},
+			{Source: "ns/c", Target: "ns/d:TCP/80"},
+		},
+	}
+
+	tests := map[string]struct {
+		target   string
+		expected []string
+	}{
+		"reached from any": {
+			target:   "ns/a",
+			expected: []string{"ns/b", "ns/c", "ns/d"},
+		},
+		"downstream of any": {
+			target:   "ns/b",
+			expected: []string{"ns/a", "ns/c", "ns/d"},
+		},
+		"not reached from any": {
+			target:   "ns/d",
+			expected: []string{"ns/c"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			result := TransitiveSources(g, tt.target)
+			if strings.Join(result, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestReachable(t *testing.T) {
+	g := &NetworkGraph{
+		Nodes: []Node{
+			{ID: "ns/frontend", Type: NodeTypeWorkload},
+			{ID: "ns/backend", Type: NodeTypeWorkload},
+			{ID: "ns/backend:TCP/8080", Type: NodeTypePort, Parent: "ns/backend", Port: 8080, Protocol: "TCP"},
+			{ID: "ns/backend:TCP/9090", Type: NodeTypePort, Parent: "ns/backend", Port: 9090, Protocol: "TCP"},
+			{ID: "ns/backend:TCP/7070", Type: NodeTypePort, Parent: "ns/backend", Port: 7070, Protocol: "TCP"},
+			{ID: "ns/db", Type: NodeTypeWorkload},
+			{ID: "ns/monitor", Type: NodeTypeWorkload},
+			{ID: "ns/api", Type: NodeTypeWorkload},
+			{ID: "ns/api:TCP/80", Type: NodeTypePort, Parent: "ns/api", Port: 80, Protocol: "TCP"},
+			{ID: "ns/api:TCP/443", Type: NodeTypePort, Parent: "ns/api", Port: 443, Protocol: "TCP"},
+			{ID: "ns/cache", Type: NodeTypeWorkload, Warnings: []WarningType{WarningUncovered}},
+			{ID: "ns/cache:TCP/6379", Type: NodeTypePort, Parent: "ns/cache", Port: 6379, Protocol: "TCP"},
+			{ID: AnyNodeID, Type: NodeTypeAny},
+		},
+		Edges: []Edge{
+			{Source: "ns/frontend", Target: "ns/backend:TCP/8080", Policy: "ns/allow-frontend"},
+			{Source: "ns/monitor", Target: "ns/backend:TCP/8080", Policy: "ns/allow-monitor"},
+			{Source: "ns/monitor", Target: "ns/backend:TCP/9090", Policy: "ns/allow-monitor"},
+			{Source: "ns/monitor", Target: "ns/db", Policy: "ns/allow-monitor-db"},
+			{Source: "ns/frontend", Target: "ns/db", Policy: "ns/frontend-egress", Direction: DirectionEgress},
+			{Source: AnyNodeID, Target: "ns/backend:TCP/7070", Policy: "ns/allow-all"},
+			{Source: "ns/frontend", Target: "ns/api:TCP/80", Policy: "ns/allow-api", Metadata: map[string]string{"action": "ALLOW"}},
+			{Source: "ns/frontend", Target: "ns/api:TCP/443", Policy: "ns/allow-api", Metadata: map[string]string{"action": "ALLOW"}},
+			{Source: "ns/frontend", Target: "ns/api:TCP/80", Policy: "ns/deny-frontend", Metadata: map[string]string{"action": "DENY"}},
+			{Source: "ns/monitor", Target: "ns/api:TCP/80", Policy: "ns/allow-api", Metadata: map[string]string{"action": "ALLOW"}},
+			{
+				Source:     "ns/monitor",
+				Target:     "ns/api:TCP/80",
+				Policy:     "ns/deny-monitor-writes",
+				Operations: []HTTPOperation{{Methods: []string{"POST"}}},
+				Metadata:   map[string]string{"action": "DENY"},
+			},
+			{Source: "ns/monitor", Target: "ns/api", Policy: "ns/deny-monitor", Metadata: map[string]string{"action": "DENY"}},
+		},
+	}
+
+	tests := map[string]struct {
+		source           string
+		target           string
+		port             int32
+		expected         bool
+		expectedPolicies []string
+		expectedDenies   []string
+	}{
+		"allowed port": {
+			source:           "ns/frontend",
+			target:           "ns/backend",
+			port:             8080,
+			expected:         true,
+			expectedPolicies: []string{"ns/allow-frontend"},
+		},
+		"denied port": {
+			source:   "ns/frontend",
+			target:   "ns/backend",
+			port:     9090,
+			expected: false,
+		},
+		"denied workload": {
+			source:   "ns/frontend",
+			target:   "ns/db",
+			port:     5432,
+			expected: false,
+		},
+		"any port": {
+			source:           "ns/monitor",
+			target:           "ns/backend",
+			port:             0,
+			expected:         true,
+			expectedPolicies: []string{"ns/allow-monitor", "ns/allow-monitor", "ns/allow-all"},
+		},
+		"allow-all port from any source": {
+			source:           "ns/frontend",
+			target:           "ns/backend",
+			port:             7070,
+			expected:         true,
+			expectedPolicies: []string{"ns/allow-all"},
+		},
+		"edge to workload allows all ports": {
+			source:           "ns/monitor",
+			target:           "ns/db",
+			port:             5432,
+			expected:         true,
+			expectedPolicies: []string{"ns/allow-monitor-db"},
+		},
+		"unknown source": {
+			source:   "ns/missing",
+			target:   "ns/backend",
+			port:     8080,
+			expected: false,
+		},
+		"deny overrides allow": {
+			source:         "ns/frontend",
+			target:         "ns/api",
+			port:           80,
+			expected:       false,
+			expectedDenies: []string{"ns/deny-frontend"},
+		},
+		"deny on one port leaves the others": {
+			source:           "ns/frontend",
+			target:           "ns/api",
+			port:             0,
+			expected:         true,
+			expectedPolicies: []string{"ns/allow-api"},
+			expectedDenies:   []string{"ns/deny-frontend"},
+		},
+		"deny on the workload covers every port": {
+			source:         "ns/monitor",
+			target:         "ns/api",
+			port:           80,
+			expected:       false,
+			expectedDenies: []string{"ns/deny-monitor"},
+		},
+		"uncovered target allows by default": {
+			source:   "ns/frontend",
+			target:   "ns/cache",
+			port:     6379,
+			expected: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if result := Reachable(g, tt.source, tt.target, tt.port); result != tt.expected {
+				t.Errorf("expected Reachable = %v, got %v", tt.expected, result)
+			}
+
+			edges := ReachingEdges(g, tt.source, tt.target, tt.port)
+			if len(edges) != len(tt.expectedPolicies) {
+				t.Fatalf("expected %d edges, got %d", len(tt.expectedPolicies), len(edges))
+			}
+			for i, e := range edges {
+				if e.Policy != tt.expectedPolicies[i] {
+					t.Errorf("expected edge[%d] policy %q, got %q", i, tt.expectedPolicies[i], e.Policy)
+				}
+			}
+
+			denies := DenyingEdges(g, tt.source, tt.target, tt.port)
+			if len(denies) != len(tt.expectedDenies) {
+				t.Fatalf("expected %d denying edges, got %d", len(tt.expectedDenies), len(denies))
+			}
+			for i, e := range denies {
+				if e.Policy != tt.expectedDenies[i] {
+					t.Errorf("expected denying edge[%d] policy %q, got %q", i, tt.expectedDenies[i], e.Policy)
+				}
+			}
+		})
+	}
+}
+
+func TestFindPaths(t *testing.T) {
+	// a -> b -> d and a -> c -> d, d -> b forms a cycle, d -> e; f is unrelated
+	g := &NetworkGraph{
+		Nodes: []Node{
+			{ID: "ns/a", Type: NodeTypeWorkload},
+			{ID: "ns/b", Type: NodeTypeWorkload},
+			{ID: "ns/b:TCP/80", Type: NodeTypePort, Parent: "ns/b"},
+			{ID: "ns/c", Type: NodeTypeWorkload},
+			{ID: "ns/c:TCP/80", Type: NodeTypePort, Parent: "ns/c"},
+			{ID: "ns/d", Type: NodeTypeWorkload},
+			{ID: "ns/d:TCP/80", Type: NodeTypePort, Parent: "ns/d"},
+			{ID: "ns/d:TCP/81", Type: NodeTypePort, Parent: "ns/d"},
+			{ID: "ns/e", Type: NodeTypeWorkload},
+			{ID: "ns/f", Type: NodeTypeWorkload},
+		},
+		Edges: []Edge{
+			{Source: "ns/a", Target: "ns/c:TCP/80"},
+			{Source: "ns/a", Target: "ns/b:TCP/80"},
+			{Source: "ns/b", Target: "ns/d:TCP/80"},
+			{Source: "ns/b", Target: "ns/d:TCP/81"},
+			{Source: "ns/c", Target: "ns/d:TCP/80"},
+			{Source: "ns/d", Target: "ns/b:TCP/80"},
+			{Source: "ns/d", Target: "ns/e"},
+		},
+	}
+
+	tests := map[string]struct {
+		source   string
+		target   string
+		maxDepth int
+		expected [][]string
+	}{
+		"single hop": {
+			source:   "ns/a",
+			target:   "ns/b",
+			maxDepth: 5,
+			expected: [][]string{{"ns/a", "ns/b"}, {"ns/a", "ns/c", "ns/d", "ns/b"}},
+		},
+		"several paths shortest first": {
+			source:   "ns/a",
+			target:   "ns/e",
+			maxDepth: 5,
+			expected: [][]string{{"ns/a", "ns/b", "ns/d", "ns/e"}, {"ns/a", "ns/c", "ns/d", "ns/e"}},
+		},
+		"depth limit": {
+			source:   "ns/a",
+			target:   "ns/e",
+			maxDepth: 2,
+			expected: nil,
+		},
+		"cycle not revisited": {
+			source:   "ns/d",
+			target:   "ns/e",
+			maxDepth: 5,
+			expected: [][]string{{"ns/d", "ns/e"}},
+		},
+		"unreachable": {
+			source:   "ns/a",
+			target:   "ns/f",
+			maxDepth: 5,
+			expected: nil,
+		},
+		"same workload": {
+			source:   "ns/a",
+			target:   "ns/a",
+			maxDepth: 5,
+			expected: nil,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			result := FindPaths(g, tt.source, tt.target, tt.maxDepth)
+			if len(result) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, result)
+			}
+			for i, path := range result {
+				if strings.Join(path, ",") != strings.Join(tt.expected[i], ",") {
+					t.Errorf("expected path[%d] = %v, got %v", i, tt.expected[i], path)
+				}
+			}
+		})
+	}
+}
+
+func TestFindPathsAnySource(t *testing.T) {
+	// Anything may reach b, which reaches c
+	g := &NetworkGraph{
+		Nodes: []Node{
+			{ID: "ns/a", Type: NodeTypeWorkload},
+			{ID: "ns/b", Type: NodeTypeWorkload},
+			{ID: "ns/b:TCP/80", Type: NodeTypePort, Parent: "ns/b"},
+			{ID: "ns/c", Type: NodeTypeWorkload},
+			{ID: "ns/c:TCP/80", Type: NodeTypePort, Parent: "ns/c"},
+			{ID: AnyNodeID, Type: NodeTypeAny},
+		},
+		Edges: []Edge{
+			{Source: AnyNodeID, Target: "ns/b:TCP/80"},
+			{Source: "ns/b", Target: "ns/c:TCP/80"},
+		},
+	}
+
+	tests := map[string]struct {
+		source   string
+		target   string
+		expected [][]string
+	}{
+		"direct": {
+			source:   "ns/a",
+			target:   "ns/b",
+			expected: [][]string{{"ns/a", "ns/b"}},
+		},
+		"through allow-all workload": {
+			source:   "ns/a",
+			target:   "ns/c",
+			expected: [][]string{{"ns/a", "ns/b", "ns/c"}},
+		},
+		"nothing reaches a": {
+			source:   "ns/c",
+			target:   "ns/a",
+			expected: nil,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			result := FindPaths(g, tt.source, tt.target, 5)
+			if fmt.Sprint(result) != fmt.Sprint(tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestFindPathsDenied(t *testing.T) {
+	// a reaches b only through a DENY edge; b's ALLOW edge to c is refused on the same port,
+	// and c's edge to d is refused for every source
+	g := &NetworkGraph{
+		Nodes: []Node{
+			{ID: "ns/a", Type: NodeTypeWorkload},
+			{ID: "ns/b", Type: NodeTypeWorkload},
+			{ID: "ns/b:TCP/80", Type: NodeTypePort, Parent: "ns/b"},
+			{ID: "ns/c", Type: NodeTypeWorkload},
+			{ID: "ns/c:TCP/80", Type: NodeTypePort, Parent: "ns/c"},
+			{ID: "ns/d", Type: NodeTypeWorkload},
+			{ID: "ns/d:TCP/80", Type: NodeTypePort, Parent: "ns/d"},
+			{ID: AnyNodeID, Type: NodeTypeAny},
+		},
+		Edges: []Edge{
+			{Source: "ns/a", Target: "ns/b:TCP/80", Policy: "ns/deny-a", Metadata: map[string]string{"action": "DENY"}},
+			{Source: "ns/b", Target: "ns/c:TCP/80", Policy: "ns/allow-b", Metadata: map[string]string{"action": "ALLOW"}},
+			{Source: "ns/b", Target: "ns/c:TCP/80", Policy: "ns/deny-b", Metadata: map[string]string{"action": "DENY"}},
+			{Source: "ns/c", Target: "ns/d:TCP/80", Policy: "ns/allow-c"},
+			{Source: AnyNodeID, Target: "ns/d", Policy: "ns/deny-all", Metadata: map[string]string{"action": "DENY"}},
+		},
+	}
+
+	tests := map[string]struct {
+		source string
+		target string
+	}{
+		"only a DENY edge":               {source: "ns/a", target: "ns/b"},
+		"ALLOW refused on the same port": {source: "ns/b", target: "ns/c"},
+		"refused for every source":       {source: "ns/c", target: "ns/d"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if result := FindPaths(g, tt.source, tt.target, 5); len(result) != 0 {
+				t.Errorf("expected no path, got %v", result)
+			}
+		})
+	}
+}
+
+func TestFindPathsCapped(t *testing.T) {
+	// src fans out to more middle workloads than MaxPaths, each reaching dst
+	g := &NetworkGraph{}
+	for i := 0; i < MaxPaths+20; i++ {
+		mid := fmt.Sprintf("ns/mid-%03d", i)
+		g.Edges = append(g.Edges, Edge{Source: "ns/src", Target: mid}, Edge{Source: mid, Target: "ns/dst"})
+	}
+
+	if result := FindPaths(g, "ns/src", "ns/dst", 3); len(result) != MaxPaths {
+		t.Errorf("expected %d paths, got %d", MaxPaths, len(result))
+	}
+}
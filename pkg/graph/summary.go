@@ -0,0 +1,73 @@
+package graph
+
+// Summary counts what a graph contains, for CI logs and quick comparisons.
+type Summary struct {
+	WorkloadsByKind  map[string]int      `json:"workloadsByKind"`  // Workload nodes (excluding stubs) per kind
+	Ports            int                 `json:"ports"`            // Port nodes
+	EdgesByDirection map[string]int      `json:"edgesByDirection"` // Edges per DirectionIngress or DirectionEgress
+	PoliciesByType   map[string]int      `json:"policiesByType"`   // Policies the graph was built from, per policy type
+	WarningsByType   map[WarningType]int `json:"warningsByType"`   // Warning details per warning type
+}
+
+// Summarize counts the workloads, ports, edges, policies and warnings in g. Policies are
+// taken from g.PolicyCounts, so policies that allow nothing are counted too; graphs without
+// it, such as ones read back from older JSON output, fall back to the distinct policies
+// granting at least one edge.
+func Summarize(g *NetworkGraph) Summary {
+	s := Summary{
+		WorkloadsByKind:  make(map[string]int),
+		EdgesByDirection: make(map[string]int),
+		PoliciesByType:   make(map[string]int),
+		WarningsByType:   make(map[WarningType]int),
+	}
+	if g == nil {
+		return s
+	}
+
+	for _, n := range g.Nodes {
+		switch n.Type {
+		case NodeTypeWorkload:
+			if !n.Stub {
+				s.WorkloadsByKind[n.Kind]++
+			}
+		case NodeTypePort:
+			s.Ports++
+		}
+	}
+
+	seenPolicies := make(map[string]bool)
+	for _, e := range g.Edges {
+		direction := e.Direction
+		if direction == "" {
+			direction = DirectionIngress
+		}
+		s.EdgesByDirection[direction]++
+
+		if g.PolicyCounts != nil {
+			continue
+		}
+		policies := e.Policies
+		if len(policies) == 0 {
+			policies = []string{e.Policy}
+		}
+		policyType := e.Metadata["policyType"]
+		for _, p := range policies {
+			// Namespace/name alone may be shared by a NetworkPolicy and an AuthorizationPolicy
+			key := policyType + "|" + p
+			if p == "" || seenPolicies[key] {
+				continue
+			}
+			seenPolicies[key] = true
+			s.PoliciesByType[policyType]++
+		}
+	}
+
+	for policyType, n := range g.PolicyCounts {
+		s.PoliciesByType[policyType] = n
+	}
+
+	for _, w := range g.WarningDetails {
+		s.WarningsByType[w.WarningType]++
+	}
+	return s
+}
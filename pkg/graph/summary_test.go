@@ -0,0 +1,116 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/ddl-r-abdulaziz/dnmap/pkg/k8s"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSummarize(t *testing.T) {
+	g := &NetworkGraph{
+		Nodes: []Node{
+			{ID: "ns/web", Type: NodeTypeWorkload, Kind: "Deployment"},
+			{ID: "ns/api", Type: NodeTypeWorkload, Kind: "Deployment"},
+			{ID: "ns/api:TCP/8080", Type: NodeTypePort, Parent: "ns/api"},
+			{ID: "ns/db", Type: NodeTypeWorkload, Kind: "StatefulSet"},
+			{ID: "ns/db:TCP/5432", Type: NodeTypePort, Parent: "ns/db"},
+			{ID: "other/agent", Type: NodeTypeWorkload, Kind: "DaemonSet", Stub: true},
+		},
+		Edges: []Edge{
+			{Source: "ns/web", Target: "ns/api:TCP/8080", Policy: "ns/allow-web", Metadata: map[string]string{"policyType": "NetworkPolicy"}},
+			{Source: "ns/api", Target: "ns/db:TCP/5432", Policy: "ns/allow-api", Policies: []string{"ns/allow-api", "ns/allow-web"}, Metadata: map[string]string{"policyType": "NetworkPolicy"}},
+			{Source: "ns/api", Target: "ns/db:TCP/5432", Policy: "ns/allow-api", Direction: DirectionEgress, Metadata: map[string]string{"policyType": "NetworkPolicy"}},
+			{Source: "ns/web", Target: "ns/api:TCP/8080", Policy: "ns/allow-web", Metadata: map[string]string{"policyType": "AuthorizationPolicy"}},
+			{Source: "other/agent", Target: "ns/api:TCP/8080", Policy: "ns/allow-agent", Direction: DirectionIngress, Metadata: map[string]string{"policyType": "AuthorizationPolicy"}},
+		},
+		WarningDetails: []WarningDetail{
+			{WorkloadID: "ns/api", WarningType: WarningNoPorts},
+			{WorkloadID: "ns/db", WarningType: WarningNoPorts},
+			{WorkloadID: "ns/web", WarningType: WarningUncovered},
+		},
+	}
+
+	s := Summarize(g)
+
+	tests := map[string]struct {
+		actual   int
+		expected int
+	}{
+		"deployments":            {s.WorkloadsByKind["Deployment"], 2},
+		"statefulsets":           {s.WorkloadsByKind["StatefulSet"], 1},
+		"stub daemonset skipped": {s.WorkloadsByKind["DaemonSet"], 0},
+		"ports":                  {s.Ports, 2},
+		"ingress edges":          {s.EdgesByDirection[DirectionIngress], 4},
+		"egress edges":           {s.EdgesByDirection[DirectionEgress], 1},
+		"network policies":       {s.PoliciesByType["NetworkPolicy"], 2},
+		"authorization policies": {s.PoliciesByType["AuthorizationPolicy"], 2},
+		"no-ports warnings":      {s.WarningsByType[WarningNoPorts], 2},
+		"uncovered warnings":     {s.WarningsByType[WarningUncovered], 1},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if tt.actual != tt.expected {
+				t.Errorf("expected %d, got %d", tt.expected, tt.actual)
+			}
+		})
+	}
+
+	if empty := Summarize(nil); empty.Ports != 0 || len(empty.WorkloadsByKind) != 0 {
+		t.Errorf("expected empty summary for nil graph, got %+v", empty)
+	}
+}
+
+func TestSummarizePolicyCounts(t *testing.T) {
+	workloads := []k8s.Workload{
+		{Name: "web", Namespace: "ns", Labels: map[string]string{"app": "web"}},
+	}
+	denyAll := networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "deny-all", Namespace: "ns"},
+		Spec: networkingv1.NetworkPolicySpec{
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+		},
+	}
+
+	tests := map[string]struct {
+		graph    *NetworkGraph
+		expected map[string]int
+	}{
+		"policies granting nothing are counted": {
+			graph:    NewBuilder().BuildFromNetworkPolicies(workloads, []networkingv1.NetworkPolicy{denyAll}),
+			expected: map[string]int{"NetworkPolicy": 1},
+		},
+		"recorded counts win over edges": {
+			graph: &NetworkGraph{
+				Edges: []Edge{
+					{Source: "ns/web", Target: "ns/api:TCP/8080", Policy: "ns/allow-web", Metadata: map[string]string{"policyType": "NetworkPolicy"}},
+				},
+				PolicyCounts: map[string]int{"NetworkPolicy": 3, "PeerAuthentication": 1},
+			},
+			expected: map[string]int{"NetworkPolicy": 3, "PeerAuthentication": 1},
+		},
+		"combined graphs sum their counts": {
+			graph: Combine(
+				&NetworkGraph{PolicyCounts: map[string]int{"NetworkPolicy": 2}},
+				&NetworkGraph{PolicyCounts: map[string]int{"NetworkPolicy": 1, "HTTPRoute": 1}},
+			),
+			expected: map[string]int{"NetworkPolicy": 3, "HTTPRoute": 1},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := Summarize(tt.graph)
+			if len(s.PoliciesByType) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, s.PoliciesByType)
+			}
+			for policyType, n := range tt.expected {
+				if s.PoliciesByType[policyType] != n {
+					t.Errorf("expected %d %s, got %d", n, policyType, s.PoliciesByType[policyType])
+				}
+			}
+		})
+	}
+}
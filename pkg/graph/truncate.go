@@ -0,0 +1,156 @@
+package graph
+
+import "sort"
+
+// Truncate limits the graph to at most maxWorkloads workload nodes using a deterministic
+// selection. Workloads carrying warnings or reached from the ANY node are kept first; the
+// rest of the budget goes to neighbors of the workloads already kept, so kept workloads keep
+// their connections, and then to the remaining workloads. Candidates are ranked by their
+// degree (number of incident edges), with the workload ID as a tie-breaker so the result is
+// reproducible. Isolated workloads are therefore the first to be dropped. Ports of dropped
+// workloads and edges touching them are removed, and the returned graph records the
+// truncation so the output can say so. A non-positive maxWorkloads disables truncation.
+func Truncate(g *NetworkGraph, maxWorkloads int) *NetworkGraph {
+	if g == nil || maxWorkloads <= 0 {
+		return g
+	}
+
+	var workloads []Node
+	portParent := make(map[string]string) // port ID -> parent workload ID
+	sources := make(map[string]bool)      // gateway and ANY node IDs, always kept
+	for _, n := range g.Nodes {
+		switch n.Type {
+		case NodeTypeWorkload:
+			workloads = append(workloads, n)
+		case NodeTypePort:
+			portParent[n.ID] = n.Parent
+		case NodeTypeGateway, NodeTypeAny:
+			sources[n.ID] = true
+		}
+	}
+	if len(workloads) <= maxWorkloads {
+		return g
+	}
+
+	// Count incident edges per workload, and index neighbors and workloads open to everyone
+	anyNodes := anyNodeIDs(g)
+	degree := make(map[string]int)
+	neighbors := make(map[string][]string)
+	fromAny := make(map[string]bool)
+	for _, e := range g.Edges {
+		target := edgeTargetWorkload(e, portParent)
+		degree[e.Source]++
+		degree[target]++
+		if anyNodes[e.Source] {
+			fromAny[target] = true
+		}
+		neighbors[e.Source] = append(neighbors[e.Source], target)
+		neighbors[target] = append(neighbors[target], e.Source)
+	}
+
+	priority := func(n Node) bool { return len(n.Warnings) > 0 || fromAny[n.ID] }
+	sort.SliceStable(workloads, func(i, j int) bool {
+		pi, pj := priority(workloads[i]), priority(workloads[j])
+		if pi != pj {
+			return pi
+		}
+		if degree[workloads[i].ID] != degree[workloads[j].ID] {
+			return degree[workloads[i].ID] > degree[workloads[j].ID]
+		}
+		return workloads[i].ID < workloads[j].ID
+	})
+
+	keep := make(map[string]bool, maxWorkloads)
+	adjacent := make(map[string]bool) // workloads with an edge to or from a kept workload
+	add := func(id string) {
+		keep[id] = true
+		for _, n := range neighbors[id] {
+			adjacent[n] = true
+		}
+	}
+	for _, w := range workloads {
+		if len(keep) < maxWorkloads && priority(w) {
+			add(w.ID)
+		}
+	}
+	for len(keep) < maxWorkloads {
+		next := ""
+		for _, w := range workloads {
+			if keep[w.ID] {
+				continue
+			}
+			if adjacent[w.ID] {
+				next = w.ID
+				break
+			}
+			if next == "" {
+				next = w.ID
+			}
+		}
+		add(next)
+	}
+
+	truncated := &NetworkGraph{
+		Nodes:        make([]Node, 0, len(g.Nodes)),
+		Edges:        make([]Edge, 0, len(g.Edges)),
+		Namespaces:   g.Namespaces,
+		PolicyCounts: g.PolicyCounts,
+		Truncation: &Truncation{
+			ShownWorkloads: maxWorkloads,
+			TotalWorkloads: len(workloads),
+		},
+	}
+
+	// Preserve the original node order for the kept workloads and their ports
+	for _, n := range g.Nodes {
+		switch n.Type {
+		case NodeTypeWorkload:
+			if keep[n.ID] {
+				truncated.Nodes = append(truncated.Nodes, n)
+			}
+		case NodeTypePort:
+			if keep[n.Parent] {
+				truncated.Nodes = append(truncated.Nodes, n)
+			}
+		default:
+			truncated.Nodes = append(truncated.Nodes, n)
+		}
+	}
+
+	for _, e := range g.Edges {
+		target := edgeTargetWorkload(e, portParent)
+		if (keep[e.Source] || sources[e.Source]) && (keep[target] || sources[target]) {
+			truncated.Edges = append(truncated.Edges, e)
+		}
+	}
+
+	// Keep the members of kept meta-nodes, and their edges, so they can still be expanded
+	memberKept := make(map[string]bool) // kept member workload and port IDs
+	for _, n := range g.MergedNodes {
+		if n.Type == NodeTypeWorkload && keep[n.Group] {
+			memberKept[n.ID] = true
+		}
+	}
+	for _, n := range g.MergedNodes {
+		if n.Type == NodeTypePort && memberKept[n.Parent] {
+			memberKept[n.ID] = true
+		}
+		if memberKept[n.ID] {
+			truncated.MergedNodes = append(truncated.MergedNodes, n)
+		}
+	}
+	for _, e := range g.MergedEdges {
+		if memberKept[e.Source] || memberKept[e.Target] {
+			truncated.MergedEdges = append(truncated.MergedEdges, e)
+		}
+	}
+
+	for _, wd := range g.WarningDetails {
+		// Namespace-level warnings are always kept, and members' warnings stay with their meta-node
+		if wd.WorkloadID == "" || keep[wd.WorkloadID] || memberKept[wd.WorkloadID] {
+			truncated.WarningDetails = append(truncated.WarningDetails, wd)
+		}
+	}
+
+	return truncated
+}
@@ -0,0 +1,235 @@
+package graph
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTruncate(t *testing.T) {
+	base := func() *NetworkGraph {
+		return &NetworkGraph{
+			Nodes: []Node{
+				{ID: "ns/a", Type: NodeTypeWorkload},
+				{ID: "ns/b", Type: NodeTypeWorkload},
+				{ID: "ns/b:TCP/80", Type: NodeTypePort, Parent: "ns/b"},
+				{ID: "ns/c", Type: NodeTypeWorkload},
+				{ID: "ns/c:TCP/80", Type: NodeTypePort, Parent: "ns/c"},
+				{ID: "ns/isolated", Type: NodeTypeWorkload},
+				{ID: "ns/warned", Type: NodeTypeWorkload, Warnings: []WarningType{WarningNoSelector}},
+			},
+			Edges: []Edge{
+				{ID: "edge-0", Source: "ns/a", Target: "ns/b:TCP/80"},
+				{ID: "edge-1", Source: "ns/a", Target: "ns/c:TCP/80"},
+				{ID: "edge-2", Source: "ns/b", Target: "ns/c:TCP/80"},
+			},
+			WarningDetails: []WarningDetail{
+				{WorkloadID: "ns/warned", WarningType: WarningNoSelector},
+			},
+		}
+	}
+
+	tests := map[string]struct {
+		maxWorkloads      int
+		expectedWorkloads []string
+		expectedEdges     int
+		expectTruncation  bool
+	}{
+		"under limit is unchanged": {
+			maxWorkloads:      10,
+			expectedWorkloads: []string{"ns/a", "ns/b", "ns/c", "ns/isolated", "ns/warned"},
+			expectedEdges:     3,
+		},
+		"zero disables truncation": {
+			maxWorkloads:      0,
+			expectedWorkloads: []string{"ns/a", "ns/b", "ns/c", "ns/isolated", "ns/warned"},
+			expectedEdges:     3,
+		},
+		"keeps warned then highest degree": {
+			maxWorkloads:      3,
+			expectedWorkloads: []string{"ns/a", "ns/b", "ns/warned"},
+			expectedEdges:     1,
+			expectTruncation:  true,
+		},
+		"drops isolated workloads first": {
+			maxWorkloads:      4,
+			expectedWorkloads: []string{"ns/a", "ns/b", "ns/c", "ns/warned"},
+			expectedEdges:     3,
+			expectTruncation:  true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := Truncate(base(), tt.maxWorkloads)
+
+			var workloads []string
+			for _, n := range g.Nodes {
+				if n.Type == NodeTypeWorkload {
+					workloads = append(workloads, n.ID)
+				}
+			}
+			if len(workloads) != len(tt.expectedWorkloads) {
+				t.Fatalf("expected workloads %v, got %v", tt.expectedWorkloads, workloads)
+			}
+			for i, id := range workloads {
+				if id != tt.expectedWorkloads[i] {
+					t.Errorf("expected workload[%d] = %q, got %q", i, tt.expectedWorkloads[i], id)
+				}
+			}
+			if len(g.Edges) != tt.expectedEdges {
+				t.Errorf("expected %d edges, got %d", tt.expectedEdges, len(g.Edges))
+			}
+			if tt.expectTruncation {
+				if g.Truncation == nil {
+					t.Fatal("expected truncation info, got nil")
+				}
+				if g.Truncation.ShownWorkloads != tt.maxWorkloads || g.Truncation.TotalWorkloads != 5 {
+					t.Errorf("expected %d of 5 workloads, got %d of %d", tt.maxWorkloads, g.Truncation.ShownWorkloads, g.Truncation.TotalWorkloads)
+				}
+				if len(g.WarningDetails) != 1 {
+					t.Errorf("expected warning details for kept workloads, got %d", len(g.WarningDetails))
+				}
+			} else if g.Truncation != nil {
+				t.Errorf("expected no truncation, got %+v", g.Truncation)
+			}
+		})
+	}
+}
+
+func TestTruncateKeepsMemberWarnings(t *testing.T) {
+	// ns/web is a meta-node merged from web-1 and web-2, each carrying a warning
+	g := &NetworkGraph{
+		Nodes: []Node{
+			{ID: "ns/web", Type: NodeTypeWorkload, Warnings: []WarningType{WarningNoSelector}},
+			{ID: "ns/other", Type: NodeTypeWorkload},
+		},
+		MergedNodes: []Node{
+			{ID: "ns/web-1", Type: NodeTypeWorkload, Group: "ns/web"},
+			{ID: "ns/web-2", Type: NodeTypeWorkload, Group: "ns/web"},
+			{ID: "ns/other-1", Type: NodeTypeWorkload, Group: "ns/other"},
+		},
+		WarningDetails: []WarningDetail{
+			{WorkloadID: "ns/web-1", WarningType: WarningNoSelector},
+			{WorkloadID: "ns/web-2", WarningType: WarningNoSelector},
+			{WorkloadID: "ns/other-1", WarningType: WarningNoSelector},
+		},
+	}
+
+	result := Truncate(g, 1)
+
+	var ids []string
+	for _, wd := range result.WarningDetails {
+		ids = append(ids, wd.WorkloadID)
+	}
+	if expected := []string{"ns/web-1", "ns/web-2"}; !slices.Equal(ids, expected) {
+		t.Errorf("expected warning details for %v, got %v", expected, ids)
+	}
+}
+
+func TestTruncateSelection(t *testing.T) {
+	tests := map[string]struct {
+		graph             *NetworkGraph
+		maxWorkloads      int
+		expectedWorkloads []string
+		expectedEdges     []string
+	}{
+		"keeps neighbors of kept workloads": {
+			graph: &NetworkGraph{
+				Nodes: []Node{
+					{ID: "ns/hub", Type: NodeTypeWorkload},
+					{ID: "ns/hub:TCP/80", Type: NodeTypePort, Parent: "ns/hub"},
+					{ID: "ns/p", Type: NodeTypeWorkload},
+					{ID: "ns/q", Type: NodeTypeWorkload},
+					{ID: "ns/warned", Type: NodeTypeWorkload, Warnings: []WarningType{WarningUncovered}},
+					{ID: "ns/warned:TCP/80", Type: NodeTypePort, Parent: "ns/warned"},
+					{ID: "ns/x", Type: NodeTypeWorkload},
+				},
+				Edges: []Edge{
+					{ID: "edge-0", Source: "ns/p", Target: "ns/hub:TCP/80"},
+					{ID: "edge-1", Source: "ns/q", Target: "ns/hub:TCP/80"},
+					{ID: "edge-2", Source: "ns/x", Target: "ns/warned:TCP/80"},
+				},
+			},
+			maxWorkloads:      2,
+			expectedWorkloads: []string{"ns/warned", "ns/x"},
+			expectedEdges:     []string{"edge-2"},
+		},
+		"prioritizes workloads reached from ANY": {
+			graph: &NetworkGraph{
+				Nodes: []Node{
+					{ID: "ns/a", Type: NodeTypeWorkload},
+					{ID: "ns/b", Type: NodeTypeWorkload},
+					{ID: "ns/b:TCP/80", Type: NodeTypePort, Parent: "ns/b"},
+					{ID: "ns/public", Type: NodeTypeWorkload},
+					{ID: "ns/public:TCP/443", Type: NodeTypePort, Parent: "ns/public"},
+					{ID: AnyNodeID, Type: NodeTypeAny},
+				},
+				Edges: []Edge{
+					{ID: "edge-0", Source: "ns/a", Target: "ns/b:TCP/80"},
+					{ID: "edge-1", Source: AnyNodeID, Target: "ns/public:TCP/443"},
+				},
+			},
+			maxWorkloads:      1,
+			expectedWorkloads: []string{"ns/public"},
+			expectedEdges:     []string{"edge-1"},
+		},
+		"prioritizes workloads reached from a cluster's ANY node": {
+			graph: &NetworkGraph{
+				Nodes: []Node{
+					{ID: "prod/ns/a", Type: NodeTypeWorkload},
+					{ID: "prod/ns/b", Type: NodeTypeWorkload},
+					{ID: "prod/ns/b:TCP/80", Type: NodeTypePort, Parent: "prod/ns/b"},
+					{ID: "prod/ns/public", Type: NodeTypeWorkload},
+					{ID: "prod/ns/public:TCP/443", Type: NodeTypePort, Parent: "prod/ns/public"},
+					{ID: ClusterID("prod", AnyNodeID), Type: NodeTypeAny},
+				},
+				Edges: []Edge{
+					{ID: "edge-0", Source: "prod/ns/a", Target: "prod/ns/b:TCP/80"},
+					{ID: "edge-1", Source: ClusterID("prod", AnyNodeID), Target: "prod/ns/public:TCP/443"},
+				},
+			},
+			maxWorkloads:      1,
+			expectedWorkloads: []string{"prod/ns/public"},
+			expectedEdges:     []string{"edge-1"},
+		},
+		"keeps edges to non-port targets": {
+			graph: &NetworkGraph{
+				Nodes: []Node{
+					{ID: "ns/a", Type: NodeTypeWorkload},
+					{ID: "ns/b", Type: NodeTypeWorkload},
+					{ID: "ns/c", Type: NodeTypeWorkload},
+					{ID: AnyNodeID, Type: NodeTypeAny},
+				},
+				Edges: []Edge{
+					{ID: "edge-0", Source: "ns/a", Target: "ns/b", Direction: DirectionEgress},
+					{ID: "edge-1", Source: "ns/a", Target: AnyNodeID, Direction: DirectionEgress},
+				},
+			},
+			maxWorkloads:      2,
+			expectedWorkloads: []string{"ns/a", "ns/b"},
+			expectedEdges:     []string{"edge-0", "edge-1"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := Truncate(tt.graph, tt.maxWorkloads)
+
+			var workloads, edges []string
+			for _, n := range g.Nodes {
+				if n.Type == NodeTypeWorkload {
+					workloads = append(workloads, n.ID)
+				}
+			}
+			for _, e := range g.Edges {
+				edges = append(edges, e.ID)
+			}
+			if !slices.Equal(workloads, tt.expectedWorkloads) {
+				t.Errorf("expected workloads %v, got %v", tt.expectedWorkloads, workloads)
+			}
+			if !slices.Equal(edges, tt.expectedEdges) {
+				t.Errorf("expected edges %v, got %v", tt.expectedEdges, edges)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package k8s
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Snapshot holds the resources fetched from a cluster for one set of namespaces, in the
+// form the graph builder consumes.
+type Snapshot struct {
+	FetchedAt  time.Time
+	Namespaces []NamespaceInfo
+	Workloads  []Workload
+	Policies   []Policy
+	Services   []ServiceInfo
+}
+
+// Cache stores Snapshots on disk, one JSON file per key, so repeated runs against the same
+// cluster can skip the API. Fetching stays with Client; Cache only reads and writes files.
+type Cache struct {
+	dir string
+	ttl time.Duration    // age after which a snapshot is stale; zero means never
+	now func() time.Time // clock, replaceable in tests
+}
+
+// NewCache returns a Cache storing snapshots in dir. Snapshots older than ttl are treated
+// as missing; a zero ttl keeps them forever.
+func NewCache(dir string, ttl time.Duration) *Cache {
+	return &Cache{dir: dir, ttl: ttl, now: time.Now}
+}
+
+// CacheKey identifies the snapshot of namespaces fetched through the kubeconfig context.
+// The namespaces' order doesn't matter; options holds any other settings that change what
+// is fetched, such as the label selector.
+func CacheKey(context string, namespaces []string, options ...string) string {
+	sorted := slices.Clone(namespaces)
+	slices.Sort(sorted)
+	sorted = slices.Compact(sorted)
+	parts := append([]string{context, strings.Join(sorted, ",")}, options...)
+	return strings.Join(parts, "\x00")
+}
+
+// path returns the file holding the snapshot for key.
+func (c *Cache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, "snapshot-"+hex.EncodeToString(sum[:8])+".json")
+}
+
+// Load returns the snapshot stored for key. It reports false, without an error, when
+// there is no snapshot or it has outlived the TTL.
+func (c *Cache) Load(key string) (*Snapshot, bool, error) {
+	data, err := os.ReadFile(c.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read cache: %w", err)
+	}
+
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, false, fmt.Errorf("failed to decode cache %s: %w", c.path(key), err)
+	}
+	if c.ttl > 0 && c.now().Sub(snapshot.FetchedAt) > c.ttl {
+		return nil, false, nil
+	}
+	return &snapshot, true, nil
+}
+
+// Save stores snapshot under key, replacing any earlier one. The file is written to a
+// temporary name first so a concurrent Load never sees a partial snapshot.
+func (c *Cache) Save(key string, snapshot *Snapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache: %w", err)
+	}
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(c.dir, "snapshot-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path(key)); err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package k8s
+
+import (
+	"testing"
+	"time"
+
+	securityv1beta1 "istio.io/api/security/v1beta1"
+	istiotypev1beta1 "istio.io/api/type/v1beta1"
+	securityclientv1 "istio.io/client-go/pkg/apis/security/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestCacheRoundTrip(t *testing.T) {
+	fetchedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	snapshot := &Snapshot{
+		FetchedAt:  fetchedAt,
+		Namespaces: []NamespaceInfo{{Name: "apps", Labels: map[string]string{"team": "ml"}}},
+		Workloads: []Workload{{
+			Name:      "web",
+			Namespace: "apps",
+			Type:      WorkloadTypeDeployment,
+			Labels:    map[string]string{"app": "web"},
+			Ports:     []Port{{Name: "http", ContainerPort: 8080, Protocol: corev1.ProtocolTCP, ServiceName: "web", ServicePort: 80}},
+		}},
+		Policies: []Policy{
+			{
+				Name:      "allow-web",
+				Namespace: "apps",
+				Type:      PolicyTypeK8sNetworkPolicy,
+				K8sNetworkPolicy: &networkingv1.NetworkPolicy{
+					ObjectMeta: metav1.ObjectMeta{Name: "allow-web", Namespace: "apps"},
+					Spec: networkingv1.NetworkPolicySpec{
+						PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+					},
+				},
+			},
+			{
+				Name:      "deny-web",
+				Namespace: "apps",
+				Type:      PolicyTypeIstioAuthorizationPolicy,
+				IstioAuthPolicy: &securityclientv1.AuthorizationPolicy{
+					ObjectMeta: metav1.ObjectMeta{Name: "deny-web", Namespace: "apps"},
+					Spec: securityv1beta1.AuthorizationPolicy{
+						Selector: &istiotypev1beta1.WorkloadSelector{MatchLabels: map[string]string{"app": "web"}},
+						Action:   securityv1beta1.AuthorizationPolicy_DENY,
+						Rules: []*securityv1beta1.Rule{{
+							To: []*securityv1beta1.Rule_To{{Operation: &securityv1beta1.Operation{Ports: []string{"8080"}}}},
+						}},
+					},
+				},
+			},
+		},
+		Services: []ServiceInfo{{
+			Name:      "web",
+			Namespace: "apps",
+			Selector:  map[string]string{"app": "web"},
+			Ports:     []ServicePortInfo{{Name: "http", Port: 80, Protocol: corev1.ProtocolTCP, TargetPort: intstr.FromString("http")}},
+		}},
+	}
+
+	cache := NewCache(t.TempDir(), time.Hour)
+	cache.now = func() time.Time { return fetchedAt.Add(time.Minute) }
+	key := CacheKey("prod", []string{"apps", "data"})
+	if err := cache.Save(key, snapshot); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	got, ok, err := cache.Load(CacheKey("prod", []string{"data", "apps"}))
+	if err != nil || !ok {
+		t.Fatalf("expected a cache hit regardless of namespace order, got ok=%v err=%v", ok, err)
+	}
+	if !got.FetchedAt.Equal(fetchedAt) {
+		t.Errorf("expected FetchedAt %v, got %v", fetchedAt, got.FetchedAt)
+	}
+	if len(got.Workloads) != 1 || got.Workloads[0].Ports[0].ServicePort != 80 {
+		t.Errorf("expected the workload and its ports to survive, got %+v", got.Workloads)
+	}
+	if len(got.Services) != 1 || got.Services[0].Ports[0].TargetPort.StrVal != "http" {
+		t.Errorf("expected the service and its named target port to survive, got %+v", got.Services)
+	}
+	if len(got.Policies) != 2 || got.Policies[0].K8sNetworkPolicy == nil {
+		t.Fatalf("expected both policies to survive, got %+v", got.Policies)
+	}
+	istio := got.Policies[1].IstioAuthPolicy
+	if istio == nil || istio.Spec.Action != securityv1beta1.AuthorizationPolicy_DENY || istio.Spec.Selector.MatchLabels["app"] != "web" {
+		t.Errorf("expected the AuthorizationPolicy spec to survive, got %+v", istio)
+	}
+	if ports := istio.Spec.Rules[0].To[0].Operation.Ports; len(ports) != 1 || ports[0] != "8080" {
+		t.Errorf("expected rule ports [8080], got %v", ports)
+	}
+}
+
+func TestCacheLoadMiss(t *testing.T) {
+	fetchedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := map[string]struct {
+		ttl      time.Duration
+		age      time.Duration
+		key      string
+		expectOK bool
+	}{
+		"fresh snapshot": {
+			ttl:      time.Hour,
+			age:      time.Minute,
+			key:      CacheKey("prod", []string{"apps"}),
+			expectOK: true,
+		},
+		"stale snapshot": {
+			ttl: time.Hour,
+			age: 2 * time.Hour,
+			key: CacheKey("prod", []string{"apps"}),
+		},
+		"zero TTL never expires": {
+			age:      24 * time.Hour,
+			key:      CacheKey("prod", []string{"apps"}),
+			expectOK: true,
+		},
+		"other namespace set": {
+			ttl: time.Hour,
+			key: CacheKey("prod", []string{"apps", "data"}),
+		},
+		"other context": {
+			ttl: time.Hour,
+			key: CacheKey("staging", []string{"apps"}),
+		},
+		"other options": {
+			ttl: time.Hour,
+			key: CacheKey("prod", []string{"apps"}, "team=ml"),
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			cache := NewCache(t.TempDir(), tt.ttl)
+			cache.now = func() time.Time { return fetchedAt.Add(tt.age) }
+			if err := cache.Save(CacheKey("prod", []string{"apps"}), &Snapshot{FetchedAt: fetchedAt}); err != nil {
+				t.Fatalf("Save failed: %v", err)
+			}
+
+			_, ok, err := cache.Load(tt.key)
+			if err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+			if ok != tt.expectOK {
+				t.Errorf("expected hit=%v, got %v", tt.expectOK, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package k8s
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// CiliumNetworkPolicyGVR identifies CiliumNetworkPolicies for the dynamic client.
+var CiliumNetworkPolicyGVR = schema.GroupVersionResource{
+	Group:    "cilium.io",
+	Version:  "v2",
+	Resource: "ciliumnetworkpolicies",
+}
+
+// CiliumNetworkPolicy is the subset of a CiliumNetworkPolicy dnmap graphs. It is decoded
+// from unstructured objects so the Cilium module isn't a dependency.
+type CiliumNetworkPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              *CiliumRule  `json:"spec,omitempty"`
+	Specs             []CiliumRule `json:"specs,omitempty"`
+}
+
+// Rules returns the policy's spec followed by its specs.
+func (p *CiliumNetworkPolicy) Rules() []CiliumRule {
+	var rules []CiliumRule
+	if p.Spec != nil {
+		rules = append(rules, *p.Spec)
+	}
+	return append(rules, p.Specs...)
+}
+
+// CiliumRule selects endpoints and lists the traffic allowed to and from them.
+type CiliumRule struct {
+	EndpointSelector metav1.LabelSelector `json:"endpointSelector"`
+	Ingress          []CiliumIngressRule  `json:"ingress,omitempty"`
+	Egress           []CiliumEgressRule   `json:"egress,omitempty"`
+}
+
+// CiliumIngressRule allows traffic from the listed peers to the listed ports.
+type CiliumIngressRule struct {
+	FromEndpoints []metav1.LabelSelector `json:"fromEndpoints,omitempty"`
+	FromEntities  []string               `json:"fromEntities,omitempty"`
+	FromCIDR      []string               `json:"fromCIDR,omitempty"`
+	FromCIDRSet   []map[string]any       `json:"fromCIDRSet,omitempty"`
+	ToPorts       []CiliumPortRule       `json:"toPorts,omitempty"`
+}
+
+// CiliumEgressRule allows traffic to the listed peers on the listed ports.
+type CiliumEgressRule struct {
+	ToEndpoints []metav1.LabelSelector `json:"toEndpoints,omitempty"`
+	ToEntities  []string               `json:"toEntities,omitempty"`
+	ToCIDR      []string               `json:"toCIDR,omitempty"`
+	ToCIDRSet   []map[string]any       `json:"toCIDRSet,omitempty"`
+	ToFQDNs     []map[string]any       `json:"toFQDNs,omitempty"`
+	ToPorts     []CiliumPortRule       `json:"toPorts,omitempty"`
+}
+
+// CiliumPortRule lists ports and the L7 rules applied to them.
+type CiliumPortRule struct {
+	Ports []CiliumPortProtocol `json:"ports,omitempty"`
+	Rules *CiliumL7Rules       `json:"rules,omitempty"`
+}
+
+// CiliumPortProtocol is a port number or name, optionally a range up to EndPort. An
+// empty Protocol or "ANY" matches every protocol.
+type CiliumPortProtocol struct {
+	Port     string `json:"port,omitempty"`
+	EndPort  int32  `json:"endPort,omitempty"`
+	Protocol string `json:"protocol,omitempty"`
+}
+
+// CiliumL7Rules holds the L7 rules of a port rule; dnmap only records that they exist.
+type CiliumL7Rules struct {
+	HTTP  []map[string]any `json:"http,omitempty"`
+	Kafka []map[string]any `json:"kafka,omitempty"`
+	DNS   []map[string]any `json:"dns,omitempty"`
+}
+
+// Empty reports whether no L7 rules are set.
+func (r *CiliumL7Rules) Empty() bool {
+	return r == nil || (len(r.HTTP) == 0 && len(r.Kafka) == 0 && len(r.DNS) == 0)
+}
+
+// GetCiliumNetworkPolicies fetches CiliumNetworkPolicies from the specified namespaces.
+// Like Istio policies, they are skipped with a warning when Cilium isn't installed.
+func (c *Client) GetCiliumNetworkPolicies(namespaces []string) ([]Policy, error) {
+	var policies []Policy
+
+	if c.dynamicClient == nil {
+		return policies, nil
+	}
+
+	for _, ns := range namespaces {
+		list, err := withRetry(c, func(ctx context.Context) (*unstructured.UnstructuredList, error) {
+			return c.dynamicClient.Resource(CiliumNetworkPolicyGVR).Namespace(ns).List(ctx, metav1.ListOptions{})
+		})
+		if err != nil {
+			c.log().Warn("failed to list CiliumNetworkPolicies", "namespace", ns, "error", err)
+			continue
+		}
+		for i := range list.Items {
+			cnp := &CiliumNetworkPolicy{}
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(list.Items[i].Object, cnp); err != nil {
+				c.log().Warn("failed to decode CiliumNetworkPolicy", "namespace", ns, "name", list.Items[i].GetName(), "error", err)
+				continue
+			}
+			policies = append(policies, Policy{
+				Name:                cnp.Name,
+				Namespace:           cnp.Namespace,
+				Type:                PolicyTypeCiliumNetworkPolicy,
+				CiliumNetworkPolicy: cnp,
+			})
+		}
+	}
+
+	return policies, nil
+}
@@ -3,14 +3,24 @@ package k8s
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
+	"golang.org/x/sync/errgroup"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -38,15 +48,22 @@ type Port struct {
 	Protocol      corev1.Protocol
 	ServiceName   string // Name of the K8s Service exposing this port, if any
 	ServicePort   int32  // The service port number, if different from container port
+	HostPort      int32  // Port bound on the node's network (hostPort), if any
 }
 
+// IgnoreAnnotation opts a workload, or every workload in a namespace, out of the map when set to "true".
+const IgnoreAnnotation = "dnmap.io/ignore"
+
 // Workload represents a Kubernetes workload (Deployment, StatefulSet, DaemonSet, or standalone Pod).
 type Workload struct {
-	Name      string
-	Namespace string
-	Type      WorkloadType
-	Labels    map[string]string
-	Ports     []Port
+	Name           string
+	Namespace      string
+	Type           WorkloadType
+	Labels         map[string]string // Pod labels policies are matched against; see podLabels
+	Ports          []Port
+	Ignored        bool   // Set when the workload or its namespace carries IgnoreAnnotation
+	ServiceAccount string // Pod template serviceAccountName, named by Istio principals
+	HostNetwork    bool   // Pods share the node's network namespace (hostNetwork: true)
 }
 
 // PolicyType represents the type of network policy.
@@ -55,9 +72,13 @@ type PolicyType string
 const (
 	PolicyTypeK8sNetworkPolicy         PolicyType = "NetworkPolicy"
 	PolicyTypeIstioAuthorizationPolicy PolicyType = "AuthorizationPolicy"
+	PolicyTypeIstioPeerAuthentication  PolicyType = "PeerAuthentication"
+	PolicyTypeHTTPRoute                PolicyType = "HTTPRoute"
+	PolicyTypeCiliumNetworkPolicy      PolicyType = "CiliumNetworkPolicy"
 )
 
-// Policy represents a unified view of network policies (both K8s NetworkPolicy and Istio AuthorizationPolicy).
+// Policy represents a unified view of network policies (K8s NetworkPolicy, Istio AuthorizationPolicy
+// and the other sources dnmap reads).
 type Policy struct {
 	Name      string
 	Namespace string
@@ -66,40 +87,105 @@ type Policy struct {
 	K8sNetworkPolicy *networkingv1.NetworkPolicy
 	// For Istio AuthorizationPolicy
 	IstioAuthPolicy *securityclientv1.AuthorizationPolicy
+	// For Istio PeerAuthentication
+	IstioPeerAuth *securityclientv1.PeerAuthentication
+	// For Gateway API HTTPRoute
+	HTTPRoute *HTTPRoute
+	// For CiliumNetworkPolicy
+	CiliumNetworkPolicy *CiliumNetworkPolicy
+	// SourceFile is the manifest file the policy was loaded from; empty for live clusters
+	SourceFile string
 }
 
 // Client wraps the Kubernetes and Istio clientsets.
 type Client struct {
-	k8sClientset   kubernetes.Interface
-	istioClientset istioclient.Interface
+	k8sClientset            kubernetes.Interface
+	istioClientset          istioclient.Interface
+	dynamicClient           dynamic.Interface // lists CRDs without typed clients, e.g. HTTPRoutes; nil skips them
+	respectIgnoreAnnotation bool
+	context                 string        // kubeconfig context name, or InClusterContext
+	labelSelector           string        // restricts GetWorkloads to matching workloads
+	initContainerPorts      bool          // also collects ports declared on init containers
+	timeout                 time.Duration // bounds each API call; zero means no limit
+	concurrency             int           // namespaces fetched in parallel; <= 0 means DefaultConcurrency
+	logger                  *slog.Logger  // receives non-fatal warnings; nil means slog.Default()
+	skipForbidden           bool          // lists the RBAC role may not make are skipped instead of failing
+
+	forbiddenMu sync.Mutex
+	forbidden   map[ForbiddenResource]bool // skipped since the last TakeForbidden
+}
+
+// DefaultConcurrency is the number of namespaces fetched in parallel unless WithConcurrency
+// says otherwise.
+const DefaultConcurrency = 8
+
+// Client-side rate limits used when ClientOptions leaves them unset. client-go's own
+// defaults (5 QPS, burst 10) make scans of large clusters crawl.
+const (
+	DefaultQPS   = 50
+	DefaultBurst = 100
+)
+
+// ClientOptions tunes the REST config of clients created by NewClient and NewClientForContext.
+type ClientOptions struct {
+	// QPS is the sustained request rate to the API server; zero uses DefaultQPS and a
+	// negative value disables client-side rate limiting.
+	QPS float32
+	// Burst is the number of requests allowed above QPS; zero uses DefaultBurst.
+	Burst int
+}
+
+// apply sets the rate limits in config, falling back to the defaults.
+func (o ClientOptions) apply(config *rest.Config) {
+	config.QPS = o.QPS
+	if config.QPS == 0 {
+		config.QPS = DefaultQPS
+	}
+	config.Burst = o.Burst
+	if config.Burst <= 0 {
+		config.Burst = DefaultBurst
+	}
 }
 
+// InClusterContext is the context name reported when running with the in-cluster config.
+const InClusterContext = "in-cluster"
+
 // NewClient creates a new Kubernetes and Istio client.
 // It uses the standard kubectl config loading rules:
 // 1. If kubeconfig is provided, use that file
 // 2. Otherwise, check KUBECONFIG environment variable
 // 3. Fall back to ~/.kube/config
 // 4. If running in-cluster, use the service account token
-// The currently selected context in the kubeconfig is used.
-func NewClient(kubeconfig string) (*Client, error) {
+// The currently selected context in the kubeconfig is used. opts sets client-side rate limits.
+func NewClient(kubeconfig string, opts ClientOptions) (*Client, error) {
+	return NewClientForContext(kubeconfig, "", opts)
+}
+
+// NewClientForContext creates a new Kubernetes and Istio client for the named kubeconfig
+// context. An empty contextName behaves like NewClient: the in-cluster config is preferred,
+// then the kubeconfig's current context. A named context always comes from the kubeconfig.
+func NewClientForContext(kubeconfig, contextName string, opts ClientOptions) (*Client, error) {
 	var config *rest.Config
 	var err error
 
 	// First, try in-cluster config (for when running inside a pod)
-	config, err = rest.InClusterConfig()
-	if err == nil {
-		// We're running in-cluster, use that config
-		goto createClients
+	if contextName == "" {
+		config, err = rest.InClusterConfig()
+		if err == nil {
+			// We're running in-cluster, use that config
+			contextName = InClusterContext
+			goto createClients
+		}
 	}
 
-	// Not in-cluster, try kubeconfig (respects current context)
+	// Not in-cluster, try kubeconfig (respects current context unless one is named)
 	{
 		loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
 		if kubeconfig != "" {
 			loadingRules.ExplicitPath = kubeconfig
 		}
 
-		configOverrides := &clientcmd.ConfigOverrides{}
+		configOverrides := &clientcmd.ConfigOverrides{CurrentContext: contextName}
 		kubeConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, configOverrides)
 
 		// Get the raw config to check what's happening
@@ -108,17 +194,23 @@ func NewClient(kubeconfig string) (*Client, error) {
 			return nil, fmt.Errorf("failed to load kubeconfig: %w", rawErr)
 		}
 
-		if rawConfig.CurrentContext == "" {
-			return nil, fmt.Errorf("no current context set in kubeconfig; run 'kubectl config use-context <context>' to set one")
+		if contextName == "" {
+			if rawConfig.CurrentContext == "" {
+				return nil, fmt.Errorf("no current context set in kubeconfig; run 'kubectl config use-context <context>' to set one")
+			}
+			contextName = rawConfig.CurrentContext
+		} else if _, ok := rawConfig.Contexts[contextName]; !ok {
+			return nil, fmt.Errorf("context %q not found in kubeconfig", contextName)
 		}
 
 		config, err = kubeConfig.ClientConfig()
 		if err != nil {
-			return nil, fmt.Errorf("failed to create client config from kubeconfig (current-context: %s): %w", rawConfig.CurrentContext, err)
+			return nil, fmt.Errorf("failed to create client config from kubeconfig (context: %s): %w", contextName, err)
 		}
 	}
 
 createClients:
+	opts.apply(config)
 
 	k8sClientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
@@ -130,9 +222,16 @@ createClients:
 		return nil, fmt.Errorf("failed to create istio clientset: %w", err)
 	}
 
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+
 	return &Client{
 		k8sClientset:   k8sClientset,
 		istioClientset: istioClientset,
+		dynamicClient:  dynamicClient,
+		context:        contextName,
 	}, nil
 }
 
@@ -145,6 +244,200 @@ func NewClientWithInterface(k8s kubernetes.Interface, istio istioclient.Interfac
 	}
 }
 
+// WithDynamicClient sets the dynamic client used for resources without a typed clientset,
+// such as Gateway API HTTPRoutes. This is useful for testing.
+func (c *Client) WithDynamicClient(d dynamic.Interface) *Client {
+	c.dynamicClient = d
+	return c
+}
+
+// WithIgnoreAnnotation controls whether workloads annotated with IgnoreAnnotation
+// (directly or through their namespace) are marked as Ignored.
+func (c *Client) WithIgnoreAnnotation(respect bool) *Client {
+	c.respectIgnoreAnnotation = respect
+	return c
+}
+
+// WithLabelSelector restricts GetWorkloads to workloads matching the label selector,
+// given in Kubernetes label-selector syntax. Callers should validate it with labels.Parse.
+func (c *Client) WithLabelSelector(selector string) *Client {
+	c.labelSelector = selector
+	return c
+}
+
+// WithInitContainerPorts controls whether GetWorkloads also collects the ports declared on
+// pod template init containers, such as sidecars that serve readiness probes. Off by default.
+func (c *Client) WithInitContainerPorts(include bool) *Client {
+	c.initContainerPorts = include
+	return c
+}
+
+// WithTimeout bounds every Kubernetes and Istio API call the client makes. A zero or
+// negative timeout leaves calls unbounded.
+func (c *Client) WithTimeout(timeout time.Duration) *Client {
+	c.timeout = timeout
+	return c
+}
+
+// withTimeout runs a single API call with a context bounded by the client's timeout.
+// Callers wrap the returned error with the namespace and resource being listed.
+func withTimeout[T any](c *Client, call func(ctx context.Context) (T, error)) (T, error) {
+	ctx := context.Background()
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	result, err := call(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return result, fmt.Errorf("timed out after %s: %w", c.timeout, err)
+	}
+	return result, err
+}
+
+// WithLogger sets the logger that receives non-fatal warnings, such as Istio resources
+// that can't be listed because Istio isn't installed.
+func (c *Client) WithLogger(logger *slog.Logger) *Client {
+	c.logger = logger
+	return c
+}
+
+// log returns the client's logger, falling back to slog.Default().
+func (c *Client) log() *slog.Logger {
+	if c.logger == nil {
+		return slog.Default()
+	}
+	return c.logger
+}
+
+// WithConcurrency sets how many namespaces GetWorkloads and GetPolicies fetch in parallel.
+// Zero or negative values use DefaultConcurrency.
+func (c *Client) WithConcurrency(concurrency int) *Client {
+	c.concurrency = concurrency
+	return c
+}
+
+// ForbiddenResource is a resource the client's RBAC role may not list in a namespace.
+type ForbiddenResource struct {
+	Namespace string
+	Resource  string // e.g. "deployments"
+}
+
+// WithSkipForbidden controls whether namespace-scoped lists that the API server forbids are
+// skipped, so a user with partial access still gets a map of what they can see. Skipped
+// resources are reported by TakeForbidden. Off by default: a forbidden list fails the call.
+func (c *Client) WithSkipForbidden(skip bool) *Client {
+	c.skipForbidden = skip
+	return c
+}
+
+// TakeForbidden returns the resources skipped as forbidden since the last call, sorted by
+// namespace and resource, and forgets them.
+func (c *Client) TakeForbidden() []ForbiddenResource {
+	c.forbiddenMu.Lock()
+	defer c.forbiddenMu.Unlock()
+
+	result := make([]ForbiddenResource, 0, len(c.forbidden))
+	for r := range c.forbidden {
+		result = append(result, r)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Namespace != result[j].Namespace {
+			return result[i].Namespace < result[j].Namespace
+		}
+		return result[i].Resource < result[j].Resource
+	})
+	c.forbidden = nil
+	return result
+}
+
+// skip reports whether err is a forbidden error the client skips, recording the namespace
+// and resource it was listing when so. A nil err is never skipped.
+func (c *Client) skip(err error, ns, resource string) bool {
+	if !c.skipForbidden || !apierrors.IsForbidden(err) {
+		return false
+	}
+	c.forbiddenMu.Lock()
+	defer c.forbiddenMu.Unlock()
+	if c.forbidden == nil {
+		c.forbidden = make(map[ForbiddenResource]bool)
+	}
+	c.forbidden[ForbiddenResource{Namespace: ns, Resource: resource}] = true
+	return true
+}
+
+// fetchNamespaces calls fetch for every namespace on a pool of c.concurrency workers and
+// concatenates the results in namespace order. After the first error no further namespaces
+// are started, and that error is returned.
+func fetchNamespaces[T any](c *Client, namespaces []string, fetch func(ns string) ([]T, error)) ([]T, error) {
+	limit := c.concurrency
+	if limit <= 0 {
+		limit = DefaultConcurrency
+	}
+
+	// Each worker writes only its own slot, so results need no lock
+	results := make([][]T, len(namespaces))
+	g, ctx := errgroup.WithContext(context.Background())
+	g.SetLimit(limit)
+	for i, ns := range namespaces {
+		g.Go(func() error {
+			if ctx.Err() != nil {
+				return nil
+			}
+			items, err := fetch(ns)
+			results[i] = items
+			return err
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	var all []T
+	for _, items := range results {
+		all = append(all, items...)
+	}
+	return all, nil
+}
+
+// retryBackoff spaces out retries of transient API errors: up to 5 attempts, starting
+// at 200ms and doubling to at most 5s.
+var retryBackoff = wait.Backoff{
+	Steps:    5,
+	Duration: 200 * time.Millisecond,
+	Factor:   2,
+	Jitter:   0.1,
+	Cap:      5 * time.Second,
+}
+
+// isTransient reports whether an API error is worth retrying, e.g. etcd leader changes
+// (surfaced as internal errors) or API-server throttling. RBAC and validation errors aren't.
+func isTransient(err error) bool {
+	return apierrors.IsServerTimeout(err) || apierrors.IsTooManyRequests(err) || apierrors.IsInternalError(err)
+}
+
+// withRetry runs a single API call through withTimeout, retrying transient errors with
+// retryBackoff. Other errors, and the last transient one, are returned as is.
+// retry.OnError isn't used because it mistakes a timed-out call for its own interruption
+// and returns nil.
+func withRetry[T any](c *Client, call func(ctx context.Context) (T, error)) (T, error) {
+	backoff := retryBackoff
+	for {
+		result, err := withTimeout(c, call)
+		if err == nil || !isTransient(err) || backoff.Steps <= 1 {
+			return result, err
+		}
+		time.Sleep(backoff.Step())
+	}
+}
+
+// Context returns the name of the kubeconfig context the client talks to,
+// InClusterContext when running in a pod, or "" for clients built from interfaces.
+func (c *Client) Context() string {
+	return c.context
+}
+
 // ParseNamespaces parses a comma-separated list of namespaces.
 func ParseNamespaces(namespaces string) []string {
 	parts := strings.Split(namespaces, ",")
@@ -158,53 +451,132 @@ func ParseNamespaces(namespaces string) []string {
 	return result
 }
 
-// GetWorkloads fetches all workloads from the specified namespaces.
-func (c *Client) GetWorkloads(namespaces []string) ([]Workload, error) {
-	ctx := context.Background()
-	var workloads []Workload
+// FilterNamespaces returns the namespaces in all that are not in exclude, preserving order.
+func FilterNamespaces(all, exclude []string) []string {
+	excluded := make(map[string]bool, len(exclude))
+	for _, ns := range exclude {
+		excluded[ns] = true
+	}
 
-	for _, ns := range namespaces {
-		// Get Services first to map them to workloads
-		services, err := c.k8sClientset.CoreV1().Services(ns).List(ctx, metav1.ListOptions{})
-		if err != nil {
-			return nil, fmt.Errorf("failed to list services in namespace %s: %w", ns, err)
+	result := make([]string, 0, len(all))
+	for _, ns := range all {
+		if !excluded[ns] {
+			result = append(result, ns)
 		}
+	}
+	return result
+}
 
-		// Get Deployments
-		deployments, err := c.k8sClientset.AppsV1().Deployments(ns).List(ctx, metav1.ListOptions{})
-		if err != nil {
-			return nil, fmt.Errorf("failed to list deployments in namespace %s: %w", ns, err)
-		}
-		for _, d := range deployments.Items {
-			w := deploymentToWorkload(d)
-			enrichPortsWithServices(&w, services.Items)
-			workloads = append(workloads, w)
-		}
+// GetWorkloads fetches all workloads from the specified namespaces, along with the selectors
+// and port mappings of their Services, which are listed once per namespace for both.
+// Namespaces are fetched concurrently (see WithConcurrency); the first failure aborts the scan.
+func (c *Client) GetWorkloads(namespaces []string) ([]Workload, []ServiceInfo, error) {
+	workloadOpts := metav1.ListOptions{LabelSelector: c.labelSelector}
+	results, err := fetchNamespaces(c, namespaces, func(ns string) ([]namespaceWorkloads, error) {
+		result, err := c.getNamespaceWorkloads(ns, workloadOpts)
+		return []namespaceWorkloads{result}, err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
 
-		// Get StatefulSets
-		statefulSets, err := c.k8sClientset.AppsV1().StatefulSets(ns).List(ctx, metav1.ListOptions{})
-		if err != nil {
-			return nil, fmt.Errorf("failed to list statefulsets in namespace %s: %w", ns, err)
-		}
-		for _, s := range statefulSets.Items {
-			w := statefulSetToWorkload(s)
-			enrichPortsWithServices(&w, services.Items)
-			workloads = append(workloads, w)
-		}
+	var workloads []Workload
+	var services []ServiceInfo
+	for _, r := range results {
+		workloads = append(workloads, r.workloads...)
+		services = append(services, r.services...)
+	}
+	return workloads, services, nil
+}
 
-		// Get DaemonSets
-		daemonSets, err := c.k8sClientset.AppsV1().DaemonSets(ns).List(ctx, metav1.ListOptions{})
-		if err != nil {
-			return nil, fmt.Errorf("failed to list daemonsets in namespace %s: %w", ns, err)
-		}
-		for _, ds := range daemonSets.Items {
-			w := daemonSetToWorkload(ds)
-			enrichPortsWithServices(&w, services.Items)
-			workloads = append(workloads, w)
+// namespaceWorkloads holds the workloads and Services fetched from a single namespace.
+type namespaceWorkloads struct {
+	workloads []Workload
+	services  []ServiceInfo
+}
+
+// getNamespaceWorkloads fetches the workloads and Services of a single namespace.
+func (c *Client) getNamespaceWorkloads(ns string, workloadOpts metav1.ListOptions) (namespaceWorkloads, error) {
+	var result namespaceWorkloads
+
+	// Check whether the whole namespace is opted out
+	nsIgnored := false
+	if c.respectIgnoreAnnotation {
+		namespace, err := withRetry(c, func(ctx context.Context) (*corev1.Namespace, error) {
+			return c.k8sClientset.CoreV1().Namespaces().Get(ctx, ns, metav1.GetOptions{})
+		})
+		if c.skip(err, ns, "namespaces") {
+			namespace = &corev1.Namespace{} // Only namespace annotations are missed
+		} else if err != nil {
+			return namespaceWorkloads{}, fmt.Errorf("failed to get namespace %s: %w", ns, err)
 		}
+		nsIgnored = c.isIgnored(namespace.Annotations)
+	}
+
+	// Get Services first to map them to workloads
+	services, err := withRetry(c, func(ctx context.Context) (*corev1.ServiceList, error) {
+		return c.k8sClientset.CoreV1().Services(ns).List(ctx, metav1.ListOptions{})
+	})
+	if c.skip(err, ns, "services") {
+		services = &corev1.ServiceList{}
+	} else if err != nil {
+		return namespaceWorkloads{}, fmt.Errorf("failed to list services in namespace %s: %w", ns, err)
+	}
+
+	// Get Deployments
+	deployments, err := withRetry(c, func(ctx context.Context) (*appsv1.DeploymentList, error) {
+		return c.k8sClientset.AppsV1().Deployments(ns).List(ctx, workloadOpts)
+	})
+	if c.skip(err, ns, "deployments") {
+		deployments = &appsv1.DeploymentList{}
+	} else if err != nil {
+		return namespaceWorkloads{}, fmt.Errorf("failed to list deployments in namespace %s: %w", ns, err)
+	}
+	for _, d := range deployments.Items {
+		w := deploymentToWorkload(d, c.initContainerPorts)
+		w.Ignored = nsIgnored || c.isIgnored(d.Annotations)
+		enrichPortsWithServices(&w, services.Items)
+		result.workloads = append(result.workloads, w)
+	}
+
+	// Get StatefulSets
+	statefulSets, err := withRetry(c, func(ctx context.Context) (*appsv1.StatefulSetList, error) {
+		return c.k8sClientset.AppsV1().StatefulSets(ns).List(ctx, workloadOpts)
+	})
+	if c.skip(err, ns, "statefulsets") {
+		statefulSets = &appsv1.StatefulSetList{}
+	} else if err != nil {
+		return namespaceWorkloads{}, fmt.Errorf("failed to list statefulsets in namespace %s: %w", ns, err)
 	}
+	for _, s := range statefulSets.Items {
+		w := statefulSetToWorkload(s, c.initContainerPorts)
+		w.Ignored = nsIgnored || c.isIgnored(s.Annotations)
+		enrichPortsWithServices(&w, services.Items)
+		result.workloads = append(result.workloads, w)
+	}
+
+	// Get DaemonSets
+	daemonSets, err := withRetry(c, func(ctx context.Context) (*appsv1.DaemonSetList, error) {
+		return c.k8sClientset.AppsV1().DaemonSets(ns).List(ctx, workloadOpts)
+	})
+	if c.skip(err, ns, "daemonsets") {
+		daemonSets = &appsv1.DaemonSetList{}
+	} else if err != nil {
+		return namespaceWorkloads{}, fmt.Errorf("failed to list daemonsets in namespace %s: %w", ns, err)
+	}
+	for _, ds := range daemonSets.Items {
+		w := daemonSetToWorkload(ds, c.initContainerPorts)
+		w.Ignored = nsIgnored || c.isIgnored(ds.Annotations)
+		enrichPortsWithServices(&w, services.Items)
+		result.workloads = append(result.workloads, w)
+	}
+
+	return result, nil
+}
 
-	return workloads, nil
+// isIgnored reports whether the annotations opt the object out of the map.
+func (c *Client) isIgnored(annotations map[string]string) bool {
+	return c.respectIgnoreAnnotation && annotations[IgnoreAnnotation] == "true"
 }
 
 // enrichPortsWithServices adds service information to workload ports.
@@ -246,43 +618,116 @@ func labelsMatch(selector, labels map[string]string) bool {
 	return true
 }
 
+// ServiceInfo holds the selector and port mappings of a Service.
+type ServiceInfo struct {
+	Name      string
+	Namespace string
+	Selector  map[string]string
+	Ports     []ServicePortInfo
+}
+
+// ServicePortInfo maps a Service port to the port it targets on the selected pods.
+type ServicePortInfo struct {
+	Name       string
+	Port       int32
+	Protocol   corev1.Protocol
+	TargetPort intstr.IntOrString // Container port number or name; zero means same as Port
+}
+
+// serviceInfo returns the selector and port mappings of svc.
+func serviceInfo(svc corev1.Service) ServiceInfo {
+	info := ServiceInfo{
+		Name:      svc.Name,
+		Namespace: svc.Namespace,
+		Selector:  svc.Spec.Selector,
+	}
+	for _, sp := range svc.Spec.Ports {
+		info.Ports = append(info.Ports, ServicePortInfo{
+			Name:       sp.Name,
+			Port:       sp.Port,
+			Protocol:   sp.Protocol,
+			TargetPort: sp.TargetPort,
+		})
+	}
+	return info
+}
+
 // GetPolicies fetches all network policies (K8s and Istio) from the specified namespaces.
+// Namespaces are fetched concurrently, like GetWorkloads.
 func (c *Client) GetPolicies(namespaces []string) ([]Policy, error) {
-	ctx := context.Background()
+	return fetchNamespaces(c, namespaces, c.getNamespacePolicies)
+}
+
+// getNamespacePolicies fetches the policies of a single namespace.
+func (c *Client) getNamespacePolicies(ns string) ([]Policy, error) {
 	var policies []Policy
 
+	// Get K8s NetworkPolicies
+	netPolicies, err := withRetry(c, func(ctx context.Context) (*networkingv1.NetworkPolicyList, error) {
+		return c.k8sClientset.NetworkingV1().NetworkPolicies(ns).List(ctx, metav1.ListOptions{})
+	})
+	if c.skip(err, ns, "networkpolicies") {
+		netPolicies = &networkingv1.NetworkPolicyList{}
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to list network policies in namespace %s: %w", ns, err)
+	}
+	for i := range netPolicies.Items {
+		policies = append(policies, Policy{
+			Name:             netPolicies.Items[i].Name,
+			Namespace:        netPolicies.Items[i].Namespace,
+			Type:             PolicyTypeK8sNetworkPolicy,
+			K8sNetworkPolicy: &netPolicies.Items[i],
+		})
+	}
+
+	// Get Istio AuthorizationPolicies
+	if c.istioClientset != nil {
+		authPolicies, err := withRetry(c, func(ctx context.Context) (*securityclientv1.AuthorizationPolicyList, error) {
+			return c.istioClientset.SecurityV1().AuthorizationPolicies(ns).List(ctx, metav1.ListOptions{})
+		})
+		if err != nil {
+			// Istio might not be installed, so we just log and continue
+			c.log().Warn("failed to list Istio AuthorizationPolicies", "namespace", ns, "error", err)
+		} else {
+			for _, ap := range authPolicies.Items {
+				policies = append(policies, Policy{
+					Name:            ap.Name,
+					Namespace:       ap.Namespace,
+					Type:            PolicyTypeIstioAuthorizationPolicy,
+					IstioAuthPolicy: ap,
+				})
+			}
+		}
+	}
+
+	return policies, nil
+}
+
+// GetPeerAuthentications fetches Istio PeerAuthentications from the specified namespaces.
+// Like AuthorizationPolicies, they are skipped with a warning when Istio isn't installed.
+func (c *Client) GetPeerAuthentications(namespaces []string) ([]Policy, error) {
+	var policies []Policy
+
+	if c.istioClientset == nil {
+		return policies, nil
+	}
+
 	for _, ns := range namespaces {
-		// Get K8s NetworkPolicies
-		netPolicies, err := c.k8sClientset.NetworkingV1().NetworkPolicies(ns).List(ctx, metav1.ListOptions{})
+		peerAuths, err := withRetry(c, func(ctx context.Context) (*securityclientv1.PeerAuthenticationList, error) {
+			return c.istioClientset.SecurityV1().PeerAuthentications(ns).List(ctx, metav1.ListOptions{})
+		})
 		if err != nil {
-			return nil, fmt.Errorf("failed to list network policies in namespace %s: %w", ns, err)
+			c.log().Warn("failed to list Istio PeerAuthentications", "namespace", ns, "error", err)
+			continue
 		}
-		for i := range netPolicies.Items {
+		for _, pa := range peerAuths.Items {
 			policies = append(policies, Policy{
-				Name:             netPolicies.Items[i].Name,
-				Namespace:        netPolicies.Items[i].Namespace,
-				Type:             PolicyTypeK8sNetworkPolicy,
-				K8sNetworkPolicy: &netPolicies.Items[i],
+				Name:          pa.Name,
+				Namespace:     pa.Namespace,
+				Type:          PolicyTypeIstioPeerAuthentication,
+				IstioPeerAuth: pa,
 			})
 		}
-
-		// Get Istio AuthorizationPolicies
-		if c.istioClientset != nil {
-			authPolicies, err := c.istioClientset.SecurityV1().AuthorizationPolicies(ns).List(ctx, metav1.ListOptions{})
-			if err != nil {
-				// Istio might not be installed, so we just log and continue
-				fmt.Printf("Warning: failed to list Istio AuthorizationPolicies in namespace %s: %v\n", ns, err)
-			} else {
-				for _, ap := range authPolicies.Items {
-					policies = append(policies, Policy{
-						Name:            ap.Name,
-						Namespace:       ap.Namespace,
-						Type:            PolicyTypeIstioAuthorizationPolicy,
-						IstioAuthPolicy: ap,
-					})
-				}
-			}
-		}
 	}
 
 	return policies, nil
@@ -294,13 +739,36 @@ type NamespaceInfo struct {
 	Labels map[string]string
 }
 
+// ListAllNamespaces returns the names of every namespace in the cluster, sorted.
+func (c *Client) ListAllNamespaces() ([]string, error) {
+	namespaces, err := withRetry(c, func(ctx context.Context) (*corev1.NamespaceList, error) {
+		return c.k8sClientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+
+	names := make([]string, 0, len(namespaces.Items))
+	for _, ns := range namespaces.Items {
+		names = append(names, ns.Name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
 // GetNamespaces fetches namespace metadata for the specified namespaces.
 func (c *Client) GetNamespaces(namespaces []string) ([]NamespaceInfo, error) {
-	ctx := context.Background()
 	var result []NamespaceInfo
 
 	for _, ns := range namespaces {
-		namespace, err := c.k8sClientset.CoreV1().Namespaces().Get(ctx, ns, metav1.GetOptions{})
+		namespace, err := withRetry(c, func(ctx context.Context) (*corev1.Namespace, error) {
+			return c.k8sClientset.CoreV1().Namespaces().Get(ctx, ns, metav1.GetOptions{})
+		})
+		if c.skip(err, ns, "namespaces") {
+			// Without its labels, namespace selectors can't match it
+			result = append(result, NamespaceInfo{Name: ns})
+			continue
+		}
 		if err != nil {
 			return nil, fmt.Errorf("failed to get namespace %s: %w", ns, err)
 		}
@@ -316,11 +784,12 @@ func (c *Client) GetNamespaces(namespaces []string) ([]NamespaceInfo, error) {
 // GetNetworkPolicies fetches K8s NetworkPolicies from the specified namespaces.
 // Deprecated: Use GetPolicies instead for unified policy access.
 func (c *Client) GetNetworkPolicies(namespaces []string) ([]networkingv1.NetworkPolicy, error) {
-	ctx := context.Background()
 	var policies []networkingv1.NetworkPolicy
 
 	for _, ns := range namespaces {
-		policyList, err := c.k8sClientset.NetworkingV1().NetworkPolicies(ns).List(ctx, metav1.ListOptions{})
+		policyList, err := withRetry(c, func(ctx context.Context) (*networkingv1.NetworkPolicyList, error) {
+			return c.k8sClientset.NetworkingV1().NetworkPolicies(ns).List(ctx, metav1.ListOptions{})
+		})
 		if err != nil {
 			return nil, fmt.Errorf("failed to list network policies in namespace %s: %w", ns, err)
 		}
@@ -332,7 +801,6 @@ func (c *Client) GetNetworkPolicies(namespaces []string) ([]networkingv1.Network
 
 // GetAuthorizationPolicies fetches Istio AuthorizationPolicies from the specified namespaces.
 func (c *Client) GetAuthorizationPolicies(namespaces []string) ([]*securityclientv1.AuthorizationPolicy, error) {
-	ctx := context.Background()
 	var policies []*securityclientv1.AuthorizationPolicy
 
 	if c.istioClientset == nil {
@@ -340,7 +808,9 @@ func (c *Client) GetAuthorizationPolicies(namespaces []string) ([]*securityclien
 	}
 
 	for _, ns := range namespaces {
-		policyList, err := c.istioClientset.SecurityV1().AuthorizationPolicies(ns).List(ctx, metav1.ListOptions{})
+		policyList, err := withRetry(c, func(ctx context.Context) (*securityclientv1.AuthorizationPolicyList, error) {
+			return c.istioClientset.SecurityV1().AuthorizationPolicies(ns).List(ctx, metav1.ListOptions{})
+		})
 		if err != nil {
 			return nil, fmt.Errorf("failed to list authorization policies in namespace %s: %w", ns, err)
 		}
@@ -350,58 +820,113 @@ func (c *Client) GetAuthorizationPolicies(namespaces []string) ([]*securityclien
 	return policies, nil
 }
 
-func deploymentToWorkload(d appsv1.Deployment) Workload {
+// serviceAccountName returns the service account pods run as, which is "default" when
+// the pod spec doesn't name one.
+func serviceAccountName(spec corev1.PodSpec) string {
+	if spec.ServiceAccountName == "" {
+		return "default"
+	}
+	return spec.ServiceAccountName
+}
+
+// podLabels returns the labels of the pods a controller creates: the pod template's labels,
+// or the selector's matchLabels when the template has none, since every pod the selector
+// picks must carry them.
+func podLabels(template corev1.PodTemplateSpec, selector *metav1.LabelSelector) map[string]string {
+	if len(template.Labels) > 0 || selector == nil {
+		return template.Labels
+	}
+	return selector.MatchLabels
+}
+
+func deploymentToWorkload(d appsv1.Deployment, initPorts bool) Workload {
 	return Workload{
-		Name:      d.Name,
-		Namespace: d.Namespace,
-		Type:      WorkloadTypeDeployment,
-		Labels:    d.Spec.Template.Labels,
-		Ports:     extractPorts(d.Spec.Template.Spec.Containers),
+		Name:           d.Name,
+		Namespace:      d.Namespace,
+		Type:           WorkloadTypeDeployment,
+		Labels:         podLabels(d.Spec.Template, d.Spec.Selector),
+		Ports:          extractPorts(d.Spec.Template.Spec, initPorts),
+		ServiceAccount: serviceAccountName(d.Spec.Template.Spec),
+		HostNetwork:    d.Spec.Template.Spec.HostNetwork,
 	}
 }
 
-func statefulSetToWorkload(s appsv1.StatefulSet) Workload {
+func statefulSetToWorkload(s appsv1.StatefulSet, initPorts bool) Workload {
 	return Workload{
-		Name:      s.Name,
-		Namespace: s.Namespace,
-		Type:      WorkloadTypeStatefulSet,
-		Labels:    s.Spec.Template.Labels,
-		Ports:     extractPorts(s.Spec.Template.Spec.Containers),
+		Name:           s.Name,
+		Namespace:      s.Namespace,
+		Type:           WorkloadTypeStatefulSet,
+		Labels:         podLabels(s.Spec.Template, s.Spec.Selector),
+		Ports:          extractPorts(s.Spec.Template.Spec, initPorts),
+		ServiceAccount: serviceAccountName(s.Spec.Template.Spec),
+		HostNetwork:    s.Spec.Template.Spec.HostNetwork,
 	}
 }
 
-func daemonSetToWorkload(ds appsv1.DaemonSet) Workload {
+func daemonSetToWorkload(ds appsv1.DaemonSet, initPorts bool) Workload {
 	return Workload{
-		Name:      ds.Name,
-		Namespace: ds.Namespace,
-		Type:      WorkloadTypeDaemonSet,
-		Labels:    ds.Spec.Template.Labels,
-		Ports:     extractPorts(ds.Spec.Template.Spec.Containers),
+		Name:           ds.Name,
+		Namespace:      ds.Namespace,
+		Type:           WorkloadTypeDaemonSet,
+		Labels:         podLabels(ds.Spec.Template, ds.Spec.Selector),
+		Ports:          extractPorts(ds.Spec.Template.Spec, initPorts),
+		ServiceAccount: serviceAccountName(ds.Spec.Template.Spec),
+		HostNetwork:    ds.Spec.Template.Spec.HostNetwork,
 	}
 }
 
-func extractPorts(containers []corev1.Container) []Port {
+// extractPorts returns the ports declared on the pod's containers and, with initPorts, on
+// its init containers. An init container port already declared by a regular container, with
+// the same number and protocol, isn't repeated.
+func extractPorts(spec corev1.PodSpec, initPorts bool) []Port {
 	var ports []Port
-	for _, c := range containers {
+	for _, c := range spec.Containers {
 		for _, p := range c.Ports {
-			protocol := p.Protocol
-			if protocol == "" {
-				protocol = corev1.ProtocolTCP
+			ports = append(ports, containerPort(p))
+		}
+	}
+	if !initPorts {
+		return ports
+	}
+
+	declared := make(map[string]bool, len(ports))
+	for _, p := range ports {
+		declared[fmt.Sprintf("%d/%s", p.ContainerPort, p.Protocol)] = true
+	}
+	for _, c := range spec.InitContainers {
+		for _, p := range c.Ports {
+			port := containerPort(p)
+			key := fmt.Sprintf("%d/%s", port.ContainerPort, port.Protocol)
+			if declared[key] {
+				continue
 			}
-			ports = append(ports, Port{
-				Name:          p.Name,
-				ContainerPort: p.ContainerPort,
-				Protocol:      protocol,
-			})
+			declared[key] = true
+			ports = append(ports, port)
 		}
 	}
 	return ports
 }
 
+// containerPort converts a container port, defaulting its protocol to TCP.
+func containerPort(p corev1.ContainerPort) Port {
+	protocol := p.Protocol
+	if protocol == "" {
+		protocol = corev1.ProtocolTCP
+	}
+	return Port{
+		Name:          p.Name,
+		ContainerPort: p.ContainerPort,
+		Protocol:      protocol,
+		HostPort:      p.HostPort,
+	}
+}
+
 // Helper types for Istio API access - re-exported for graph builder
 type (
 	// IstioAuthorizationPolicy is an alias for the Istio AuthorizationPolicy type.
 	IstioAuthorizationPolicy = securityclientv1.AuthorizationPolicy
+	// IstioPeerAuthentication is an alias for the Istio PeerAuthentication type.
+	IstioPeerAuthentication = securityclientv1.PeerAuthentication
 	// IstioRule is an alias for the Istio Rule type.
 	IstioRule = securityv1beta1.Rule
 	// IstioSource is an alias for the Istio Source type.
@@ -1,7 +1,36 @@
 package k8s
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
 	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/wait"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+
+	istiofake "istio.io/client-go/pkg/clientset/versioned/fake"
 )
 
 func TestParseNamespaces(t *testing.T) {
@@ -51,3 +80,719 @@ func TestParseNamespaces(t *testing.T) {
 	}
 }
 
+func TestFilterNamespaces(t *testing.T) {
+	tests := map[string]struct {
+		all      []string
+		exclude  []string
+		expected []string
+	}{
+		"no exclusions": {
+			all:      []string{"apps", "kube-system"},
+			exclude:  nil,
+			expected: []string{"apps", "kube-system"},
+		},
+		"exclude system namespaces": {
+			all:      []string{"apps", "kube-system", "data", "kube-public"},
+			exclude:  []string{"kube-system", "kube-public"},
+			expected: []string{"apps", "data"},
+		},
+		"exclusion not in list": {
+			all:      []string{"apps"},
+			exclude:  []string{"missing"},
+			expected: []string{"apps"},
+		},
+		"everything excluded": {
+			all:      []string{"apps"},
+			exclude:  []string{"apps"},
+			expected: []string{},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			result := FilterNamespaces(tt.all, tt.exclude)
+			if len(result) != len(tt.expected) {
+				t.Errorf("expected %d namespaces, got %d", len(tt.expected), len(result))
+				return
+			}
+			for i, ns := range result {
+				if ns != tt.expected[i] {
+					t.Errorf("expected namespace[%d] = %q, got %q", i, tt.expected[i], ns)
+				}
+			}
+		})
+	}
+}
+
+func TestGetWorkloadsIgnoreAnnotation(t *testing.T) {
+	ignored := map[string]string{IgnoreAnnotation: "true"}
+	objects := []runtime.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "noisy", Annotations: ignored}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "apps"}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "debug", Namespace: "apps", Annotations: ignored}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "chatty", Namespace: "noisy"}},
+	}
+
+	tests := map[string]struct {
+		respect         bool
+		expectedIgnored map[string]bool
+	}{
+		"annotation respected": {
+			respect:         true,
+			expectedIgnored: map[string]bool{"web": false, "debug": true, "chatty": true},
+		},
+		"annotation not respected": {
+			respect:         false,
+			expectedIgnored: map[string]bool{"web": false, "debug": false, "chatty": false},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			client := NewClientWithInterface(fake.NewSimpleClientset(objects...), nil).WithIgnoreAnnotation(tt.respect)
+
+			workloads, _, err := client.GetWorkloads([]string{"apps", "noisy"})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(workloads) != len(tt.expectedIgnored) {
+				t.Fatalf("expected %d workloads, got %d", len(tt.expectedIgnored), len(workloads))
+			}
+			for _, w := range workloads {
+				if w.Ignored != tt.expectedIgnored[w.Name] {
+					t.Errorf("workload %q: expected ignored=%v, got %v", w.Name, tt.expectedIgnored[w.Name], w.Ignored)
+				}
+			}
+		})
+	}
+}
+
+func TestGetWorkloadsLabelSelector(t *testing.T) {
+	objects := []runtime.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps"}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "apps", Labels: map[string]string{"team": "ml"}}},
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "apps", Labels: map[string]string{"team": "ml", "tier": "data"}}},
+		&appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "apps", Labels: map[string]string{"team": "infra"}}},
+	}
+
+	tests := map[string]struct {
+		selector string
+		expected []string
+	}{
+		"no selector": {
+			selector: "",
+			expected: []string{"web", "db", "agent"},
+		},
+		"equality": {
+			selector: "team=ml",
+			expected: []string{"web", "db"},
+		},
+		"set based": {
+			selector: "team in (infra),!tier",
+			expected: []string{"agent"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			client := NewClientWithInterface(fake.NewSimpleClientset(objects...), nil).WithLabelSelector(tt.selector)
+
+			workloads, _, err := client.GetWorkloads([]string{"apps"})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(workloads) != len(tt.expected) {
+				t.Fatalf("expected %d workloads, got %d", len(tt.expected), len(workloads))
+			}
+			for i, w := range workloads {
+				if w.Name != tt.expected[i] {
+					t.Errorf("expected %s at %d, got %s", tt.expected[i], i, w.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestGetWorkloadsTimeout(t *testing.T) {
+	clientset := fake.NewSimpleClientset(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps"}})
+	clientset.PrependReactor("list", "statefulsets", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, context.DeadlineExceeded
+	})
+	client := NewClientWithInterface(clientset, nil).WithTimeout(50 * time.Millisecond)
+
+	_, _, err := client.GetWorkloads([]string{"apps"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	for _, substr := range []string{"statefulsets in namespace apps", "timed out after 50ms"} {
+		if !strings.Contains(err.Error(), substr) {
+			t.Errorf("expected error to contain %q, got %q", substr, err)
+		}
+	}
+}
+
+func TestGetWorkloadsRetry(t *testing.T) {
+	defer func(backoff wait.Backoff) { retryBackoff = backoff }(retryBackoff)
+	retryBackoff = wait.Backoff{Steps: 3, Duration: time.Millisecond, Factor: 2}
+
+	deployments := schema.GroupResource{Group: "apps", Resource: "deployments"}
+	tests := map[string]struct {
+		failure       error
+		failures      int
+		expectError   bool
+		expectedCalls int
+	}{
+		"transient errors then success": {
+			failure:       apierrors.NewInternalError(errors.New("etcdserver: leader changed")),
+			failures:      2,
+			expectedCalls: 3,
+		},
+		"throttled past max attempts": {
+			failure:       apierrors.NewTooManyRequests("slow down", 1),
+			failures:      5,
+			expectError:   true,
+			expectedCalls: 3,
+		},
+		"forbidden fails immediately": {
+			failure:       apierrors.NewForbidden(deployments, "", errors.New("rbac")),
+			failures:      5,
+			expectError:   true,
+			expectedCalls: 1,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps"}},
+				&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "apps"}},
+			)
+			calls := 0
+			clientset.PrependReactor("list", "deployments", func(k8stesting.Action) (bool, runtime.Object, error) {
+				calls++
+				if calls <= tt.failures {
+					return true, nil, tt.failure
+				}
+				// Fall through to the fake object tracker
+				return false, nil, nil
+			})
+
+			workloads, _, err := NewClientWithInterface(clientset, nil).GetWorkloads([]string{"apps"})
+			if tt.expectError {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			} else if len(workloads) != 1 {
+				t.Errorf("expected 1 workload, got %d", len(workloads))
+			}
+			if calls != tt.expectedCalls {
+				t.Errorf("expected %d list calls, got %d", tt.expectedCalls, calls)
+			}
+		})
+	}
+}
+
+func TestSkipForbidden(t *testing.T) {
+	tests := map[string]struct {
+		skip              bool
+		expectError       bool
+		expectedWorkloads []string
+		expectedPolicies  []string
+		expectedForbidden []ForbiddenResource
+	}{
+		"strict": {
+			expectError: true,
+		},
+		"skip forbidden": {
+			skip:              true,
+			expectedWorkloads: []string{"apps/web", "secret/db"},
+			expectedPolicies:  []string{"apps/deny-all"},
+			expectedForbidden: []ForbiddenResource{
+				{Namespace: "secret", Resource: "networkpolicies"},
+				{Namespace: "secret", Resource: "services"},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(
+				&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "apps"}},
+				&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "secret"}},
+				&networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: "deny-all", Namespace: "apps"}},
+				&networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: "deny-all", Namespace: "secret"}},
+			)
+			for _, resource := range []string{"services", "networkpolicies"} {
+				clientset.PrependReactor("list", resource, func(action k8stesting.Action) (bool, runtime.Object, error) {
+					if action.GetNamespace() != "secret" {
+						return false, nil, nil
+					}
+					return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: resource}, "", errors.New("rbac"))
+				})
+			}
+			client := NewClientWithInterface(clientset, nil).WithSkipForbidden(tt.skip)
+
+			workloads, _, err := client.GetWorkloads([]string{"apps", "secret"})
+			if tt.expectError {
+				if err == nil || !apierrors.IsForbidden(err) {
+					t.Errorf("expected a forbidden error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			policies, err := client.GetPolicies([]string{"apps", "secret"})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var workloadIDs, policyIDs []string
+			for _, w := range workloads {
+				workloadIDs = append(workloadIDs, w.Namespace+"/"+w.Name)
+			}
+			for _, p := range policies {
+				policyIDs = append(policyIDs, p.Namespace+"/"+p.Name)
+			}
+			if !slices.Equal(workloadIDs, tt.expectedWorkloads) {
+				t.Errorf("expected workloads %v, got %v", tt.expectedWorkloads, workloadIDs)
+			}
+			if !slices.Equal(policyIDs, tt.expectedPolicies) {
+				t.Errorf("expected policies %v, got %v", tt.expectedPolicies, policyIDs)
+			}
+			if forbidden := client.TakeForbidden(); !slices.Equal(forbidden, tt.expectedForbidden) {
+				t.Errorf("expected forbidden %v, got %v", tt.expectedForbidden, forbidden)
+			}
+			if forbidden := client.TakeForbidden(); len(forbidden) != 0 {
+				t.Errorf("expected forbidden resources to be forgotten once taken, got %v", forbidden)
+			}
+		})
+	}
+}
+
+func TestGetWorkloadsConcurrency(t *testing.T) {
+	var objects []runtime.Object
+	var namespaces []string
+	for i := range 20 {
+		ns := fmt.Sprintf("ns-%02d", i)
+		namespaces = append(namespaces, ns)
+		objects = append(objects, &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: ns}})
+	}
+
+	tests := map[string]struct {
+		failNamespace string
+		expectError   bool
+	}{
+		"all namespaces in order": {},
+		"error names namespace": {
+			failNamespace: "ns-07",
+			expectError:   true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(objects...)
+			clientset.PrependReactor("list", "statefulsets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				if action.GetNamespace() == tt.failNamespace {
+					return true, nil, errors.New("boom")
+				}
+				return false, nil, nil
+			})
+
+			workloads, _, err := NewClientWithInterface(clientset, nil).WithConcurrency(4).GetWorkloads(namespaces)
+			if tt.expectError {
+				if err == nil || !strings.Contains(err.Error(), "namespace "+tt.failNamespace) {
+					t.Errorf("expected error naming %s, got %v", tt.failNamespace, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(workloads) != len(namespaces) {
+				t.Fatalf("expected %d workloads, got %d", len(namespaces), len(workloads))
+			}
+			for i, w := range workloads {
+				if w.Namespace != namespaces[i] {
+					t.Errorf("expected namespace %s at %d, got %s", namespaces[i], i, w.Namespace)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkGetWorkloads scans 40 namespaces against an API server that takes 1ms per
+// request, sequentially and with the default worker pool. A real client is used because
+// the fake clientset serializes every call behind a lock.
+func BenchmarkGetWorkloads(b *testing.B) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"items":[]}`)
+	}))
+	defer server.Close()
+
+	clientset, err := kubernetes.NewForConfig(&rest.Config{Host: server.URL, QPS: -1})
+	if err != nil {
+		b.Fatal(err)
+	}
+	var namespaces []string
+	for i := range 40 {
+		namespaces = append(namespaces, fmt.Sprintf("ns-%02d", i))
+	}
+
+	for _, concurrency := range []int{1, DefaultConcurrency} {
+		b.Run(fmt.Sprintf("concurrency=%d", concurrency), func(b *testing.B) {
+			client := NewClientWithInterface(clientset, nil).WithConcurrency(concurrency)
+			for b.Loop() {
+				if _, _, err := client.GetWorkloads(namespaces); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestClientOptionsApply(t *testing.T) {
+	tests := map[string]struct {
+		opts          ClientOptions
+		expectedQPS   float32
+		expectedBurst int
+	}{
+		"defaults": {
+			opts:          ClientOptions{},
+			expectedQPS:   DefaultQPS,
+			expectedBurst: DefaultBurst,
+		},
+		"explicit": {
+			opts:          ClientOptions{QPS: 200, Burst: 400},
+			expectedQPS:   200,
+			expectedBurst: 400,
+		},
+		"rate limiting disabled": {
+			opts:          ClientOptions{QPS: -1},
+			expectedQPS:   -1,
+			expectedBurst: DefaultBurst,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			config := &rest.Config{}
+			tt.opts.apply(config)
+			if config.QPS != tt.expectedQPS || config.Burst != tt.expectedBurst {
+				t.Errorf("expected QPS %v burst %d, got QPS %v burst %d", tt.expectedQPS, tt.expectedBurst, config.QPS, config.Burst)
+			}
+		})
+	}
+}
+
+func TestWorkloadConverters(t *testing.T) {
+	podSpec := func(serviceAccount string) corev1.PodTemplateSpec {
+		return corev1.PodTemplateSpec{Spec: corev1.PodSpec{ServiceAccountName: serviceAccount}}
+	}
+
+	tests := map[string]struct {
+		workload               Workload
+		expectedType           WorkloadType
+		expectedServiceAccount string
+		expectedLabels         map[string]string
+	}{
+		"deployment with service account": {
+			workload:               deploymentToWorkload(appsv1.Deployment{Spec: appsv1.DeploymentSpec{Template: podSpec("api")}}, false),
+			expectedType:           WorkloadTypeDeployment,
+			expectedServiceAccount: "api",
+		},
+		"deployment without service account": {
+			workload:               deploymentToWorkload(appsv1.Deployment{}, false),
+			expectedType:           WorkloadTypeDeployment,
+			expectedServiceAccount: "default",
+		},
+		"statefulset with service account": {
+			workload:               statefulSetToWorkload(appsv1.StatefulSet{Spec: appsv1.StatefulSetSpec{Template: podSpec("db")}}, false),
+			expectedType:           WorkloadTypeStatefulSet,
+			expectedServiceAccount: "db",
+		},
+		"statefulset without service account": {
+			workload:               statefulSetToWorkload(appsv1.StatefulSet{}, false),
+			expectedType:           WorkloadTypeStatefulSet,
+			expectedServiceAccount: "default",
+		},
+		"daemonset with service account": {
+			workload:               daemonSetToWorkload(appsv1.DaemonSet{Spec: appsv1.DaemonSetSpec{Template: podSpec("agent")}}, false),
+			expectedType:           WorkloadTypeDaemonSet,
+			expectedServiceAccount: "agent",
+		},
+		"daemonset without service account": {
+			workload:               daemonSetToWorkload(appsv1.DaemonSet{}, false),
+			expectedType:           WorkloadTypeDaemonSet,
+			expectedServiceAccount: "default",
+		},
+		"deployment with template labels": {
+			workload: deploymentToWorkload(appsv1.Deployment{Spec: appsv1.DeploymentSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+				Template: corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "web", "tier": "frontend"}}},
+			}}, false),
+			expectedType:           WorkloadTypeDeployment,
+			expectedServiceAccount: "default",
+			expectedLabels:         map[string]string{"app": "web", "tier": "frontend"},
+		},
+		"deployment with labels only on the selector": {
+			workload: deploymentToWorkload(appsv1.Deployment{Spec: appsv1.DeploymentSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			}}, false),
+			expectedType:           WorkloadTypeDeployment,
+			expectedServiceAccount: "default",
+			expectedLabels:         map[string]string{"app": "web"},
+		},
+		"statefulset with labels only on the selector": {
+			workload: statefulSetToWorkload(appsv1.StatefulSet{Spec: appsv1.StatefulSetSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+			}}, false),
+			expectedType:           WorkloadTypeStatefulSet,
+			expectedServiceAccount: "default",
+			expectedLabels:         map[string]string{"app": "db"},
+		},
+		"daemonset with labels only on the selector": {
+			workload: daemonSetToWorkload(appsv1.DaemonSet{Spec: appsv1.DaemonSetSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "agent"}},
+			}}, false),
+			expectedType:           WorkloadTypeDaemonSet,
+			expectedServiceAccount: "default",
+			expectedLabels:         map[string]string{"app": "agent"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if tt.workload.Type != tt.expectedType {
+				t.Errorf("expected type %s, got %s", tt.expectedType, tt.workload.Type)
+			}
+			if tt.workload.ServiceAccount != tt.expectedServiceAccount {
+				t.Errorf("expected service account %q, got %q", tt.expectedServiceAccount, tt.workload.ServiceAccount)
+			}
+			if !maps.Equal(tt.workload.Labels, tt.expectedLabels) {
+				t.Errorf("expected labels %v, got %v", tt.expectedLabels, tt.workload.Labels)
+			}
+		})
+	}
+}
+
+func TestExtractPorts(t *testing.T) {
+	spec := corev1.PodSpec{
+		Containers: []corev1.Container{
+			{Name: "app", Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 8080}}},
+		},
+		InitContainers: []corev1.Container{
+			{Name: "proxy", Ports: []corev1.ContainerPort{
+				{Name: "ready", ContainerPort: 15021},
+				{Name: "http-dup", ContainerPort: 8080, Protocol: corev1.ProtocolTCP},
+				{Name: "dns", ContainerPort: 8080, Protocol: corev1.ProtocolUDP},
+			}},
+		},
+	}
+
+	tests := map[string]struct {
+		initPorts     bool
+		expectedPorts []string
+	}{
+		"regular containers only": {
+			expectedPorts: []string{"http:8080/TCP"},
+		},
+		"with init containers": {
+			initPorts:     true,
+			expectedPorts: []string{"http:8080/TCP", "ready:15021/TCP", "dns:8080/UDP"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var got []string
+			for _, p := range extractPorts(spec, tt.initPorts) {
+				got = append(got, fmt.Sprintf("%s:%d/%s", p.Name, p.ContainerPort, p.Protocol))
+			}
+			if !slices.Equal(got, tt.expectedPorts) {
+				t.Errorf("expected ports %v, got %v", tt.expectedPorts, got)
+			}
+		})
+	}
+}
+
+func TestGetPoliciesIstioWarningLogged(t *testing.T) {
+	istioClientset := istiofake.NewSimpleClientset()
+	istioClientset.PrependReactor("list", "authorizationpolicies", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("the server could not find the requested resource")
+	})
+
+	var logs bytes.Buffer
+	client := NewClientWithInterface(fake.NewSimpleClientset(), istioClientset).
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+
+	if _, err := client.GetPolicies([]string{"apps"}); err != nil {
+		t.Fatalf("expected Istio errors to be non-fatal, got %v", err)
+	}
+	for _, substr := range []string{"level=WARN", "failed to list Istio AuthorizationPolicies", "namespace=apps"} {
+		if !strings.Contains(logs.String(), substr) {
+			t.Errorf("expected log to contain %q, got %q", substr, logs.String())
+		}
+	}
+}
+
+func TestGetHTTPRoutes(t *testing.T) {
+	route := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "gateway.networking.k8s.io/v1",
+		"kind":       "HTTPRoute",
+		"metadata":   map[string]any{"name": "web", "namespace": "apps"},
+		"spec": map[string]any{
+			"parentRefs": []any{map[string]any{"name": "public", "namespace": "gateways"}},
+			"hostnames":  []any{"web.example.com"},
+			"rules": []any{map[string]any{
+				"backendRefs": []any{map[string]any{"name": "web", "port": int64(80)}},
+			}},
+		},
+	}}
+	other := route.DeepCopy()
+	other.SetNamespace("other")
+
+	scheme := runtime.NewScheme()
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(scheme,
+		map[schema.GroupVersionResource]string{HTTPRouteGVR: "HTTPRouteList"}, route, other)
+	client := NewClientWithInterface(fake.NewSimpleClientset(), nil).WithDynamicClient(dynamicClient)
+
+	policies, err := client.GetHTTPRoutes([]string{"apps"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(policies) != 1 {
+		t.Fatalf("expected 1 route, got %d", len(policies))
+	}
+	p := policies[0]
+	if p.Type != PolicyTypeHTTPRoute || p.Name != "web" || p.Namespace != "apps" || p.HTTPRoute == nil {
+		t.Fatalf("unexpected policy: %+v", p)
+	}
+	spec := p.HTTPRoute.Spec
+	if len(spec.ParentRefs) != 1 || spec.ParentRefs[0].Name != "public" || spec.ParentRefs[0].Namespace != "gateways" {
+		t.Errorf("unexpected parentRefs: %+v", spec.ParentRefs)
+	}
+	if len(spec.Rules) != 1 || len(spec.Rules[0].BackendRefs) != 1 || spec.Rules[0].BackendRefs[0].Port != 80 {
+		t.Errorf("unexpected rules: %+v", spec.Rules)
+	}
+
+	// Without a dynamic client, e.g. in tests, routes are skipped
+	policies, err = NewClientWithInterface(fake.NewSimpleClientset(), nil).GetHTTPRoutes([]string{"apps"})
+	if err != nil || len(policies) != 0 {
+		t.Errorf("expected no routes without a dynamic client, got %v, %v", policies, err)
+	}
+}
+
+func TestGetCiliumNetworkPolicies(t *testing.T) {
+	cnp := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "cilium.io/v2",
+		"kind":       "CiliumNetworkPolicy",
+		"metadata":   map[string]any{"name": "api", "namespace": "apps"},
+		"spec": map[string]any{
+			"endpointSelector": map[string]any{"matchLabels": map[string]any{"app": "api"}},
+			"ingress": []any{map[string]any{
+				"fromEndpoints": []any{map[string]any{"matchLabels": map[string]any{"k8s:app": "web"}}},
+				"toPorts": []any{map[string]any{
+					"ports": []any{map[string]any{"port": "8080", "protocol": "TCP"}},
+					"rules": map[string]any{"http": []any{map[string]any{"method": "GET"}}},
+				}},
+			}},
+		},
+	}}
+	other := cnp.DeepCopy()
+	other.SetNamespace("other")
+
+	scheme := runtime.NewScheme()
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(scheme,
+		map[schema.GroupVersionResource]string{CiliumNetworkPolicyGVR: "CiliumNetworkPolicyList"}, cnp, other)
+	client := NewClientWithInterface(fake.NewSimpleClientset(), nil).WithDynamicClient(dynamicClient)
+
+	policies, err := client.GetCiliumNetworkPolicies([]string{"apps"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(policies) != 1 {
+		t.Fatalf("expected 1 policy, got %d", len(policies))
+	}
+	p := policies[0]
+	if p.Type != PolicyTypeCiliumNetworkPolicy || p.Name != "api" || p.Namespace != "apps" || p.CiliumNetworkPolicy == nil {
+		t.Fatalf("unexpected policy: %+v", p)
+	}
+	rules := p.CiliumNetworkPolicy.Rules()
+	if len(rules) != 1 || rules[0].EndpointSelector.MatchLabels["app"] != "api" {
+		t.Fatalf("unexpected rules: %+v", rules)
+	}
+	if len(rules[0].Ingress) != 1 || len(rules[0].Ingress[0].ToPorts) != 1 {
+		t.Fatalf("unexpected ingress: %+v", rules[0].Ingress)
+	}
+	portRule := rules[0].Ingress[0].ToPorts[0]
+	if len(portRule.Ports) != 1 || portRule.Ports[0].Port != "8080" || portRule.Rules.Empty() {
+		t.Errorf("unexpected port rule: %+v", portRule)
+	}
+
+	// Without a dynamic client, e.g. in tests, policies are skipped
+	policies, err = NewClientWithInterface(fake.NewSimpleClientset(), nil).GetCiliumNetworkPolicies([]string{"apps"})
+	if err != nil || len(policies) != 0 {
+		t.Errorf("expected no policies without a dynamic client, got %v, %v", policies, err)
+	}
+}
+
+func TestGetWorkloadsServices(t *testing.T) {
+	client := NewClientWithInterface(fake.NewSimpleClientset(
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "apps"},
+			Spec: corev1.ServiceSpec{
+				Selector: map[string]string{"app": "api"},
+				Ports: []corev1.ServicePort{
+					{Name: "http", Port: 80, Protocol: corev1.ProtocolTCP, TargetPort: intstr.FromString("http")},
+				},
+			},
+		},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "elsewhere", Namespace: "other"}},
+	), nil)
+
+	_, services, err := client.GetWorkloads([]string{"apps"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(services) != 1 {
+		t.Fatalf("expected 1 service, got %d", len(services))
+	}
+
+	svc := services[0]
+	if svc.Name != "api" || svc.Namespace != "apps" || svc.Selector["app"] != "api" {
+		t.Errorf("unexpected service %+v", svc)
+	}
+	if len(svc.Ports) != 1 || svc.Ports[0].Port != 80 || svc.Ports[0].TargetPort.StrVal != "http" {
+		t.Errorf("unexpected service ports %+v", svc.Ports)
+	}
+}
+
+func TestListAllNamespaces(t *testing.T) {
+	client := NewClientWithInterface(fake.NewSimpleClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "zeta"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "alpha"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system"}},
+	), nil)
+
+	namespaces, err := client.ListAllNamespaces()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"alpha", "kube-system", "zeta"}
+	if len(namespaces) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, namespaces)
+	}
+	for i, ns := range namespaces {
+		if ns != expected[i] {
+			t.Errorf("expected %s at %d, got %s", expected[i], i, ns)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package k8s
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// HTTPRouteGVR identifies Gateway API HTTPRoutes for the dynamic client.
+var HTTPRouteGVR = schema.GroupVersionResource{
+	Group:    "gateway.networking.k8s.io",
+	Version:  "v1",
+	Resource: "httproutes",
+}
+
+// HTTPRoute is the subset of a Gateway API HTTPRoute dnmap graphs: the Gateways it
+// attaches to and the backends its rules forward to. It is decoded from unstructured
+// objects so the Gateway API module isn't a dependency.
+type HTTPRoute struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              HTTPRouteSpec `json:"spec"`
+}
+
+// HTTPRouteSpec holds an HTTPRoute's parent Gateways, hostnames and rules.
+type HTTPRouteSpec struct {
+	ParentRefs []ParentReference `json:"parentRefs,omitempty"`
+	Hostnames  []string          `json:"hostnames,omitempty"`
+	Rules      []HTTPRouteRule   `json:"rules,omitempty"`
+}
+
+// ParentReference names a Gateway an HTTPRoute attaches to. Empty Group and Kind mean
+// gateway.networking.k8s.io Gateway; an empty Namespace means the route's namespace.
+type ParentReference struct {
+	Group       string `json:"group,omitempty"`
+	Kind        string `json:"kind,omitempty"`
+	Namespace   string `json:"namespace,omitempty"`
+	Name        string `json:"name"`
+	SectionName string `json:"sectionName,omitempty"`
+}
+
+// HTTPRouteRule lists the backends matching requests are forwarded to.
+type HTTPRouteRule struct {
+	BackendRefs []BackendRef `json:"backendRefs,omitempty"`
+}
+
+// BackendRef names a route backend. Empty Group and Kind mean a core Service; an empty
+// Namespace means the route's namespace. Port is the Service port.
+type BackendRef struct {
+	Group     string `json:"group,omitempty"`
+	Kind      string `json:"kind,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Port      int32  `json:"port,omitempty"`
+}
+
+// GetHTTPRoutes fetches Gateway API HTTPRoutes from the specified namespaces. Like Istio
+// policies, they are skipped with a warning when the Gateway API CRDs aren't installed.
+func (c *Client) GetHTTPRoutes(namespaces []string) ([]Policy, error) {
+	var policies []Policy
+
+	if c.dynamicClient == nil {
+		return policies, nil
+	}
+
+	for _, ns := range namespaces {
+		routes, err := withRetry(c, func(ctx context.Context) (*unstructured.UnstructuredList, error) {
+			return c.dynamicClient.Resource(HTTPRouteGVR).Namespace(ns).List(ctx, metav1.ListOptions{})
+		})
+		if err != nil {
+			c.log().Warn("failed to list Gateway API HTTPRoutes", "namespace", ns, "error", err)
+			continue
+		}
+		for i := range routes.Items {
+			route := &HTTPRoute{}
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(routes.Items[i].Object, route); err != nil {
+				c.log().Warn("failed to decode Gateway API HTTPRoute", "namespace", ns, "name", routes.Items[i].GetName(), "error", err)
+				continue
+			}
+			policies = append(policies, Policy{
+				Name:      route.Name,
+				Namespace: route.Namespace,
+				Type:      PolicyTypeHTTPRoute,
+				HTTPRoute: route,
+			})
+		}
+	}
+
+	return policies, nil
+}
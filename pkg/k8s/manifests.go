@@ -0,0 +1,289 @@
+package k8s
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+
+	securityclientv1beta1 "istio.io/client-go/pkg/apis/security/v1beta1"
+	istioscheme "istio.io/client-go/pkg/clientset/versioned/scheme"
+)
+
+// manifestDecoder decodes core Kubernetes and Istio objects from YAML or JSON.
+var manifestDecoder = func() runtime.Decoder {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(istioscheme.AddToScheme(scheme))
+	return serializer.NewCodecFactory(scheme).UniversalDeserializer()
+}()
+
+// ManifestOptions tunes how LoadFromManifests reads workloads, like the matching Client
+// options do for clusters.
+type ManifestOptions struct {
+	// InitContainerPorts also collects the ports declared on init containers; see
+	// Client.WithInitContainerPorts.
+	InitContainerPorts bool
+	// LabelSelector keeps only the workloads whose own labels match, in Kubernetes
+	// label-selector syntax; see Client.WithLabelSelector.
+	LabelSelector string
+	// RespectIgnoreAnnotation marks workloads annotated with IgnoreAnnotation, directly or
+	// through their Namespace object, as Ignored; see Client.WithIgnoreAnnotation.
+	RespectIgnoreAnnotation bool
+	// ExcludeNamespaces drops every object in these namespaces.
+	ExcludeNamespaces []string
+}
+
+// manifestLoader accumulates the objects decoded from manifest files.
+type manifestLoader struct {
+	opts              ManifestOptions
+	selector          labels.Selector // parsed opts.LabelSelector
+	workloads         []Workload
+	policies          []Policy
+	namespaces        map[string]map[string]string // namespace name -> labels
+	ignoredNamespaces map[string]bool              // namespaces whose Namespace object carries IgnoreAnnotation
+	services          map[string][]corev1.Service  // namespace name -> services
+}
+
+// LoadFromManifests reads workloads, policies, namespaces and Services from YAML or JSON
+// manifests instead of a live cluster, filtered by opts as a cluster scan would be. Each
+// path may be a file or a directory, which is walked for .yaml, .yml and .json files. Files
+// may hold several documents or a v1 List; objects of kinds dnmap doesn't graph are
+// skipped. Objects without a namespace are placed in "default".
+func LoadFromManifests(paths []string, opts ManifestOptions) (*Snapshot, error) {
+	selector, err := labels.Parse(opts.LabelSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid label selector %q: %w", opts.LabelSelector, err)
+	}
+	loader := &manifestLoader{
+		opts:              opts,
+		selector:          selector,
+		namespaces:        make(map[string]map[string]string),
+		ignoredNamespaces: make(map[string]bool),
+		services:          make(map[string][]corev1.Service),
+	}
+
+	for _, path := range paths {
+		files, err := manifestFiles(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			if err := loader.loadFile(file); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	excluded := func(ns string) bool { return slices.Contains(opts.ExcludeNamespaces, ns) }
+	snapshot := &Snapshot{}
+
+	// Match ports to Services once every file is read, as GetWorkloads does per namespace
+	for _, w := range loader.workloads {
+		if excluded(w.Namespace) {
+			continue
+		}
+		enrichPortsWithServices(&w, loader.services[w.Namespace])
+		w.Ignored = w.Ignored || loader.ignoredNamespaces[w.Namespace]
+		snapshot.Workloads = append(snapshot.Workloads, w)
+	}
+	for _, p := range loader.policies {
+		if !excluded(p.Namespace) {
+			snapshot.Policies = append(snapshot.Policies, p)
+		}
+	}
+
+	names := make([]string, 0, len(loader.namespaces))
+	for name := range loader.namespaces {
+		if !excluded(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		snapshot.Namespaces = append(snapshot.Namespaces, NamespaceInfo{Name: name, Labels: loader.namespaces[name]})
+		for _, svc := range loader.services[name] {
+			snapshot.Services = append(snapshot.Services, serviceInfo(svc))
+		}
+	}
+
+	return snapshot, nil
+}
+
+// manifestFiles expands path into the manifest files it names, in lexical order.
+func manifestFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifests: %w", err)
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		switch strings.ToLower(filepath.Ext(p)) {
+		case ".yaml", ".yml", ".json":
+			if !d.IsDir() {
+				files = append(files, p)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk manifest directory %s: %w", path, err)
+	}
+	return files, nil
+}
+
+// loadFile decodes every document in a manifest file.
+func (l *manifestLoader) loadFile(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("failed to open manifest: %w", err)
+	}
+	defer f.Close()
+
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(f))
+	for {
+		doc, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read manifest %s: %w", file, err)
+		}
+		if err := l.loadDocument(file, doc); err != nil {
+			return err
+		}
+	}
+}
+
+// loadDocument decodes a single YAML or JSON document and records the object it holds.
+func (l *manifestLoader) loadDocument(file string, doc []byte) error {
+	if len(bytes.TrimSpace(doc)) == 0 {
+		return nil
+	}
+
+	obj, _, err := manifestDecoder.Decode(doc, nil, nil)
+	if err != nil {
+		// Unknown kinds and kind-less documents (e.g. comment-only) aren't graphed
+		if runtime.IsNotRegisteredError(err) || runtime.IsMissingKind(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to decode manifest %s: %w", file, err)
+	}
+
+	return l.add(file, obj)
+}
+
+// add records a decoded object; kinds that aren't graphed are ignored.
+func (l *manifestLoader) add(file string, obj runtime.Object) error {
+	switch o := obj.(type) {
+	case *corev1.List:
+		for _, item := range o.Items {
+			if err := l.loadDocument(file, item.Raw); err != nil {
+				return err
+			}
+		}
+	case *corev1.Namespace:
+		l.namespaces[o.Name] = o.Labels
+		l.ignoredNamespaces[o.Name] = l.ignored(o.ObjectMeta)
+	case *corev1.Service:
+		ns := l.namespace(&o.Namespace)
+		l.services[ns] = append(l.services[ns], *o)
+	case *appsv1.Deployment:
+		l.namespace(&o.Namespace)
+		l.addWorkload(o.ObjectMeta, deploymentToWorkload(*o, l.opts.InitContainerPorts))
+	case *appsv1.StatefulSet:
+		l.namespace(&o.Namespace)
+		l.addWorkload(o.ObjectMeta, statefulSetToWorkload(*o, l.opts.InitContainerPorts))
+	case *appsv1.DaemonSet:
+		l.namespace(&o.Namespace)
+		l.addWorkload(o.ObjectMeta, daemonSetToWorkload(*o, l.opts.InitContainerPorts))
+	case *networkingv1.NetworkPolicy:
+		l.namespace(&o.Namespace)
+		l.policies = append(l.policies, Policy{
+			Name:             o.Name,
+			Namespace:        o.Namespace,
+			Type:             PolicyTypeK8sNetworkPolicy,
+			K8sNetworkPolicy: o,
+			SourceFile:       file,
+		})
+	case *IstioAuthorizationPolicy:
+		l.namespace(&o.Namespace)
+		l.policies = append(l.policies, Policy{
+			Name:            o.Name,
+			Namespace:       o.Namespace,
+			Type:            PolicyTypeIstioAuthorizationPolicy,
+			IstioAuthPolicy: o,
+			SourceFile:      file,
+		})
+	case *IstioPeerAuthentication:
+		l.namespace(&o.Namespace)
+		l.policies = append(l.policies, Policy{
+			Name:          o.Name,
+			Namespace:     o.Namespace,
+			Type:          PolicyTypeIstioPeerAuthentication,
+			IstioPeerAuth: o,
+			SourceFile:    file,
+		})
+	case *securityclientv1beta1.AuthorizationPolicy:
+		// v1beta1 shares the v1 spec; convert so the builder sees a single type
+		ap := &IstioAuthorizationPolicy{ObjectMeta: *o.ObjectMeta.DeepCopy()}
+		o.Spec.DeepCopyInto(&ap.Spec)
+		return l.add(file, ap)
+	case *securityclientv1beta1.PeerAuthentication:
+		pa := &IstioPeerAuthentication{ObjectMeta: *o.ObjectMeta.DeepCopy()}
+		o.Spec.DeepCopyInto(&pa.Spec)
+		return l.add(file, pa)
+	}
+	return nil
+}
+
+// addWorkload records w unless the label selector excludes it. Like the API server's list
+// filtering, the selector matches the object's own labels, not its pod template's.
+func (l *manifestLoader) addWorkload(meta metav1.ObjectMeta, w Workload) {
+	if !l.selector.Matches(labels.Set(meta.Labels)) {
+		return
+	}
+	w.Ignored = l.ignored(meta)
+	l.workloads = append(l.workloads, w)
+}
+
+// ignored reports whether the object opts out of the map through IgnoreAnnotation.
+func (l *manifestLoader) ignored(meta metav1.ObjectMeta) bool {
+	return l.opts.RespectIgnoreAnnotation && meta.Annotations[IgnoreAnnotation] == "true"
+}
+
+// namespace defaults an empty namespace to "default" and records it as seen.
+func (l *manifestLoader) namespace(ns *string) string {
+	if *ns == "" {
+		*ns = corev1.NamespaceDefault
+	}
+	if _, ok := l.namespaces[*ns]; !ok {
+		l.namespaces[*ns] = nil
+	}
+	return *ns
+}
@@ -0,0 +1,373 @@
+package k8s
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+const testManifests = `# leading comment-only document
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: apps
+  labels:
+    team: ml
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: apps
+spec:
+  selector:
+    app: web
+  ports:
+    - name: http
+      port: 80
+      targetPort: 8080
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: apps
+spec:
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+        - name: web
+          image: web
+          ports:
+            - name: http
+              containerPort: 8080
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: unknown
+---
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: allow-web
+  namespace: apps
+spec:
+  podSelector:
+    matchLabels:
+      app: web
+`
+
+const testIstioManifests = `apiVersion: security.istio.io/v1beta1
+kind: AuthorizationPolicy
+metadata:
+  name: allow-api
+spec:
+  selector:
+    matchLabels:
+      app: api
+  rules:
+    - to:
+        - operation:
+            ports: ["9090"]
+---
+apiVersion: v1
+kind: List
+items:
+  - apiVersion: apps/v1
+    kind: StatefulSet
+    metadata:
+      name: api
+    spec:
+      selector:
+        matchLabels:
+          app: api
+      template:
+        metadata:
+          labels:
+            app: api
+        spec:
+          containers:
+            - name: api
+              image: api
+`
+
+func TestLoadFromManifests(t *testing.T) {
+	dir := t.TempDir()
+	appsFile := filepath.Join(dir, "apps.yaml")
+	istioFile := filepath.Join(dir, "nested", "istio.yml")
+	if err := os.MkdirAll(filepath.Dir(istioFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for file, content := range map[string]string{
+		appsFile:                         testManifests,
+		istioFile:                        testIstioManifests,
+		filepath.Join(dir, "README.txt"): "not a manifest",
+	} {
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	snapshot, err := LoadFromManifests([]string{dir}, ManifestOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	workloads, policies, namespaces := snapshot.Workloads, snapshot.Policies, snapshot.Namespaces
+
+	if len(workloads) != 2 {
+		t.Fatalf("expected 2 workloads, got %d", len(workloads))
+	}
+	web := workloads[0]
+	if web.Name != "web" || web.Type != WorkloadTypeDeployment || web.Namespace != "apps" {
+		t.Errorf("unexpected first workload: %+v", web)
+	}
+	if len(web.Ports) != 1 || web.Ports[0].ServiceName != "web" || web.Ports[0].ServicePort != 80 {
+		t.Errorf("expected port enriched with service web:80, got %+v", web.Ports)
+	}
+	api := workloads[1]
+	if api.Name != "api" || api.Type != WorkloadTypeStatefulSet || api.Namespace != "default" {
+		t.Errorf("expected List item api in default namespace, got %+v", api)
+	}
+
+	expectedPolicies := map[string]struct {
+		policyType PolicyType
+		sourceFile string
+	}{
+		"allow-web": {PolicyTypeK8sNetworkPolicy, appsFile},
+		"allow-api": {PolicyTypeIstioAuthorizationPolicy, istioFile},
+	}
+	if len(policies) != len(expectedPolicies) {
+		t.Fatalf("expected %d policies, got %d", len(expectedPolicies), len(policies))
+	}
+	for _, p := range policies {
+		expected := expectedPolicies[p.Name]
+		if p.Type != expected.policyType {
+			t.Errorf("policy %s: expected type %s, got %s", p.Name, expected.policyType, p.Type)
+		}
+		if p.SourceFile != expected.sourceFile {
+			t.Errorf("policy %s: expected source file %s, got %s", p.Name, expected.sourceFile, p.SourceFile)
+		}
+	}
+	if ap := policies[1].IstioAuthPolicy; ap == nil || ap.Namespace != "default" || len(ap.Spec.Rules) != 1 {
+		t.Errorf("expected v1beta1 AuthorizationPolicy converted with its rules, got %+v", ap)
+	}
+
+	if len(namespaces) != 2 || namespaces[0].Name != "apps" || namespaces[1].Name != "default" {
+		t.Fatalf("expected namespaces [apps default], got %+v", namespaces)
+	}
+	if namespaces[0].Labels["team"] != "ml" {
+		t.Errorf("expected apps namespace labels from manifest, got %v", namespaces[0].Labels)
+	}
+	if len(snapshot.Services) != 1 || snapshot.Services[0].Name != "web" || snapshot.Services[0].Ports[0].TargetPort.IntVal != 8080 {
+		t.Errorf("expected the web service with its port mapping, got %+v", snapshot.Services)
+	}
+}
+
+func TestLoadFromManifestsErrors(t *testing.T) {
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid.yaml")
+	if err := os.WriteFile(invalid, []byte("apiVersion: apps/v1\nkind: Deployment\nspec: [not, a, map]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		paths []string
+		opts  ManifestOptions
+	}{
+		"missing path": {
+			paths: []string{filepath.Join(dir, "missing")},
+		},
+		"invalid object": {
+			paths: []string{invalid},
+		},
+		"invalid label selector": {
+			paths: []string{dir},
+			opts:  ManifestOptions{LabelSelector: "app in (web"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := LoadFromManifests(tt.paths, tt.opts); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestLoadFromManifestsInitContainerPorts(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "web.yaml")
+	manifest := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      initContainers:
+        - name: proxy
+          image: proxy
+          restartPolicy: Always
+          ports:
+            - name: admin
+              containerPort: 15000
+      containers:
+        - name: web
+          image: web
+          ports:
+            - name: http
+              containerPort: 8080
+`
+	if err := os.WriteFile(file, []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		opts          ManifestOptions
+		expectedPorts int
+	}{
+		"init container ports off": {
+			expectedPorts: 1,
+		},
+		"init container ports on": {
+			opts:          ManifestOptions{InitContainerPorts: true},
+			expectedPorts: 2,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			snapshot, err := LoadFromManifests([]string{file}, tt.opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(snapshot.Workloads) != 1 || len(snapshot.Workloads[0].Ports) != tt.expectedPorts {
+				t.Errorf("expected one workload with %d ports, got %+v", tt.expectedPorts, snapshot.Workloads)
+			}
+		})
+	}
+}
+
+func TestLoadFromManifestsFilters(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "apps.yaml")
+	deployment := func(name, namespace, team, annotation string) string {
+		return `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: ` + name + `
+  namespace: ` + namespace + `
+  labels:
+    team: ` + team + `
+  annotations:
+    dnmap.io/ignore: "` + annotation + `"
+spec:
+  selector:
+    matchLabels:
+      app: ` + name + `
+  template:
+    metadata:
+      labels:
+        app: ` + name + `
+    spec:
+      containers:
+        - name: ` + name + `
+          image: ` + name + `
+`
+	}
+	manifest := `apiVersion: v1
+kind: Namespace
+metadata:
+  name: legacy
+  annotations:
+    dnmap.io/ignore: "true"
+---
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: deny-all
+  namespace: scratch
+spec:
+  podSelector: {}
+---
+` + deployment("web", "apps", "web", "false") + "---\n" +
+		deployment("batch", "apps", "data", "true") + "---\n" +
+		deployment("old", "legacy", "web", "false") + "---\n" +
+		deployment("tmp", "scratch", "web", "false")
+	if err := os.WriteFile(file, []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		opts               ManifestOptions
+		expectedWorkloads  []string // namespace/name, with "!" appended when Ignored
+		expectedNamespaces []string
+		expectedPolicies   int
+	}{
+		"no filters": {
+			expectedWorkloads:  []string{"apps/web", "apps/batch", "legacy/old", "scratch/tmp"},
+			expectedNamespaces: []string{"apps", "legacy", "scratch"},
+			expectedPolicies:   1,
+		},
+		"label selector": {
+			opts:               ManifestOptions{LabelSelector: "team=web"},
+			expectedWorkloads:  []string{"apps/web", "legacy/old", "scratch/tmp"},
+			expectedNamespaces: []string{"apps", "legacy", "scratch"},
+			expectedPolicies:   1,
+		},
+		"ignore annotation": {
+			opts:               ManifestOptions{RespectIgnoreAnnotation: true},
+			expectedWorkloads:  []string{"apps/web", "apps/batch!", "legacy/old!", "scratch/tmp"},
+			expectedNamespaces: []string{"apps", "legacy", "scratch"},
+			expectedPolicies:   1,
+		},
+		"excluded namespaces": {
+			opts:               ManifestOptions{ExcludeNamespaces: []string{"scratch", "legacy"}},
+			expectedWorkloads:  []string{"apps/web", "apps/batch"},
+			expectedNamespaces: []string{"apps"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			snapshot, err := LoadFromManifests([]string{file}, tt.opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var workloads, namespaces []string
+			for _, w := range snapshot.Workloads {
+				id := w.Namespace + "/" + w.Name
+				if w.Ignored {
+					id += "!"
+				}
+				workloads = append(workloads, id)
+			}
+			for _, ns := range snapshot.Namespaces {
+				namespaces = append(namespaces, ns.Name)
+			}
+			if !slices.Equal(workloads, tt.expectedWorkloads) {
+				t.Errorf("expected workloads %v, got %v", tt.expectedWorkloads, workloads)
+			}
+			if !slices.Equal(namespaces, tt.expectedNamespaces) {
+				t.Errorf("expected namespaces %v, got %v", tt.expectedNamespaces, namespaces)
+			}
+			if len(snapshot.Policies) != tt.expectedPolicies {
+				t.Errorf("expected %d policies, got %d", tt.expectedPolicies, len(snapshot.Policies))
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	istioinformers "istio.io/client-go/pkg/informers/externalversions"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+// watchSyncTimeout bounds how long Watch waits for its informers' initial lists.
+var watchSyncTimeout = time.Minute
+
+// Watch starts informers on the Deployments, StatefulSets, DaemonSets, NetworkPolicies and
+// AuthorizationPolicies of namespaces, or of every namespace when namespaces is empty, and
+// calls onChange for each change seen after the initial sync. Updates that leave an object's
+// generation, labels and annotations unchanged, such as status updates, are ignored.
+//
+// Watch returns once the informers have synced; they run until ctx is done. If the client
+// may not list or watch one of the resources, Watch stops them and returns an error so the
+// caller can fall back to polling. AuthorizationPolicies are skipped with a warning when
+// Istio isn't installed.
+func (c *Client) Watch(ctx context.Context, namespaces []string, onChange func()) error {
+	if len(namespaces) == 0 {
+		namespaces = []string{metav1.NamespaceAll}
+	}
+
+	// The informers run until ctx is done, or until Watch fails
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+	stopInformers := func() { stopOnce.Do(func() { close(stop) }) }
+	go func() {
+		<-ctx.Done()
+		stopInformers()
+	}()
+
+	syncCtx, cancelSync := context.WithTimeout(ctx, watchSyncTimeout)
+	defer cancelSync()
+
+	// Access errors end the wait for the sync instead of being retried forever
+	var mu sync.Mutex
+	var deniedErr error
+	onWatchError := func(ctx context.Context, r *cache.Reflector, err error) {
+		if apierrors.IsForbidden(err) || apierrors.IsUnauthorized(err) {
+			mu.Lock()
+			if deniedErr == nil {
+				deniedErr = err
+			}
+			mu.Unlock()
+			cancelSync()
+		}
+		cache.DefaultWatchErrorHandler(ctx, r, err)
+	}
+
+	// Events for the initial lists aren't changes
+	var live atomic.Bool
+	changed := func() {
+		if live.Load() {
+			onChange()
+		}
+	}
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc: func(any) { changed() },
+		UpdateFunc: func(oldObj, newObj any) {
+			if specChanged(oldObj, newObj) {
+				changed()
+			}
+		},
+		DeleteFunc: func(any) { changed() },
+	}
+
+	watchIstio := c.istioClientset != nil
+	if watchIstio {
+		_, err := withRetry(c, func(ctx context.Context) (any, error) {
+			return c.istioClientset.SecurityV1().AuthorizationPolicies(namespaces[0]).List(ctx, metav1.ListOptions{Limit: 1})
+		})
+		if err != nil {
+			c.log().Warn("not watching Istio AuthorizationPolicies", "error", err)
+			watchIstio = false
+		}
+	}
+
+	var hasSynced []cache.InformerSynced
+	register := func(informer cache.SharedIndexInformer) error {
+		if err := informer.SetWatchErrorHandlerWithContext(onWatchError); err != nil {
+			return err
+		}
+		if _, err := informer.AddEventHandler(handler); err != nil {
+			return err
+		}
+		hasSynced = append(hasSynced, informer.HasSynced)
+		return nil
+	}
+
+	for _, ns := range namespaces {
+		factory := informers.NewSharedInformerFactoryWithOptions(c.k8sClientset, 0, informers.WithNamespace(ns))
+		for _, informer := range []cache.SharedIndexInformer{
+			factory.Apps().V1().Deployments().Informer(),
+			factory.Apps().V1().StatefulSets().Informer(),
+			factory.Apps().V1().DaemonSets().Informer(),
+			factory.Networking().V1().NetworkPolicies().Informer(),
+		} {
+			if err := register(informer); err != nil {
+				stopInformers()
+				return fmt.Errorf("failed to register informer: %w", err)
+			}
+		}
+		factory.Start(stop)
+
+		if watchIstio {
+			istioFactory := istioinformers.NewSharedInformerFactoryWithOptions(c.istioClientset, 0, istioinformers.WithNamespace(ns))
+			if err := register(istioFactory.Security().V1().AuthorizationPolicies().Informer()); err != nil {
+				stopInformers()
+				return fmt.Errorf("failed to register informer: %w", err)
+			}
+			istioFactory.Start(stop)
+		}
+	}
+
+	ok := cache.WaitForCacheSync(syncCtx.Done(), hasSynced...)
+	mu.Lock()
+	err := deniedErr
+	mu.Unlock()
+	if err != nil {
+		stopInformers()
+		return fmt.Errorf("failed to watch resources: %w", err)
+	}
+	if !ok {
+		stopInformers()
+		return fmt.Errorf("failed to watch resources: informers didn't sync within %s", watchSyncTimeout)
+	}
+	live.Store(true)
+	return nil
+}
+
+// specChanged reports whether an update may change the map: a new generation (spec change)
+// or different labels or annotations.
+func specChanged(oldObj, newObj any) bool {
+	oldMeta, okOld := oldObj.(metav1.Object)
+	newMeta, okNew := newObj.(metav1.Object)
+	if !okOld || !okNew {
+		return true
+	}
+	return oldMeta.GetGeneration() != newMeta.GetGeneration() ||
+		!maps.Equal(oldMeta.GetLabels(), newMeta.GetLabels()) ||
+		!maps.Equal(oldMeta.GetAnnotations(), newMeta.GetAnnotations())
+}
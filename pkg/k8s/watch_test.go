@@ -0,0 +1,97 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestWatchReportsChanges(t *testing.T) {
+	clientset := fake.NewClientset(&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "apps"}})
+	client := NewClientWithInterface(clientset, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan struct{}, 10)
+	if err := client.Watch(ctx, []string{"apps"}, func() { changes <- struct{}{} }); err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+
+	// The existing Deployment is part of the initial sync, not a change
+	select {
+	case <-changes:
+		t.Fatal("expected no change before anything was modified")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	policy := &networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: "allow-web", Namespace: "apps"}}
+	if _, err := clientset.NetworkingV1().NetworkPolicies("apps").Create(ctx, policy, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a change after creating a NetworkPolicy")
+	}
+}
+
+func TestWatchDenied(t *testing.T) {
+	clientset := fake.NewClientset()
+	clientset.PrependReactor("list", "networkpolicies", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(schema.GroupResource{Group: "networking.k8s.io", Resource: "networkpolicies"}, "", nil)
+	})
+	client := NewClientWithInterface(clientset, nil)
+
+	if err := client.Watch(context.Background(), []string{"apps"}, func() {}); err == nil || !apierrors.IsForbidden(err) {
+		t.Errorf("expected a forbidden error, got %v", err)
+	}
+}
+
+func TestSpecChanged(t *testing.T) {
+	deployment := func(generation int64, labels map[string]string) *appsv1.Deployment {
+		return &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Generation: generation, Labels: labels}}
+	}
+
+	tests := map[string]struct {
+		oldObj   any
+		newObj   any
+		expected bool
+	}{
+		"status only": {
+			oldObj: deployment(1, map[string]string{"app": "web"}),
+			newObj: deployment(1, map[string]string{"app": "web"}),
+		},
+		"new generation": {
+			oldObj:   deployment(1, nil),
+			newObj:   deployment(2, nil),
+			expected: true,
+		},
+		"new labels": {
+			oldObj:   deployment(1, map[string]string{"app": "web"}),
+			newObj:   deployment(1, map[string]string{"app": "web", "team": "ml"}),
+			expected: true,
+		},
+		"not an object": {
+			oldObj:   "web",
+			newObj:   "web",
+			expected: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := specChanged(tt.oldObj, tt.newObj); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
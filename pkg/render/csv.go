@@ -0,0 +1,76 @@
+package render
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+
+	"github.com/ddl-r-abdulaziz/dnmap/pkg/graph"
+)
+
+// EdgesCSVRenderer renders every allowed connection in a graph as CSV rows for spreadsheet review.
+type EdgesCSVRenderer struct{}
+
+// NewEdgesCSVRenderer creates a new edges CSV renderer.
+func NewEdgesCSVRenderer() *EdgesCSVRenderer {
+	return &EdgesCSVRenderer{}
+}
+
+// EdgesCSVHeader lists the columns written by EdgesCSVRenderer.
+var EdgesCSVHeader = []string{"Source", "Target", "Protocol", "Port", "Policy", "Direction", "RuleType"}
+
+// ContentType returns the CSV MIME type.
+func (r *EdgesCSVRenderer) ContentType() string {
+	return "text/csv; charset=utf-8"
+}
+
+// FileExtension returns ".csv".
+func (r *EdgesCSVRenderer) FileExtension() string {
+	return ".csv"
+}
+
+// Render converts the graph's edges to CSV with an EdgesCSVHeader header row.
+func (r *EdgesCSVRenderer) Render(g *graph.NetworkGraph) (string, error) {
+	var buf bytes.Buffer
+	csvWriter := csv.NewWriter(&buf)
+
+	csvWriter.Write(EdgesCSVHeader)
+	for _, e := range g.Edges {
+		protocol, port := parsePortID(e.Target)
+		direction := e.Direction
+		if direction == "" {
+			direction = graph.DirectionIngress
+		}
+		// Istio edges carry the policy action instead of a rule type
+		ruleType := e.Metadata["ruleType"]
+		if ruleType == "" {
+			ruleType = e.Metadata["action"]
+		}
+		// A connection granted by several policies lists them all
+		policy := e.Policy
+		if len(e.Policies) > 1 {
+			policy = strings.Join(e.Policies, ";")
+		}
+		csvWriter.Write([]string{e.Source, e.Target, protocol, port, policy, direction, ruleType})
+	}
+
+	csvWriter.Flush()
+	if err := csvWriter.Error(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// parsePortID extracts the protocol and port from a port node ID built by graph.PortID
+// ("<workload>:<protocol>/<port>"). Both are empty for IDs of another shape.
+func parsePortID(id string) (protocol, port string) {
+	idx := strings.LastIndex(id, ":")
+	if idx < 0 {
+		return "", ""
+	}
+	protocol, port, ok := strings.Cut(id[idx+1:], "/")
+	if !ok {
+		return "", ""
+	}
+	return protocol, port
+}
@@ -0,0 +1,61 @@
+package render
+
+import (
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/ddl-r-abdulaziz/dnmap/pkg/graph"
+)
+
+func TestEdgesCSVRendererRender(t *testing.T) {
+	g := &graph.NetworkGraph{
+		Edges: []graph.Edge{
+			{
+				Source:   "default/frontend",
+				Target:   "default/backend:TCP/8080",
+				Policy:   "default/allow-frontend",
+				Policies: []string{"default/allow-frontend", "default/allow-web"},
+				Metadata: map[string]string{"ruleType": "ingress"},
+			},
+			{
+				Source:    "default/backend",
+				Target:    "kube-system/dns:UDP/53",
+				Policy:    "default/backend-egress",
+				Direction: graph.DirectionEgress,
+				Metadata:  map[string]string{"ruleType": "egress"},
+			},
+			{
+				Source:   "default/frontend",
+				Target:   "prod/default/api:TCP/9090",
+				Policy:   "default/allow-api",
+				Metadata: map[string]string{"action": "ALLOW"},
+			},
+		},
+	}
+
+	out, err := NewEdgesCSVRenderer().Render(g)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	records, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+	if err != nil {
+		t.Fatalf("output is not valid CSV: %v", err)
+	}
+
+	expected := [][]string{
+		EdgesCSVHeader,
+		{"default/frontend", "default/backend:TCP/8080", "TCP", "8080", "default/allow-frontend;default/allow-web", "ingress", "ingress"},
+		{"default/backend", "kube-system/dns:UDP/53", "UDP", "53", "default/backend-egress", "egress", "egress"},
+		{"default/frontend", "prod/default/api:TCP/9090", "TCP", "9090", "default/allow-api", "ingress", "ALLOW"},
+	}
+	if len(records) != len(expected) {
+		t.Fatalf("expected %d rows, got %d", len(expected), len(records))
+	}
+	for i, row := range records {
+		if strings.Join(row, ",") != strings.Join(expected[i], ",") {
+			t.Errorf("row %d: expected %v, got %v", i, expected[i], row)
+		}
+	}
+}
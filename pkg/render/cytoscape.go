@@ -0,0 +1,66 @@
+package render
+
+import (
+	"encoding/json"
+
+	"github.com/ddl-r-abdulaziz/dnmap/pkg/graph"
+)
+
+// CytoscapeRenderer renders network graphs in the Cytoscape.js elements format.
+type CytoscapeRenderer struct{}
+
+// NewCytoscapeRenderer creates a new Cytoscape.js renderer.
+func NewCytoscapeRenderer() *CytoscapeRenderer {
+	return &CytoscapeRenderer{}
+}
+
+// cytoscapeElement wraps a node or edge in the {data, classes} shape Cytoscape.js expects.
+type cytoscapeElement struct {
+	Data    any    `json:"data"`
+	Classes string `json:"classes,omitempty"`
+}
+
+// cytoscapeGraph is the top-level {elements: {nodes, edges}} document.
+type cytoscapeGraph struct {
+	Elements struct {
+		Nodes []cytoscapeElement `json:"nodes"`
+		Edges []cytoscapeElement `json:"edges"`
+	} `json:"elements"`
+}
+
+// ContentType returns the JSON MIME type; Cytoscape.js elements are plain JSON.
+func (r *CytoscapeRenderer) ContentType() string {
+	return "application/json"
+}
+
+// FileExtension returns ".cytoscape.json", so the elements aren't mistaken for, or saved
+// over, the graph JSON of the json format.
+func (r *CytoscapeRenderer) FileExtension() string {
+	return ".cytoscape.json"
+}
+
+// Render converts a NetworkGraph to Cytoscape.js JSON. Node and edge fields are kept
+// under data (Cytoscape uses parent for compound nodes, matching port nodes); a node's
+// Kind, or its Type for ports, becomes its classes for styling.
+func (r *CytoscapeRenderer) Render(g *graph.NetworkGraph) (string, error) {
+	var out cytoscapeGraph
+	out.Elements.Nodes = make([]cytoscapeElement, 0, len(g.Nodes))
+	out.Elements.Edges = make([]cytoscapeElement, 0, len(g.Edges))
+
+	for _, n := range g.Nodes {
+		classes := n.Kind
+		if classes == "" {
+			classes = string(n.Type)
+		}
+		out.Elements.Nodes = append(out.Elements.Nodes, cytoscapeElement{Data: n, Classes: classes})
+	}
+	for _, e := range g.Edges {
+		out.Elements.Edges = append(out.Elements.Edges, cytoscapeElement{Data: e, Classes: e.Direction})
+	}
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
@@ -0,0 +1,61 @@
+package render
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ddl-r-abdulaziz/dnmap/pkg/graph"
+)
+
+func TestCytoscapeRendererRender(t *testing.T) {
+	g := &graph.NetworkGraph{
+		Nodes: []graph.Node{
+			{ID: "default/frontend", Label: "frontend", Type: graph.NodeTypeWorkload, Kind: "Deployment"},
+			{ID: "default/backend", Label: "backend", Type: graph.NodeTypeWorkload, Kind: "StatefulSet"},
+			{ID: "default/backend:TCP/8080", Label: "8080", Type: graph.NodeTypePort, Parent: "default/backend"},
+		},
+		Edges: []graph.Edge{
+			{ID: "edge-0", Source: "default/frontend", Target: "default/backend:TCP/8080", Label: "TCP:8080"},
+		},
+	}
+
+	out, err := NewCytoscapeRenderer().Render(g)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var decoded struct {
+		Elements struct {
+			Nodes []struct {
+				Data    map[string]any `json:"data"`
+				Classes string         `json:"classes"`
+			} `json:"nodes"`
+			Edges []struct {
+				Data map[string]any `json:"data"`
+			} `json:"edges"`
+		} `json:"elements"`
+	}
+	if err := json.Unmarshal([]byte(out), &decoded); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+
+	if len(decoded.Elements.Nodes) != len(g.Nodes) {
+		t.Errorf("expected %d nodes, got %d", len(g.Nodes), len(decoded.Elements.Nodes))
+	}
+	if len(decoded.Elements.Edges) != len(g.Edges) {
+		t.Errorf("expected %d edges, got %d", len(g.Edges), len(decoded.Elements.Edges))
+	}
+
+	expectedClasses := []string{"Deployment", "StatefulSet", "port"}
+	for i, n := range decoded.Elements.Nodes {
+		if n.Classes != expectedClasses[i] {
+			t.Errorf("node %d: expected classes %q, got %q", i, expectedClasses[i], n.Classes)
+		}
+		if n.Data["id"] != g.Nodes[i].ID {
+			t.Errorf("node %d: expected data.id %q, got %v", i, g.Nodes[i].ID, n.Data["id"])
+		}
+	}
+	if e := decoded.Elements.Edges[0].Data; e["source"] != "default/frontend" || e["target"] != "default/backend:TCP/8080" {
+		t.Errorf("unexpected edge data: %v", e)
+	}
+}
@@ -0,0 +1,95 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ddl-r-abdulaziz/dnmap/pkg/graph"
+)
+
+// dotKindColors matches the workload colors used by the HTML template.
+var dotKindColors = map[string]string{
+	"Deployment":  "#7fd962",
+	"StatefulSet": "#c792ea",
+	"DaemonSet":   "#ff8f40",
+	"Gateway":     "#ffcc66",
+	"Any":         "#f07178",
+}
+
+// DOTRenderer renders network graphs as Graphviz DOT digraphs.
+type DOTRenderer struct{}
+
+// NewDOTRenderer creates a new DOT renderer.
+func NewDOTRenderer() *DOTRenderer {
+	return &DOTRenderer{}
+}
+
+// ContentType returns the Graphviz MIME type.
+func (r *DOTRenderer) ContentType() string {
+	return "text/vnd.graphviz; charset=utf-8"
+}
+
+// FileExtension returns ".dot", which Graphviz tools expect.
+func (r *DOTRenderer) FileExtension() string {
+	return ".dot"
+}
+
+// Render converts a NetworkGraph to a DOT digraph. Workloads are boxes colored by kind,
+// Gateways are hexagons, the ANY source is a double octagon, ports are small ellipses
+// attached to their workload, and edges carry edge.Label.
+func (r *DOTRenderer) Render(g *graph.NetworkGraph) (string, error) {
+	var b strings.Builder
+	b.WriteString("digraph dnmap {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [fontname=\"Helvetica\"];\n")
+	b.WriteString("  edge [fontname=\"Helvetica\", fontsize=10];\n")
+
+	for _, n := range g.Nodes {
+		switch n.Type {
+		case graph.NodeTypeWorkload:
+			color, ok := dotKindColors[n.Kind]
+			if !ok {
+				color = dotKindColors["Deployment"]
+			}
+			style := "filled"
+			if n.Stub {
+				style = "filled,dashed"
+			}
+			fmt.Fprintf(&b, "  %s [label=%s, shape=box, style=%q, fillcolor=%q];\n",
+				dotQuote(n.ID), dotQuote(n.Namespace+"/"+n.Label), style, color)
+		case graph.NodeTypeGateway:
+			fmt.Fprintf(&b, "  %s [label=%s, shape=hexagon, style=\"filled\", fillcolor=%q];\n",
+				dotQuote(n.ID), dotQuote(n.Namespace+"/"+n.Label), dotKindColors["Gateway"])
+		case graph.NodeTypeAny:
+			fmt.Fprintf(&b, "  %s [label=%s, shape=doubleoctagon, style=\"filled\", fillcolor=%q];\n",
+				dotQuote(n.ID), dotQuote(n.Label), dotKindColors["Any"])
+		case graph.NodeTypePort:
+			fmt.Fprintf(&b, "  %s [label=%s, shape=ellipse, fontsize=9, height=0.3];\n",
+				dotQuote(n.ID), dotQuote(n.Label))
+			if n.Parent != "" {
+				fmt.Fprintf(&b, "  %s -> %s [style=dotted, arrowhead=none];\n", dotQuote(n.Parent), dotQuote(n.ID))
+			}
+		}
+	}
+
+	for _, e := range g.Edges {
+		attrs := []string{"label=" + dotQuote(e.Label)}
+		switch e.Diff {
+		case graph.DiffAdded:
+			attrs = append(attrs, `color="#22c55e"`, "penwidth=2")
+		case graph.DiffRemoved:
+			attrs = append(attrs, `color="#f07178"`, "style=dashed")
+		}
+		fmt.Fprintf(&b, "  %s -> %s [%s];\n", dotQuote(e.Source), dotQuote(e.Target), strings.Join(attrs, ", "))
+	}
+
+	b.WriteString("}\n")
+	return b.String(), nil
+}
+
+// dotQuote returns s as a DOT quoted ID, since node IDs contain slashes and colons.
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
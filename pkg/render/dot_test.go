@@ -0,0 +1,77 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ddl-r-abdulaziz/dnmap/pkg/graph"
+)
+
+func TestDOTRendererRender(t *testing.T) {
+	tests := map[string]struct {
+		graph           *graph.NetworkGraph
+		expectSubstring []string
+	}{
+		"empty graph": {
+			graph:           &graph.NetworkGraph{},
+			expectSubstring: []string{"digraph dnmap {"},
+		},
+		"graph with edges": {
+			graph: &graph.NetworkGraph{
+				Nodes: []graph.Node{
+					{ID: "default/frontend", Label: "frontend", Namespace: "default", Type: graph.NodeTypeWorkload, Kind: "Deployment"},
+					{ID: "default/backend", Label: "backend", Namespace: "default", Type: graph.NodeTypeWorkload, Kind: "StatefulSet"},
+					{ID: "default/backend:TCP/8080", Label: "8080", Type: graph.NodeTypePort, Parent: "default/backend"},
+				},
+				Edges: []graph.Edge{
+					{ID: "edge-0", Source: "default/frontend", Target: "default/backend:TCP/8080", Label: "TCP:8080"},
+				},
+			},
+			expectSubstring: []string{
+				`"default/frontend" [label="default/frontend", shape=box, style="filled", fillcolor="#7fd962"];`,
+				`"default/backend" [label="default/backend", shape=box, style="filled", fillcolor="#c792ea"];`,
+				`"default/backend:TCP/8080" [label="8080", shape=ellipse`,
+				`"default/backend" -> "default/backend:TCP/8080" [style=dotted, arrowhead=none];`,
+				`"default/frontend" -> "default/backend:TCP/8080" [label="TCP:8080"];`,
+			},
+		},
+		"gateway": {
+			graph: &graph.NetworkGraph{
+				Nodes: []graph.Node{
+					{ID: "gateway:gateways/public", Label: "public", Namespace: "gateways", Type: graph.NodeTypeGateway, Kind: "Gateway"},
+				},
+			},
+			expectSubstring: []string{`"gateway:gateways/public" [label="gateways/public", shape=hexagon, style="filled", fillcolor="#ffcc66"];`},
+		},
+		"quotes escaped": {
+			graph: &graph.NetworkGraph{
+				Nodes: []graph.Node{
+					{ID: `ns/we"ird`, Label: `we"ird`, Namespace: "ns", Type: graph.NodeTypeWorkload},
+				},
+			},
+			expectSubstring: []string{`"ns/we\"ird"`},
+		},
+	}
+
+	renderer := NewDOTRenderer()
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			dot, err := renderer.Render(tt.graph)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !strings.HasPrefix(dot, "digraph ") || !strings.HasSuffix(dot, "}\n") {
+				t.Errorf("expected a digraph, got:\n%s", dot)
+			}
+			if strings.Count(dot, "{") != strings.Count(dot, "}") {
+				t.Errorf("unbalanced braces in:\n%s", dot)
+			}
+			for _, substr := range tt.expectSubstring {
+				if !strings.Contains(dot, substr) {
+					t.Errorf("expected DOT to contain %q, got:\n%s", substr, dot)
+				}
+			}
+		})
+	}
+}
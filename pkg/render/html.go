@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"embed"
 	"encoding/json"
+	"fmt"
 	"text/template"
 
 	"github.com/ddl-r-abdulaziz/dnmap/pkg/graph"
@@ -13,18 +14,60 @@ import (
 //go:embed templates/*.tmpl
 var templateFS embed.FS
 
+// Initial layouts for the HTML page; users can switch between them in the UI.
+const (
+	LayoutGrid         = "grid"         // Workloads in rows, grouped by namespace
+	LayoutHierarchical = "hierarchical" // Sources on the left, the workloads they reach to the right
+)
+
 // HTMLRenderer renders network graphs to interactive HTML pages.
 type HTMLRenderer struct {
-	tmpl *template.Template
+	tmpl    *template.Template
+	layout  string
+	colorBy string // namespace label key coloring workload borders; empty for none
 }
 
-// NewHTMLRenderer creates a new HTML renderer.
+// NewHTMLRenderer creates a new HTML renderer using the built-in template.
 func NewHTMLRenderer() (*HTMLRenderer, error) {
 	tmpl, err := template.ParseFS(templateFS, "templates/graph.html.tmpl")
 	if err != nil {
 		return nil, err
 	}
-	return &HTMLRenderer{tmpl: tmpl}, nil
+	return &HTMLRenderer{tmpl: tmpl, layout: LayoutGrid}, nil
+}
+
+// NewHTMLRendererWithTemplate creates a new HTML renderer using a custom template file.
+// The template receives the same data as the built-in one (e.g. {{.GraphData}}).
+func NewHTMLRendererWithTemplate(path string) (*HTMLRenderer, error) {
+	tmpl, err := template.ParseFiles(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", path, err)
+	}
+	return &HTMLRenderer{tmpl: tmpl, layout: LayoutGrid}, nil
+}
+
+// WithLayout sets the initial layout, LayoutGrid or LayoutHierarchical, passed to the
+// template as {{.Layout}}.
+func (r *HTMLRenderer) WithLayout(layout string) *HTMLRenderer {
+	r.layout = layout
+	return r
+}
+
+// WithColorBy colors workload borders by the value of the namespace label key (e.g.
+// "environment"), passed to the template as {{.ColorBy}}, a JSON string.
+func (r *HTMLRenderer) WithColorBy(key string) *HTMLRenderer {
+	r.colorBy = key
+	return r
+}
+
+// ContentType returns the HTML MIME type, also for custom templates.
+func (r *HTMLRenderer) ContentType() string {
+	return "text/html; charset=utf-8"
+}
+
+// FileExtension returns ".html".
+func (r *HTMLRenderer) FileExtension() string {
+	return ".html"
 }
 
 // Render converts a NetworkGraph to an interactive HTML page.
@@ -34,9 +77,26 @@ func (r *HTMLRenderer) Render(g *graph.NetworkGraph) (string, error) {
 		return "", err
 	}
 
+	colorByJSON, err := json.Marshal(r.colorBy)
+	if err != nil {
+		return "", err
+	}
+
+	descriptions := make(map[graph.WarningType]string, len(graph.WarningTypes))
+	for _, t := range graph.WarningTypes {
+		descriptions[t] = t.Description()
+	}
+	descriptionsJSON, err := json.Marshal(descriptions)
+	if err != nil {
+		return "", err
+	}
+
 	var buf bytes.Buffer
 	if err := r.tmpl.Execute(&buf, map[string]string{
-		"GraphData": string(graphJSON),
+		"GraphData":           string(graphJSON),
+		"WarningDescriptions": string(descriptionsJSON),
+		"Layout":              r.layout,
+		"ColorBy":             string(colorByJSON),
 	}); err != nil {
 		return "", err
 	}
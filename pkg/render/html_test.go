@@ -1,6 +1,8 @@
 package render
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -78,6 +80,29 @@ func TestHTMLRendererRender(t *testing.T) {
 				"backend",
 				"edge-0",
 				"allow-frontend",
+				`"direction":"ingress"`, // unset direction defaults to ingress
+			},
+		},
+		"graph with egress edge": {
+			graph: &graph.NetworkGraph{
+				Nodes: []graph.Node{
+					{ID: "default/frontend", Label: "frontend", Type: graph.NodeTypeWorkload},
+					{ID: "default/backend", Label: "backend", Type: graph.NodeTypeWorkload},
+					{ID: "default/backend:TCP/8080", Label: "8080", Type: graph.NodeTypePort, Parent: "default/backend"},
+				},
+				Edges: []graph.Edge{
+					{
+						ID:        "edge-0",
+						Source:    "default/frontend",
+						Target:    "default/backend:TCP/8080",
+						Label:     "TCP:8080",
+						Policy:    "default/frontend-egress",
+						Direction: graph.DirectionEgress,
+					},
+				},
+			},
+			expectSubstring: []string{
+				`"direction":"egress"`,
 			},
 		},
 	}
@@ -97,3 +122,137 @@ func TestHTMLRendererRender(t *testing.T) {
 		})
 	}
 }
+
+func TestHTMLRendererWithLayout(t *testing.T) {
+	tests := map[string]struct {
+		layout          string
+		expectSubstring string
+	}{
+		"default grid": {
+			expectSubstring: `let layoutMode = 'grid' ===`,
+		},
+		"hierarchical": {
+			layout:          LayoutHierarchical,
+			expectSubstring: `let layoutMode = 'hierarchical' ===`,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			renderer, err := NewHTMLRenderer()
+			if err != nil {
+				t.Fatalf("failed to create renderer: %v", err)
+			}
+			if tt.layout != "" {
+				renderer = renderer.WithLayout(tt.layout)
+			}
+
+			html, err := renderer.Render(&graph.NetworkGraph{Nodes: []graph.Node{}, Edges: []graph.Edge{}})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !strings.Contains(html, tt.expectSubstring) {
+				t.Errorf("expected HTML to contain %q", tt.expectSubstring)
+			}
+		})
+	}
+}
+
+func TestHTMLRendererWithColorBy(t *testing.T) {
+	tests := map[string]struct {
+		colorBy         string
+		expectSubstring string
+	}{
+		"default off": {
+			expectSubstring: `const colorByLabel = "";`,
+		},
+		"label key": {
+			colorBy:         "environment",
+			expectSubstring: `const colorByLabel = "environment";`,
+		},
+		"escaped key": {
+			colorBy:         `</script>"`,
+			expectSubstring: `const colorByLabel = "\u003c/script\u003e\"";`,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			renderer, err := NewHTMLRenderer()
+			if err != nil {
+				t.Fatalf("failed to create renderer: %v", err)
+			}
+
+			html, err := renderer.WithColorBy(tt.colorBy).Render(&graph.NetworkGraph{Nodes: []graph.Node{}, Edges: []graph.Edge{}})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !strings.Contains(html, tt.expectSubstring) {
+				t.Errorf("expected HTML to contain %q", tt.expectSubstring)
+			}
+		})
+	}
+}
+
+func TestNewHTMLRendererWithTemplate(t *testing.T) {
+	tests := map[string]struct {
+		template        string
+		expectErr       bool
+		expectSubstring string
+	}{
+		"custom template": {
+			template:        `<html><!-- custom-branding -->{{.GraphData}}</html>`,
+			expectSubstring: "custom-branding",
+		},
+		"graph data injected": {
+			template:        `<script>const graphData = {{.GraphData}};</script>`,
+			expectSubstring: `const graphData = {"nodes":[{"id":"default/sentinel"`,
+		},
+		"warning descriptions injected": {
+			template:        `<script>const descriptions = {{.WarningDescriptions}};</script>`,
+			expectSubstring: `"policy-conflict":"` + graph.WarningPolicyConflict.Description() + `"`,
+		},
+		"invalid template": {
+			template:  `<html>{{.GraphData</html>`,
+			expectErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "custom.html")
+			if err := os.WriteFile(path, []byte(tt.template), 0644); err != nil {
+				t.Fatalf("failed to write template: %v", err)
+			}
+
+			renderer, err := NewHTMLRendererWithTemplate(path)
+			if tt.expectErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			html, err := renderer.Render(&graph.NetworkGraph{
+				Nodes: []graph.Node{{ID: "default/sentinel", Label: "sentinel", Type: graph.NodeTypeWorkload}},
+				Edges: []graph.Edge{},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !strings.Contains(html, tt.expectSubstring) {
+				t.Errorf("expected HTML to contain %q", tt.expectSubstring)
+			}
+		})
+	}
+}
+
+func TestNewHTMLRendererWithTemplateMissingFile(t *testing.T) {
+	_, err := NewHTMLRendererWithTemplate(filepath.Join(t.TempDir(), "missing.html"))
+	if err == nil {
+		t.Fatal("expected error for missing template file, got nil")
+	}
+}
@@ -0,0 +1,34 @@
+package render
+
+import (
+	"encoding/json"
+
+	"github.com/ddl-r-abdulaziz/dnmap/pkg/graph"
+)
+
+// JSONRenderer renders network graphs as pretty-printed JSON for downstream tooling.
+type JSONRenderer struct{}
+
+// NewJSONRenderer creates a new JSON renderer.
+func NewJSONRenderer() *JSONRenderer {
+	return &JSONRenderer{}
+}
+
+// ContentType returns the JSON MIME type.
+func (r *JSONRenderer) ContentType() string {
+	return "application/json"
+}
+
+// FileExtension returns ".json".
+func (r *JSONRenderer) FileExtension() string {
+	return ".json"
+}
+
+// Render converts a NetworkGraph to indented JSON, including its warning details.
+func (r *JSONRenderer) Render(g *graph.NetworkGraph) (string, error) {
+	data, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
@@ -0,0 +1,49 @@
+package render
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ddl-r-abdulaziz/dnmap/pkg/graph"
+)
+
+func TestJSONRendererRender(t *testing.T) {
+	g := &graph.NetworkGraph{
+		Nodes: []graph.Node{
+			{ID: "default/frontend", Label: "frontend", Type: graph.NodeTypeWorkload},
+			{ID: "default/backend", Label: "backend", Type: graph.NodeTypeWorkload},
+			{ID: "default/backend:TCP/8080", Label: "8080", Type: graph.NodeTypePort, Parent: "default/backend"},
+		},
+		Edges: []graph.Edge{
+			{ID: "edge-0", Source: "default/frontend", Target: "default/backend:TCP/8080", Label: "TCP:8080"},
+		},
+		Namespaces: []graph.NamespaceNode{
+			{Name: "default", Labels: map[string]string{"environment": "production"}},
+		},
+		WarningDetails: []graph.WarningDetail{
+			{WorkloadID: "default/backend", WorkloadName: "backend", Namespace: "default", PolicyName: "allow-all", WarningType: graph.WarningNoPorts},
+		},
+	}
+
+	out, err := NewJSONRenderer().Render(g)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var decoded graph.NetworkGraph
+	if err := json.Unmarshal([]byte(out), &decoded); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+	if len(decoded.Nodes) != len(g.Nodes) || len(decoded.Edges) != len(g.Edges) {
+		t.Errorf("expected %d nodes and %d edges, got %d and %d", len(g.Nodes), len(g.Edges), len(decoded.Nodes), len(decoded.Edges))
+	}
+	if len(decoded.WarningDetails) != 1 || decoded.WarningDetails[0].WarningType != graph.WarningNoPorts {
+		t.Errorf("expected warning details to round-trip, got %+v", decoded.WarningDetails)
+	}
+	if len(decoded.Namespaces) != 1 || decoded.Namespaces[0].Labels["environment"] != "production" {
+		t.Errorf("expected namespace labels to round-trip, got %+v", decoded.Namespaces)
+	}
+	if decoded.Edges[0].Direction != graph.DirectionIngress {
+		t.Errorf("expected unset direction exported as ingress, got %q", decoded.Edges[0].Direction)
+	}
+}
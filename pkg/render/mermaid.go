@@ -0,0 +1,110 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ddl-r-abdulaziz/dnmap/pkg/graph"
+)
+
+// MermaidRenderer renders network graphs as Mermaid flowcharts for embedding in markdown.
+type MermaidRenderer struct{}
+
+// NewMermaidRenderer creates a new Mermaid renderer.
+func NewMermaidRenderer() *MermaidRenderer {
+	return &MermaidRenderer{}
+}
+
+// ContentType returns plain text, as Mermaid has no registered MIME type.
+func (r *MermaidRenderer) ContentType() string {
+	return "text/plain; charset=utf-8"
+}
+
+// FileExtension returns ".mmd", the extension Mermaid tooling recognizes.
+func (r *MermaidRenderer) FileExtension() string {
+	return ".mmd"
+}
+
+// Render converts a NetworkGraph to a Mermaid "graph LR" flowchart. Workloads are
+// rectangles, Gateways are hexagons, the ANY source is a circle, ports are rounded nodes
+// attached to their workload, and edges are labeled with edge.Label.
+func (r *MermaidRenderer) Render(g *graph.NetworkGraph) (string, error) {
+	ids := newMermaidIDs()
+
+	var b strings.Builder
+	b.WriteString("graph LR\n")
+
+	for _, n := range g.Nodes {
+		switch n.Type {
+		case graph.NodeTypeWorkload:
+			fmt.Fprintf(&b, "  %s[%s]\n", ids.get(n.ID), mermaidLabel(n.Namespace+"/"+n.Label))
+		case graph.NodeTypeGateway:
+			fmt.Fprintf(&b, "  %s{{%s}}\n", ids.get(n.ID), mermaidLabel(n.Namespace+"/"+n.Label))
+		case graph.NodeTypeAny:
+			fmt.Fprintf(&b, "  %s((%s))\n", ids.get(n.ID), mermaidLabel(n.Label))
+		case graph.NodeTypePort:
+			fmt.Fprintf(&b, "  %s(%s)\n", ids.get(n.ID), mermaidLabel(n.Label))
+			if n.Parent != "" {
+				fmt.Fprintf(&b, "  %s -.- %s\n", ids.get(n.Parent), ids.get(n.ID))
+			}
+		}
+	}
+
+	for _, e := range g.Edges {
+		if e.Label == "" {
+			// An empty |label| is a syntax error
+			fmt.Fprintf(&b, "  %s --> %s\n", ids.get(e.Source), ids.get(e.Target))
+			continue
+		}
+		fmt.Fprintf(&b, "  %s -->|%s| %s\n", ids.get(e.Source), mermaidEdgeLabel(e.Label), ids.get(e.Target))
+	}
+
+	return b.String(), nil
+}
+
+// mermaidIDs maps graph node IDs, which contain slashes and colons, to Mermaid-safe
+// identifiers. The mapping depends only on the order IDs are first seen, so the same
+// graph always renders the same output.
+type mermaidIDs struct {
+	byID  map[string]string
+	taken map[string]bool
+}
+
+func newMermaidIDs() *mermaidIDs {
+	return &mermaidIDs{byID: make(map[string]string), taken: make(map[string]bool)}
+}
+
+// get returns the identifier for id, assigning one on first use. Characters other than
+// ASCII letters and digits become underscores; clashes get a numeric suffix.
+func (m *mermaidIDs) get(id string) string {
+	if safe, ok := m.byID[id]; ok {
+		return safe
+	}
+
+	base := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, id)
+	// Prefix so identifiers never start with a digit or collide with keywords like "end"
+	base = "n_" + base
+
+	safe := base
+	for i := 2; m.taken[safe]; i++ {
+		safe = fmt.Sprintf("%s_%d", base, i)
+	}
+	m.byID[id] = safe
+	m.taken[safe] = true
+	return safe
+}
+
+// mermaidLabel quotes a label so slashes, colons and brackets are shown verbatim.
+func mermaidLabel(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
+
+// mermaidEdgeLabel escapes the characters that would end an unquoted |label|.
+func mermaidEdgeLabel(s string) string {
+	return strings.NewReplacer(`"`, "#quot;", "|", "#124;").Replace(s)
+}
@@ -0,0 +1,74 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ddl-r-abdulaziz/dnmap/pkg/graph"
+)
+
+func TestMermaidRendererRender(t *testing.T) {
+	tests := map[string]struct {
+		graph           *graph.NetworkGraph
+		expectSubstring []string
+	}{
+		"empty graph": {
+			graph:           &graph.NetworkGraph{},
+			expectSubstring: []string{"graph LR\n"},
+		},
+		"graph with edges": {
+			graph: &graph.NetworkGraph{
+				Nodes: []graph.Node{
+					{ID: "default/frontend", Label: "frontend", Namespace: "default", Type: graph.NodeTypeWorkload},
+					{ID: "default/backend", Label: "backend", Namespace: "default", Type: graph.NodeTypeWorkload},
+					{ID: "default/backend:TCP/8080", Label: "http", Type: graph.NodeTypePort, Parent: "default/backend"},
+				},
+				Edges: []graph.Edge{
+					{ID: "edge-0", Source: "default/frontend", Target: "default/backend:TCP/8080", Label: "TCP:8080"},
+				},
+			},
+			expectSubstring: []string{
+				`n_default_frontend["default/frontend"]`,
+				`n_default_backend_TCP_8080("http")`,
+				`n_default_backend -.- n_default_backend_TCP_8080`,
+				`n_default_frontend -->|TCP:8080| n_default_backend_TCP_8080`,
+			},
+		},
+		"edge without a label": {
+			graph: &graph.NetworkGraph{
+				Nodes: []graph.Node{
+					{ID: "default/frontend", Label: "frontend", Namespace: "default", Type: graph.NodeTypeWorkload},
+					{ID: "default/backend", Label: "backend", Namespace: "default", Type: graph.NodeTypeWorkload},
+				},
+				Edges: []graph.Edge{
+					{ID: "edge-0", Source: "default/frontend", Target: "default/backend"},
+				},
+			},
+			expectSubstring: []string{"  n_default_frontend --> n_default_backend\n"},
+		},
+		"colliding sanitized IDs": {
+			graph: &graph.NetworkGraph{
+				Nodes: []graph.Node{
+					{ID: "a/b", Label: "b", Namespace: "a", Type: graph.NodeTypeWorkload},
+					{ID: "a_b", Label: "a_b", Namespace: "", Type: graph.NodeTypeWorkload},
+				},
+			},
+			expectSubstring: []string{`n_a_b["a/b"]`, `n_a_b_2["/a_b"]`},
+		},
+	}
+
+	renderer := NewMermaidRenderer()
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			out, err := renderer.Render(tt.graph)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, substr := range tt.expectSubstring {
+				if !strings.Contains(out, substr) {
+					t.Errorf("expected Mermaid to contain %q, got:\n%s", substr, out)
+				}
+			}
+		})
+	}
+}
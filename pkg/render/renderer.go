@@ -0,0 +1,55 @@
+package render
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ddl-r-abdulaziz/dnmap/pkg/graph"
+)
+
+// Renderer renders a network graph to the contents of an output file.
+type Renderer interface {
+	Render(g *graph.NetworkGraph) (string, error)
+	// ContentType is the MIME type to serve the output with
+	ContentType() string
+	// FileExtension is the output's usual file extension, including the dot
+	FileExtension() string
+}
+
+// FormatHTML is the interactive page, the default format.
+const FormatHTML = "html"
+
+// Formats maps each output format name to its renderer's constructor. The HTML renderer
+// uses the built-in template and default layout; callers needing more construct it directly.
+var Formats = map[string]func() (Renderer, error){
+	FormatHTML:  func() (Renderer, error) { return NewHTMLRenderer() },
+	"dot":       func() (Renderer, error) { return NewDOTRenderer(), nil },
+	"mermaid":   func() (Renderer, error) { return NewMermaidRenderer(), nil },
+	"json":      func() (Renderer, error) { return NewJSONRenderer(), nil },
+	"cytoscape": func() (Renderer, error) { return NewCytoscapeRenderer(), nil },
+	"edges-csv": func() (Renderer, error) { return NewEdgesCSVRenderer(), nil },
+}
+
+// FormatNames returns the names of the registered formats, sorted.
+func FormatNames() []string {
+	names := make([]string, 0, len(Formats))
+	for name := range Formats {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// New returns the renderer registered for format.
+func New(format string) (Renderer, error) {
+	newRenderer, ok := Formats[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q (want one of %s)", format, strings.Join(FormatNames(), ", "))
+	}
+	renderer, err := newRenderer()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s renderer: %w", format, err)
+	}
+	return renderer, nil
+}
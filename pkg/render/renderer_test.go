@@ -0,0 +1,80 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ddl-r-abdulaziz/dnmap/pkg/graph"
+)
+
+func TestNew(t *testing.T) {
+	tests := map[string]struct {
+		format            string
+		expectContentType string
+		expectExtension   string
+		expectErr         bool
+	}{
+		"html": {
+			format:            FormatHTML,
+			expectContentType: "text/html; charset=utf-8",
+			expectExtension:   ".html",
+		},
+		"dot": {
+			format:            "dot",
+			expectContentType: "text/vnd.graphviz; charset=utf-8",
+			expectExtension:   ".dot",
+		},
+		"mermaid": {
+			format:            "mermaid",
+			expectContentType: "text/plain; charset=utf-8",
+			expectExtension:   ".mmd",
+		},
+		"json": {
+			format:            "json",
+			expectContentType: "application/json",
+			expectExtension:   ".json",
+		},
+		"cytoscape": {
+			format:            "cytoscape",
+			expectContentType: "application/json",
+			expectExtension:   ".cytoscape.json",
+		},
+		"edges-csv": {
+			format:            "edges-csv",
+			expectContentType: "text/csv; charset=utf-8",
+			expectExtension:   ".csv",
+		},
+		"unknown": {
+			format:    "svg",
+			expectErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			renderer, err := New(tt.format)
+			if tt.expectErr {
+				if err == nil || !strings.Contains(err.Error(), "edges-csv") {
+					t.Errorf("expected an error listing the formats, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if renderer.ContentType() != tt.expectContentType {
+				t.Errorf("expected content type %q, got %q", tt.expectContentType, renderer.ContentType())
+			}
+			if renderer.FileExtension() != tt.expectExtension {
+				t.Errorf("expected extension %q, got %q", tt.expectExtension, renderer.FileExtension())
+			}
+			if _, err := renderer.Render(&graph.NetworkGraph{Nodes: []graph.Node{}, Edges: []graph.Edge{}}); err != nil {
+				t.Errorf("failed to render an empty graph: %v", err)
+			}
+		})
+	}
+
+	if len(FormatNames()) != len(tests)-1 {
+		t.Errorf("expected every registered format to be tested, got %v", FormatNames())
+	}
+}